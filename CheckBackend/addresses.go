@@ -0,0 +1,141 @@
+package CheckBackend
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"telegram-auto-switch-dns-bot/middleware"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// maxProbeAddresses 单个目标最多检测的解析 IP 数量
+const maxProbeAddresses = 16
+
+// AddressResult 目标解析到的单个 IP 的检测结果
+type AddressResult struct {
+	IP      string        `json:"ip"`
+	Result  bool          `json:"result"`            // 是否连通（resolve_ip 接口不检测，始终为 false）
+	Message string        `json:"message,omitempty"` // 检测返回的消息
+	Latency *LatencyStats `json:"latency,omitempty"` // 耗时统计
+}
+
+// addressProbeResult 可按 IP 汇总的检测结果
+type addressProbeResult interface {
+	addressResult() AddressResult
+}
+
+func (r TCPCheckResponse) addressResult() AddressResult {
+	return AddressResult{IP: r.TargetIp, Result: r.Result, Message: r.Message, Latency: r.Latency}
+}
+
+func (r HTTPCheckResponse) addressResult() AddressResult {
+	return AddressResult{IP: r.TargetIp, Result: r.Result, Message: r.Message, Latency: r.Latency}
+}
+
+func (r TLSCheckResponse) addressResult() AddressResult {
+	return AddressResult{IP: r.TargetIp, Result: r.Result, Message: r.Message, Latency: r.Latency}
+}
+
+// resolveTargets 使用指定解析器解析目标属于 family 地址族的全部 IP（去重，最多 maxProbeAddresses 个），失败时返回错误消息
+func resolveTargets(target string, resolver string, family string) (resolution, string) {
+	res, err := lookupTarget(target, resolver, family)
+	if err == nil && len(res.IPs) == 0 {
+		err = fmt.Errorf("没有解析结果")
+	}
+	if err != nil {
+		utils.Logger.Warnf("⚠️ 无法解析目标 %s 的 IP (解析器: %s), 错误消息: %v", target, res.Resolver, err)
+		res.IPs = nil
+		return res, fmt.Sprintf("无法解析目标 %s的IP, 错误消息: %v", target, err)
+	}
+
+	seen := make(map[string]bool, len(res.IPs))
+	ips := make([]string, 0, len(res.IPs))
+	for _, ip := range res.IPs {
+		if seen[ip] {
+			continue
+		}
+		seen[ip] = true
+		ips = append(ips, ip)
+		if len(ips) == maxProbeAddresses {
+			break
+		}
+	}
+	res.IPs = ips
+	return res, ""
+}
+
+// resolveProbeTargets 解析目标并按访问控制规则过滤不允许检测的 IP 与端口（port 为 0 时不校验端口）
+func resolveProbeTargets(target string, port int, resolver string, family string) (resolution, string) {
+	res, message := resolveTargets(target, resolver, family)
+	if len(res.IPs) == 0 {
+		return res, message
+	}
+	if port != 0 {
+		if err := middleware.CheckTargetPort(port); err != nil {
+			utils.Logger.Warnf("⚠️ 拒绝检测目标 %s: %v", target, err)
+			res.IPs = nil
+			return res, err.Error()
+		}
+	}
+
+	allowed := res.IPs[:0]
+	var lastErr error
+	for _, ip := range res.IPs {
+		if err := middleware.CheckTargetIP(net.ParseIP(ip)); err != nil {
+			lastErr = err
+			continue
+		}
+		allowed = append(allowed, ip)
+	}
+	res.IPs = allowed
+	if len(allowed) == 0 {
+		utils.Logger.Warnf("⚠️ 拒绝检测目标 %s: %v", target, lastErr)
+		return res, lastErr.Error()
+	}
+	return res, ""
+}
+
+// probeEachAddress 并发检测目标解析到的所有 IP，进度只转发第一个 IP 的（与单 IP 时的进度条数一致）
+// 返回最佳 IP 的结果与每个 IP 的结果
+func probeEachAddress[T addressProbeResult](ips []string, progress progressFunc, run func(ip string, progress progressFunc) T) (best T, addresses []AddressResult) {
+	results := make([]T, len(ips))
+	var wg sync.WaitGroup
+	for i, ip := range ips {
+		wg.Add(1)
+		go func(i int, ip string) {
+			defer wg.Done()
+			var p progressFunc
+			if i == 0 {
+				p = progress
+			}
+			results[i] = run(ip, p)
+		}(i, ip)
+	}
+	wg.Wait()
+
+	addresses = make([]AddressResult, len(results))
+	for i, r := range results {
+		addresses[i] = r.addressResult()
+	}
+	return results[bestAddress(addresses)], addresses
+}
+
+// bestAddress 返回连通且平均耗时最低的 IP 下标，全部不通时返回 0
+func bestAddress(addresses []AddressResult) int {
+	best, bestAvg := 0, math.MaxFloat64
+	found := false
+	for i, a := range addresses {
+		if !a.Result {
+			continue
+		}
+		avg := math.MaxFloat64
+		if a.Latency != nil {
+			avg = a.Latency.Avg
+		}
+		if !found || avg < bestAvg {
+			best, bestAvg, found = i, avg, true
+		}
+	}
+	return best
+}
@@ -0,0 +1,212 @@
+package CheckBackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"telegram-auto-switch-dns-bot/middleware"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+const (
+	defaultBatchConcurrency = 8   // 批量检测默认并发数
+	maxBatchConcurrency     = 32  // 批量检测最大并发数
+	maxBatchTargets         = 256 // 单次批量检测最多目标数
+)
+
+// BatchCheckTarget 批量检测的单个目标
+type BatchCheckTarget struct {
+	Target string `json:"target" binding:"required"`
+	Port   int    `json:"port" binding:"required"`
+}
+
+// BatchCheckRequest 批量检测请求参数，所有目标使用同一组探测参数
+type BatchCheckRequest struct {
+	Key         string             `json:"key"`
+	Probe       string             `json:"probe"`  // 探测类型: tcp / http / tls，默认 tcp
+	Params      json.RawMessage    `json:"params"` // 对应单目标接口的请求体（target/port/key 以外的字段）
+	Targets     []BatchCheckTarget `json:"targets" binding:"required,min=1,dive"`
+	Concurrency int                `json:"concurrency"` // 并发数，默认 8，最大 32
+}
+
+// BatchCheckResult 单个目标的检测结果（逐行流式返回，Code=1, Message=result）
+type BatchCheckResult struct {
+	Index  int         `json:"index"` // 目标在请求 targets 中的下标
+	Target string      `json:"target"`
+	Port   int         `json:"port"`
+	Data   interface{} `json:"data"` // 与单目标接口的 Data 相同
+}
+
+// BatchCheckSummary 批量检测汇总（最后一行，Code=0）
+type BatchCheckSummary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+}
+
+// batchRunner 对单个目标执行探测，返回结果与是否连通
+type batchRunner func(t BatchCheckTarget) (interface{}, bool)
+
+func batchCheckHandler(c *gin.Context) {
+	var req BatchCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Logger.Error("批量检测接口绑定JSON请求体错误:", err)
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: 400, Message: "请求参数错误：" + err.Error()})
+		return
+	}
+
+	utils.Logger.Infof("批量检测请求: 类型=%s, 目标数=%d, 并发=%d", req.Probe, len(req.Targets), req.Concurrency)
+
+	// 校验通信密钥
+	if !middleware.ValidateBackendKey(req.Key, c) {
+		return
+	}
+
+	if len(req.Targets) > maxBatchTargets {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: 400, Message: fmt.Sprintf("请求参数错误：目标数量超过上限 %d", maxBatchTargets)})
+		return
+	}
+
+	runner, err := newBatchRunner(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: 400, Message: "请求参数错误：" + err.Error()})
+		return
+	}
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	if concurrency > maxBatchConcurrency {
+		concurrency = maxBatchConcurrency
+	}
+	if concurrency > len(req.Targets) {
+		concurrency = len(req.Targets)
+	}
+
+	// 有界工作池：结果通道容量与目标数相同，客户端断开也不会阻塞工作协程
+	jobs := make(chan int, len(req.Targets))
+	results := make(chan BatchCheckResult, len(req.Targets))
+	succeeded := 0
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				t := req.Targets[i]
+				data, ok := runner(t)
+				if ok {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				}
+				results <- BatchCheckResult{Index: i, Target: t.Target, Port: t.Port, Data: data}
+			}
+		}()
+	}
+	for i := range req.Targets {
+		jobs <- i
+	}
+	close(jobs)
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	c.Stream(func(w io.Writer) bool {
+		if result, ok := <-results; ok {
+			writeStreamLine(w, APIResponse[BatchCheckResult]{
+				Code:    1, // Code=1 表示单个目标的结果
+				Message: "result",
+				Data:    result,
+			})
+			return true
+		}
+
+		mu.Lock()
+		summary := BatchCheckSummary{Total: len(req.Targets), Succeeded: succeeded}
+		mu.Unlock()
+		writeStreamLine(w, APIResponse[BatchCheckSummary]{
+			Code:    0,
+			Message: "success",
+			Data:    summary,
+		})
+		return false
+	})
+}
+
+// newBatchRunner 按探测类型解析并校验公共探测参数，返回单目标探测函数
+func newBatchRunner(req BatchCheckRequest) (batchRunner, error) {
+	params := req.Params
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+
+	switch strings.ToLower(strings.TrimSpace(req.Probe)) {
+	case "", "tcp":
+		var tmpl TCPCheckRequest
+		if err := json.Unmarshal(params, &tmpl); err != nil {
+			return nil, err
+		}
+		// 提前校验协议参数，避免每个目标都返回同样的错误
+		if _, err := newProtocolProbe(&tmpl); err != nil {
+			return nil, err
+		}
+		if err := validateResolveParams(tmpl.Resolver, &tmpl.Family); err != nil {
+			return nil, err
+		}
+		return func(t BatchCheckTarget) (interface{}, bool) {
+			r := tmpl
+			r.Target, r.Port = t.Target, t.Port
+			probe, _ := newProtocolProbe(&r)
+			resp := runTCPProbe(r, probe, nil)
+			return resp, resp.Result
+		}, nil
+	case "http":
+		var tmpl HTTPCheckRequest
+		if err := json.Unmarshal(params, &tmpl); err != nil {
+			return nil, err
+		}
+		// Host 为空时需按各目标填充，先用占位目标校验其余参数
+		check := tmpl
+		check.Target = "batch"
+		bodyRegex, err := normalizeHTTPCheckRequest(&check)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateResolveParams(tmpl.Resolver, &tmpl.Family); err != nil {
+			return nil, err
+		}
+		return func(t BatchCheckTarget) (interface{}, bool) {
+			r := tmpl
+			r.Target, r.Port = t.Target, t.Port
+			_, _ = normalizeHTTPCheckRequest(&r)
+			resp := runHTTPProbe(r, bodyRegex, nil)
+			return resp, resp.Result
+		}, nil
+	case "tls":
+		var tmpl TLSCheckRequest
+		if err := json.Unmarshal(params, &tmpl); err != nil {
+			return nil, err
+		}
+		if err := validateResolveParams(tmpl.Resolver, &tmpl.Family); err != nil {
+			return nil, err
+		}
+		return func(t BatchCheckTarget) (interface{}, bool) {
+			r := tmpl
+			r.Target, r.Port = t.Target, t.Port
+			if r.ServerName == "" {
+				r.ServerName = r.Target
+			}
+			resp := runTLSProbe(r, nil)
+			return resp, resp.Result
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的探测类型: %s", req.Probe)
+	}
+}
@@ -0,0 +1,91 @@
+package CheckBackend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/middleware"
+)
+
+func TestBatchCheckProbeLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 记录目标同时收到的请求数峰值，每个请求保持一段时间以便并发的探测重叠
+	var mu sync.Mutex
+	active, peak := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	host, portText, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	port, _ := strconv.Atoi(portText)
+
+	const maxInflight = 2
+	old := config.Global
+	config.Global = &config.Config{BackendListen: config.BackendListenConfig{
+		Key:            "test-key",
+		AllowLegacyKey: true,
+		RateLimit:      config.RateLimitConfig{MaxInflight: maxInflight},
+		TargetPolicy:   config.TargetPolicyConfig{AllowPrivate: true},
+	}}
+	t.Cleanup(func() { config.Global = old })
+
+	req := BatchCheckRequest{Key: "test-key", Probe: "http", Concurrency: 8}
+	for i := 0; i < 8; i++ {
+		req.Targets = append(req.Targets, BatchCheckTarget{Target: host, Port: port})
+	}
+	body, _ := json.Marshal(req)
+
+	// 流式响应需要真实连接，使用 httptest.Server 而不是 ResponseRecorder
+	r := gin.New()
+	r.POST("/batch", batchCheckHandler)
+	api := httptest.NewServer(r)
+	defer api.Close()
+	resp, err := http.Post(api.URL+"/batch", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("批量检测请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	// 一个批量请求的全部探测共用探测名额：同时进行的探测不超过 max_inflight
+	var summary BatchCheckSummary
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var line APIResponse[json.RawMessage]
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("解析响应行失败: %v", err)
+		}
+		if line.Code == 0 {
+			_ = json.Unmarshal(line.Data, &summary)
+		}
+	}
+	if summary.Total != len(req.Targets) || summary.Succeeded != len(req.Targets) {
+		t.Errorf("summary = %+v, want all %d targets succeeded", summary, len(req.Targets))
+	}
+	if peak > maxInflight {
+		t.Errorf("并发探测峰值 = %d, want <= %d", peak, maxInflight)
+	}
+	if n := middleware.InflightCount(); n != 0 {
+		t.Errorf("InflightCount() = %d after batch, want 0", n)
+	}
+}
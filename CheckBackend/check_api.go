@@ -1,12 +1,18 @@
 package CheckBackend
 
 import (
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"io"
+	"math"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"telegram-auto-switch-dns-bot/config"
 	"telegram-auto-switch-dns-bot/middleware"
 	"telegram-auto-switch-dns-bot/utils"
@@ -15,18 +21,27 @@ import (
 
 // TCPCheckRequest 请求参数
 type TCPCheckRequest struct {
-	Target string `json:"target" binding:"required"`
-	Port   int    `json:"port" binding:"required"`
-	Key    string `json:"key" binding:"required"`
+	Target   string `json:"target" binding:"required"`
+	Port     int    `json:"port" binding:"required"`
+	Key      string `json:"key"`
+	Protocol string `json:"protocol"` // 探测协议: tcp / udp / icmp，默认 tcp
+	Payload  string `json:"payload"`  // UDP 探测发送的载荷（十六进制）
+	Expect   string `json:"expect"`   // UDP 期望回包中包含的内容（十六进制），为空时收到任意回包即视为成功
+	Resolver string `json:"resolver"` // 解析目标使用的解析器名称，为空时使用 default_resolver
+	Family   string `json:"family"`   // 探测的地址族: ipv4 / ipv6 / both，默认 both
 }
 
 // TCPCheckResponse 响应结果
 type TCPCheckResponse struct {
-	Result          bool   `json:"result"`            // true / false
-	Target          string `json:"target"`            // 检测目标
-	TargetIp        string `json:"target_ip"`         // 检测目标ip
-	Message         string `json:"message"`           // 检测返回的消息
-	BackendPublicIP string `json:"backend_public_ip"` // 本机公网 IP
+	Result          bool            `json:"result"`              // true / false
+	Target          string          `json:"target"`              // 检测目标
+	TargetIp        string          `json:"target_ip"`           // 检测目标ip
+	Message         string          `json:"message"`             // 检测返回的消息
+	BackendPublicIP string          `json:"backend_public_ip"`   // 本机公网 IP
+	Latency         *LatencyStats   `json:"latency,omitempty"`   // 连接耗时统计
+	Addresses       []AddressResult `json:"addresses,omitempty"` // 目标解析到的每个 IP 的结果
+	Resolver        string          `json:"resolver"`            // 应答的解析器名称
+	TTL             uint32          `json:"ttl"`                 // 解析应答的 TTL（秒），系统解析器为 0
 }
 
 // APIResponse 统一 REST API 返回结构
@@ -38,20 +53,55 @@ type APIResponse[T any] struct {
 
 func CheckApi() {
 	utils.Logger.Infof("检测后端启动")
+	go StartEgressIPRefresher() // 后台获取并定时刷新出口 IP，检测响应直接使用缓存
 	r := gin.Default()
+	r.Use(middleware.BackendRateLimit()) // 按来源 IP 限流，探测名额已满时拒绝新请求
+	r.Use(middleware.BackendSignature()) // 校验请求签名，未签名请求由各接口按兼容模式验证 key
 	gin.SetMode(gin.ReleaseMode)
 	r.POST("/api/v1/tcp_checks", tcpCheckHandler)
+	r.POST("/api/v1/http_checks", httpCheckHandler)
+	r.POST("/api/v1/tls_checks", tlsCheckHandler)
+	r.POST("/api/v1/batch_checks", batchCheckHandler)
 	r.POST("/api/v1/resolve_ip", resolveIPHandler) // 新增：只解析 IP 的接口
+	r.POST("/api/v1/trace", traceHandler)          // 路由追踪与路径 MTU 探测
+	r.GET("/api/v1/info", infoHandler)             // 版本、运行状态与能力
+	r.GET("/api/v1/ws", wsHandler(r))              // WebSocket 任务通道，复用以上检测接口
+
+	// 反向连接：主动连接 Bot 接收检测任务，未配置监听端口时只通过反向连接提供服务
+	if reverseEnabled() {
+		go StartReverseConnect(r)
+		if config.Global.BackendListen.Port == "" {
+			utils.Logger.Infof("检测后端仅通过反向连接提供服务: %s", config.Global.BackendListen.Reverse.URL)
+			select {}
+		}
+	}
+
+	tlsConfig, err := buildServerTLSConfig()
+	if err != nil {
+		utils.Logger.Error("检测后端 TLS 配置错误:", err)
+		return
+	}
 	srv := &http.Server{
-		Addr:           ":" + config.Global.BackendListen.Port,
+		Addr:           net.JoinHostPort(config.Global.BackendListen.Host, config.Global.BackendListen.Port),
 		Handler:        r,
+		TLSConfig:      tlsConfig,
 		ReadTimeout:    config.Global.BackendListen.ReadTimeout * time.Second,
 		WriteTimeout:   config.Global.BackendListen.WriteTimeout * time.Second,
 		MaxHeaderBytes: config.Global.BackendListen.MaxHeaderBytes,
 	}
 
-	utils.Logger.Infof("检测后端正在监听端口: %s", config.Global.BackendListen.Port)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if tlsConfig != nil {
+		mode := "HTTPS"
+		if tlsConfig.ClientCAs != nil {
+			mode = "HTTPS + mTLS"
+		}
+		utils.Logger.Infof("检测后端正在监听 (%s): %s", mode, srv.Addr)
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		utils.Logger.Infof("检测后端正在监听: %s", srv.Addr)
+		err = srv.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		utils.Logger.Error("检测后端启动失败:", err)
 	}
 }
@@ -65,138 +115,260 @@ func tcpCheckHandler(c *gin.Context) {
 		return
 	}
 
-	utils.Logger.Infof("请求体数据: %+v", req)
+	logged := req
+	logged.Key = redactKey(req.Key)
+	utils.Logger.Infof("请求体数据: %+v", logged)
 
 	// 校验通信密钥
 	if !middleware.ValidateBackendKey(req.Key, c) {
 		return
 	}
 
-	// 获取目标 IP，如果解析失败，直接返回接口
-	var targetIP string
-	var Message string
-	targetIPs, err := net.LookupIP(req.Target)
-	if err == nil && len(targetIPs) > 0 {
-		targetIP = targetIPs[0].String()
-		Message = ""
-	} else {
-		utils.Logger.Warnf("⚠️ 无法解析目标 %s 的 IP, 错误消息: %v", req.Target, err)
-		targetIP = ""
-		Message = fmt.Sprintf("无法解析目标 %s的IP, 错误消息: %v", req.Target, err)
+	attempt, err := newProtocolProbe(&req)
+	if err == nil {
+		err = validateResolveParams(req.Resolver, &req.Family)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: 400, Message: "请求参数错误：" + err.Error()})
+		return
+	}
 
-		// 获取本机公网 IP
-		backendPublicIP := getPublicIP()
+	// 流式发送进度与最终结果
+	streamProbe(c, req.Target, func(progress progressFunc) TCPCheckResponse {
+		return runTCPProbe(req, attempt, progress)
+	})
+}
 
-		// 直接返回，不再进行 TCP 检测
-		c.JSON(http.StatusOK, APIResponse[TCPCheckResponse]{
-			Code:    0,
-			Message: "success",
-			Data: TCPCheckResponse{
-				Result:          false,
-				Target:          req.Target,
-				TargetIp:        targetIP,
-				Message:         Message,
-				BackendPublicIP: backendPublicIP,
-			},
-		})
-		return
+// protocolProbe 针对已解析 IP 的单次探测
+type protocolProbe func(ip net.IP, addr string) error
+
+// newProtocolProbe 校验探测协议及参数，返回对应的单次探测函数
+func newProtocolProbe(req *TCPCheckRequest) (protocolProbe, error) {
+	req.Protocol = strings.ToLower(strings.TrimSpace(req.Protocol))
+	switch req.Protocol {
+	case "", "tcp":
+		req.Protocol = "tcp"
+		return func(_ net.IP, addr string) error {
+			conn, err := net.DialTimeout("tcp", addr, 1*time.Second)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}, nil
+	case "udp":
+		payload, err := hex.DecodeString(req.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("UDP 载荷不是有效的十六进制: %w", err)
+		}
+		expect, err := hex.DecodeString(req.Expect)
+		if err != nil {
+			return nil, fmt.Errorf("UDP 期望回包不是有效的十六进制: %w", err)
+		}
+		return func(_ net.IP, addr string) error {
+			return probeUDP(addr, payload, expect)
+		}, nil
+	case "icmp":
+		// 多个 IP 并发检测时共用序号计数
+		var seq atomic.Int32
+		return func(ip net.IP, _ string) error {
+			return probeICMP(ip, int(seq.Add(1)))
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的探测协议: %s", req.Protocol)
 	}
+}
 
-	// TCP 连接检测，最多尝试 5 次，并发送进度消息
-	result := false
-	maxTry := 5
-	addr := fmt.Sprintf("%s:%d", targetIP, req.Port)
+// runTCPProbe 解析目标并按请求协议检测每个解析 IP，返回最佳 IP 的结果
+func runTCPProbe(req TCPCheckRequest, probe protocolProbe, progress progressFunc) TCPCheckResponse {
+	port := req.Port
+	if req.Protocol == "icmp" {
+		// ICMP 不区分端口
+		port = 0
+	}
+	res, message := resolveProbeTargets(req.Target, port, req.Resolver, req.Family)
+	if len(res.IPs) == 0 {
+		// 解析失败，直接返回结果
+		return TCPCheckResponse{
+			Result:          false,
+			Target:          req.Target,
+			Message:         message,
+			BackendPublicIP: getPublicIP(),
+			Resolver:        res.Resolver,
+		}
+	}
 
-	// 创建一个通道来发送进度消息
-	progressChan := make(chan map[string]interface{}, 5)
-	doneChan := make(chan bool)
-	errorChan := make(chan error)
+	best, addresses := probeEachAddress(res.IPs, progress, func(ip string, progress progressFunc) TCPCheckResponse {
+		return probeTCPAddress(req, probe, ip, progress)
+	})
+	best.Addresses = addresses
+	best.Resolver, best.TTL = res.Resolver, res.TTL
+	best.BackendPublicIP = getPublicIP()
+	return best
+}
 
-	// 启动goroutine处理TCP检测
-	go func() {
-		defer close(progressChan)
-		defer close(doneChan)
-		defer close(errorChan)
+// probeTCPAddress 对单个 IP 按请求协议进行连通性检测，最多尝试 maxProbeTries 次
+func probeTCPAddress(req TCPCheckRequest, probe protocolProbe, targetIP string, progress progressFunc) TCPCheckResponse {
+	ip := net.ParseIP(targetIP)
+	addr := net.JoinHostPort(targetIP, strconv.Itoa(req.Port))
+	if req.Protocol == "icmp" {
+		// ICMP 不区分端口
+		addr = targetIP
+	}
+	result, message, latency := attemptProbe(addr, progress, func() error {
+		return probe(ip, addr)
+	})
 
-		for i := 1; i <= maxTry; i++ {
-			utils.Logger.Infof("🔍 正在检测第 %d/%d 次连接：%s ...", i, maxTry, addr)
+	return TCPCheckResponse{
+		Result:   result,
+		Target:   req.Target,
+		TargetIp: targetIP,
+		Message:  message,
+		Latency:  latency,
+	}
+}
 
-			// 发送进度消息
-			progressChan <- map[string]interface{}{
-				"current": i,
-				"total":   maxTry,
-				"target":  req.Target,
-				"address": addr,
-			}
+// ----------------- 探测公共逻辑 -----------------
 
-			conn, err := net.DialTimeout("tcp", addr, 1*time.Second)
-			if err == nil {
-				conn.Close()
-				result = true
-				utils.Logger.Infof("✅ 检测成功：目标 %s 可访问", addr)
-				doneChan <- true
-				return
-			} else {
-				utils.Logger.Warnf("⚠️ 第 %d 次检测失败：%v", i, err)
-				if i == maxTry {
-					doneChan <- false
-					errorChan <- err
-					return
-				}
-			}
+// maxProbeTries 单次检测最多尝试次数
+const maxProbeTries = 5
+
+// errVerifyFailed 目标可以连接，但应用层校验未通过（不再重试，也不视为无法连接）
+var errVerifyFailed = errors.New("校验未通过")
+
+// progressFunc 探测进度回调
+type progressFunc func(current int, total int, address string)
+
+// LatencyStats 多次探测的耗时统计（毫秒）
+type LatencyStats struct {
+	Min     float64 `json:"min"`
+	Avg     float64 `json:"avg"`
+	Max     float64 `json:"max"`
+	Jitter  float64 `json:"jitter"`  // 相邻两次耗时差值的平均值
+	Samples int     `json:"samples"` // 成功探测的次数
+}
+
+// newLatencyStats 根据成功探测的耗时计算统计值，无样本时返回 nil
+func newLatencyStats(samples []time.Duration) *LatencyStats {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	stats := &LatencyStats{Min: ms(samples[0]), Max: ms(samples[0]), Samples: len(samples)}
+	var sum, diffSum float64
+	for i, d := range samples {
+		v := ms(d)
+		sum += v
+		stats.Min = math.Min(stats.Min, v)
+		stats.Max = math.Max(stats.Max, v)
+		if i > 0 {
+			diffSum += math.Abs(v - ms(samples[i-1]))
 		}
-		doneChan <- result
+	}
+	// 保留到微秒精度
+	round := func(v float64) float64 { return math.Round(v*1000) / 1000 }
+	stats.Avg = round(sum / float64(len(samples)))
+	if len(samples) > 1 {
+		stats.Jitter = round(diffSum / float64(len(samples)-1))
+	}
+	return stats
+}
+
+// attemptProbe 最多执行 maxProbeTries 次单次探测，返回是否成功、结果消息与耗时统计
+// 任一次成功即视为连通，执行全部 maxProbeTries 次并按成功的各次统计耗时；
+// 连接类错误会继续重试；校验类错误（errVerifyFailed）直接结束，没有任何一次成功时判定校验失败
+func attemptProbe(addr string, progress progressFunc, attempt func() error) (bool, string, *LatencyStats) {
+	var lastErr error
+	var samples []time.Duration
+	for i := 1; i <= maxProbeTries; i++ {
+		utils.Logger.Infof("🔍 正在检测第 %d/%d 次连接：%s ...", i, maxProbeTries, addr)
+		if progress != nil {
+			progress(i, maxProbeTries, addr)
+		}
+
+		middleware.AcquireProbe()
+		start := time.Now()
+		err := attempt()
+		middleware.ReleaseProbe()
+		if err == nil {
+			samples = append(samples, time.Since(start))
+			continue
+		}
+		lastErr = err
+		utils.Logger.Warnf("⚠️ 第 %d 次检测失败：%v", i, err)
+		if errors.Is(err, errVerifyFailed) {
+			break
+		}
+	}
+
+	if len(samples) > 0 {
+		stats := newLatencyStats(samples)
+		utils.Logger.Infof("✅ 检测成功：目标 %s 可访问，耗时 min/avg/max/jitter = %.1f/%.1f/%.1f/%.1f ms",
+			addr, stats.Min, stats.Avg, stats.Max, stats.Jitter)
+		return true, "", stats
+	}
+	if errors.Is(lastErr, errVerifyFailed) {
+		utils.Logger.Warnf("❌ 检测结束：目标 %s 校验失败: %v", addr, lastErr)
+		return false, fmt.Sprintf("检测结束,目标 %s校验失败: %v", addr, lastErr), nil
+	}
+	utils.Logger.Warnf("❌ 检测结束：目标 %s 无法连接: %v", addr, lastErr)
+	return false, fmt.Sprintf("检测结束,目标 %s无法连接: %v", addr, lastErr), nil
+}
+
+// streamProbe 在后台执行探测，并逐行流式返回进度消息（Code=1）与最终结果（Code=0）
+func streamProbe[T any](c *gin.Context, target string, run func(progress progressFunc) T) {
+	// 进度消息数量不超过最大尝试次数，缓冲区足够时客户端断开也不会阻塞探测协程
+	progressChan := make(chan map[string]interface{}, maxProbeTries)
+	resultChan := make(chan T, 1)
+
+	go func() {
+		defer close(progressChan)
+		resultChan <- run(func(current int, total int, address string) {
+			progressChan <- map[string]interface{}{
+				"current": current,
+				"total":   total,
+				"target":  target,
+				"address": address,
+			}
+		})
 	}()
 
-	// 流式发送响应
 	c.Stream(func(w io.Writer) bool {
-		select {
-		case progress, ok := <-progressChan:
-			if !ok {
-				return false
-			}
+		if progress, ok := <-progressChan; ok {
 			// 发送进度消息
-			progressResp := APIResponse[map[string]interface{}]{
+			writeStreamLine(w, APIResponse[map[string]interface{}]{
 				Code:    1, // Code=1 表示进度消息
 				Message: "progress",
 				Data:    progress,
-			}
-			respBytes, _ := json.Marshal(progressResp)
-			w.Write(respBytes)
-			w.Write([]byte("\n"))
+			})
 			return true
-		case done := <-doneChan:
-			if done {
-				Message = ""
-			} else {
-				err := <-errorChan
-				Message = fmt.Sprintf("检测结束,目标 %s无法连接: %v", addr, err)
-				utils.Logger.Warnf("❌ 检测结束：目标 %s 无法连接: %v", addr, err)
-			}
-
-			// 获取本机公网 IP
-			backendPublicIP := getPublicIP()
-
-			// 发送最终结果
-			finalResp := APIResponse[TCPCheckResponse]{
-				Code:    0,
-				Message: "success",
-				Data: TCPCheckResponse{
-					Result:          result,
-					Target:          req.Target,
-					TargetIp:        targetIP,
-					Message:         Message,
-					BackendPublicIP: backendPublicIP,
-				},
-			}
-			respBytes, _ := json.Marshal(finalResp)
-			w.Write(respBytes)
-			w.Write([]byte("\n"))
-			return false
 		}
+
+		// 发送最终结果
+		writeStreamLine(w, APIResponse[T]{
+			Code:    0,
+			Message: "success",
+			Data:    <-resultChan,
+		})
+		return false
 	})
 }
 
+// writeStreamLine 写入一行 JSON 响应
+func writeStreamLine(w io.Writer, v any) {
+	respBytes, _ := json.Marshal(v)
+	w.Write(respBytes)
+	w.Write([]byte("\n"))
+}
+
+// redactKey 记录请求体日志时隐藏兼容模式下请求体中的明文通信密钥
+func redactKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	return "***"
+}
+
 // resolveIPHandler 只解析域名获取 IP，不进行连通性检测
 func resolveIPHandler(c *gin.Context) {
 	var req TCPCheckRequest
@@ -206,25 +378,30 @@ func resolveIPHandler(c *gin.Context) {
 		return
 	}
 
-	utils.Logger.Infof("解析IP请求体数据: %+v", req)
+	logged := req
+	logged.Key = redactKey(req.Key)
+	utils.Logger.Infof("解析IP请求体数据: %+v", logged)
 
 	// 校验通信密钥
 	if !middleware.ValidateBackendKey(req.Key, c) {
 		return
 	}
 
-	// 获取目标 IP
-	var targetIP string
-	var Message string
-	targetIPs, err := net.LookupIP(req.Target)
-	if err == nil && len(targetIPs) > 0 {
-		targetIP = targetIPs[0].String()
-		Message = ""
-		utils.Logger.Infof("✅ 成功解析 %s 的 IP: %s", req.Target, targetIP)
-	} else {
-		utils.Logger.Warnf("⚠️ 无法解析目标 %s 的 IP, 错误消息: %v", req.Target, err)
-		targetIP = ""
-		Message = fmt.Sprintf("无法解析目标 %s的IP, 错误消息: %v", req.Target, err)
+	if err := validateResolveParams(req.Resolver, &req.Family); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: 400, Message: "请求参数错误：" + err.Error()})
+		return
+	}
+
+	// 获取目标的全部 IP
+	res, Message := resolveTargets(req.Target, req.Resolver, req.Family)
+	targetIP := ""
+	addresses := make([]AddressResult, 0, len(res.IPs))
+	for _, ip := range res.IPs {
+		addresses = append(addresses, AddressResult{IP: ip})
+	}
+	if len(res.IPs) > 0 {
+		targetIP = res.IPs[0]
+		utils.Logger.Infof("✅ 成功解析 %s 的 IP: %v (解析器: %s, TTL: %d)", req.Target, res.IPs, res.Resolver, res.TTL)
 	}
 
 	// 获取本机公网 IP
@@ -240,31 +417,9 @@ func resolveIPHandler(c *gin.Context) {
 			TargetIp:        targetIP,
 			Message:         Message,
 			BackendPublicIP: backendPublicIP,
+			Addresses:       addresses,
+			Resolver:        res.Resolver,
+			TTL:             res.TTL,
 		},
 	})
 }
-
-// ----------------- 获取公网 IP -----------------
-func getPublicIP() string {
-	client := http.Client{
-		Timeout: 2 * time.Second,
-	}
-	resp, err := client.Get("https://ipinfo.io/json")
-	if err != nil {
-		return ""
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return ""
-	}
-
-	var data struct {
-		IP string `json:"ip"`
-	}
-	if err := json.Unmarshal(body, &data); err != nil {
-		return ""
-	}
-	return data.IP
-}
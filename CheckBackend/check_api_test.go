@@ -0,0 +1,83 @@
+package CheckBackend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+func TestMain(m *testing.M) {
+	config.Global = &config.Config{}
+	utils.Logger = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+func TestAttemptProbe(t *testing.T) {
+	errConn := errors.New("connection refused")
+	errVerify := fmt.Errorf("%w: 状态码 500", errVerifyFailed)
+
+	tests := []struct {
+		name     string
+		results  []error // 依次返回的单次探测结果，超出部分视为成功
+		wantOK   bool
+		wantRuns int
+		samples  int
+	}{
+		{name: "全部成功时执行全部尝试", results: nil, wantOK: true, wantRuns: maxProbeTries, samples: maxProbeTries},
+		{name: "连接失败后重试成功", results: []error{errConn, errConn}, wantOK: true, wantRuns: maxProbeTries, samples: maxProbeTries - 2},
+		{name: "全部连接失败", results: []error{errConn, errConn, errConn, errConn, errConn}, wantOK: false, wantRuns: maxProbeTries},
+		{name: "首次校验失败直接结束", results: []error{errVerify}, wantOK: false, wantRuns: 1},
+		{name: "成功后校验失败仍视为连通", results: []error{nil, errVerify}, wantOK: true, wantRuns: 2, samples: 1},
+		{name: "尝试次数用尽时使用已有样本", results: []error{errConn, nil, errConn, errConn, nil}, wantOK: true, wantRuns: maxProbeTries, samples: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := 0
+			ok, message, stats := attemptProbe("127.0.0.1:80", nil, func() error {
+				runs++
+				if runs <= len(tt.results) {
+					return tt.results[runs-1]
+				}
+				return nil
+			})
+
+			if ok != tt.wantOK {
+				t.Fatalf("result = %v, want %v (message %q)", ok, tt.wantOK, message)
+			}
+			if runs != tt.wantRuns {
+				t.Errorf("attempts = %d, want %d", runs, tt.wantRuns)
+			}
+			if !tt.wantOK {
+				if stats != nil {
+					t.Errorf("stats = %+v, want nil", stats)
+				}
+				return
+			}
+			if stats == nil || stats.Samples != tt.samples {
+				t.Errorf("stats = %+v, want %d samples", stats, tt.samples)
+			}
+		})
+	}
+}
+
+func TestRedactKey(t *testing.T) {
+	req := TCPCheckRequest{Target: "example.com", Port: 443, Key: "secret-key"}
+	logged := req
+	logged.Key = redactKey(req.Key)
+	if text := fmt.Sprintf("%+v", logged); strings.Contains(text, "secret-key") {
+		t.Errorf("日志中出现明文密钥: %s", text)
+	}
+	if req.Key != "secret-key" {
+		t.Error("redactKey 修改了原请求")
+	}
+	if got := redactKey(""); got != "" {
+		t.Errorf(`redactKey("") = %q, want ""`, got)
+	}
+}
@@ -0,0 +1,176 @@
+package CheckBackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// defaultEgressRefresh 默认出口 IP 刷新间隔
+const defaultEgressRefresh = 600 * time.Second
+
+// defaultEgressURLs 未配置 urls 时使用的回显地址
+var defaultEgressURLs = []string{
+	"https://ipinfo.io/json",
+	"https://api.ipify.org",
+	"https://ifconfig.me/ip",
+}
+
+// egressSource 出口 IP 来源
+type egressSource interface {
+	name() string
+	lookup() (string, error)
+}
+
+// staticEgress 配置中的固定出口 IP
+type staticEgress string
+
+func (s staticEgress) name() string { return "static" }
+
+func (s staticEgress) lookup() (string, error) {
+	if net.ParseIP(string(s)) == nil {
+		return "", fmt.Errorf("无效的固定出口 IP: %s", string(s))
+	}
+	return string(s), nil
+}
+
+// interfaceEgress 从本机网卡读取第一个全局单播地址，优先 IPv4
+type interfaceEgress string
+
+func (i interfaceEgress) name() string { return "interface:" + string(i) }
+
+func (i interfaceEgress) lookup() (string, error) {
+	iface, err := net.InterfaceByName(string(i))
+	if err != nil {
+		return "", err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", err
+	}
+	var v6 string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+		if v6 == "" {
+			v6 = ipNet.IP.String()
+		}
+	}
+	if v6 == "" {
+		return "", fmt.Errorf("网卡 %s 没有全局单播地址", string(i))
+	}
+	return v6, nil
+}
+
+// urlEgress 回显请求来源 IP 的地址，响应为纯文本 IP 或含 ip 字段的 JSON
+type urlEgress string
+
+func (u urlEgress) name() string { return string(u) }
+
+func (u urlEgress) lookup() (string, error) {
+	client := http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(string(u))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(string(body))
+	if strings.HasPrefix(text, "{") {
+		var data struct {
+			IP string `json:"ip"`
+		}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return "", err
+		}
+		text = data.IP
+	}
+	if net.ParseIP(text) == nil {
+		return "", fmt.Errorf("响应不是有效的 IP: %.64s", text)
+	}
+	return text, nil
+}
+
+// egressSources 按配置顺序构造出口 IP 来源：static → interface → urls
+func egressSources() []egressSource {
+	ec := config.Global.BackendListen.EgressIP
+	var sources []egressSource
+	if ec.Static != "" {
+		sources = append(sources, staticEgress(ec.Static))
+	}
+	if ec.Interface != "" {
+		sources = append(sources, interfaceEgress(ec.Interface))
+	}
+	urls := ec.URLs
+	if len(urls) == 0 {
+		urls = defaultEgressURLs
+	}
+	for _, u := range urls {
+		sources = append(sources, urlEgress(u))
+	}
+	return sources
+}
+
+var (
+	egressMu sync.RWMutex
+	egressIP string
+)
+
+// refreshEgressIP 依次尝试各来源，取第一个成功的结果更新缓存；全部失败时保留上次的值
+func refreshEgressIP() {
+	for _, src := range egressSources() {
+		ip, err := src.lookup()
+		if err != nil {
+			utils.Logger.Warnf("⚠️ 获取出口 IP 失败 (%s): %v", src.name(), err)
+			continue
+		}
+		egressMu.Lock()
+		if ip != egressIP {
+			utils.Logger.Infof("🌐 检测出口 IP: %s (来源: %s)", ip, src.name())
+		}
+		egressIP = ip
+		egressMu.Unlock()
+		return
+	}
+}
+
+// StartEgressIPRefresher 启动时获取出口 IP，并按配置间隔定时刷新
+func StartEgressIPRefresher() {
+	interval := time.Duration(config.Global.BackendListen.EgressIP.Refresh) * time.Second
+	if interval <= 0 {
+		interval = defaultEgressRefresh
+	}
+	refreshEgressIP()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		refreshEgressIP()
+	}
+}
+
+// getPublicIP 返回缓存的检测出口 IP，尚未获取成功时为空
+func getPublicIP() string {
+	egressMu.RLock()
+	defer egressMu.RUnlock()
+	return egressIP
+}
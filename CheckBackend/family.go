@@ -0,0 +1,87 @@
+package CheckBackend
+
+import (
+	"fmt"
+	"golang.org/x/net/dns/dnsmessage"
+	"net"
+	"strings"
+)
+
+// 探测的地址族
+const (
+	familyBoth = "both" // IPv4 与 IPv6（默认）
+	familyIPv4 = "ipv4"
+	familyIPv6 = "ipv6"
+)
+
+// normalizeFamily 校验并规范化请求中的地址族，为空时视为 both
+func normalizeFamily(family *string) error {
+	switch strings.ToLower(strings.TrimSpace(*family)) {
+	case "", "both", "dual":
+		*family = familyBoth
+	case "4", "v4", "ipv4":
+		*family = familyIPv4
+	case "6", "v6", "ipv6":
+		*family = familyIPv6
+	default:
+		return fmt.Errorf("不支持的地址族: %s", *family)
+	}
+	return nil
+}
+
+// validateResolveParams 校验请求中的解析器与地址族参数
+func validateResolveParams(resolver string, family *string) error {
+	if err := normalizeFamily(family); err != nil {
+		return err
+	}
+	_, err := findResolver(resolver)
+	return err
+}
+
+// familyMatches 判断 IP 是否属于指定地址族
+func familyMatches(ip net.IP, family string) bool {
+	switch family {
+	case familyIPv4:
+		return ip.To4() != nil
+	case familyIPv6:
+		return ip.To4() == nil
+	default:
+		return true
+	}
+}
+
+// familyNetwork 返回系统解析器查询使用的网络类型
+func familyNetwork(family string) string {
+	switch family {
+	case familyIPv4:
+		return "ip4"
+	case familyIPv6:
+		return "ip6"
+	default:
+		return "ip"
+	}
+}
+
+// familyQueryTypes 返回上游解析器需要查询的记录类型
+func familyQueryTypes(family string) []dnsmessage.Type {
+	switch family {
+	case familyIPv4:
+		return []dnsmessage.Type{dnsmessage.TypeA}
+	case familyIPv6:
+		return []dnsmessage.Type{dnsmessage.TypeAAAA}
+	default:
+		return []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}
+	}
+}
+
+// familyRecordTypes 地址族对应的记录类型展示文本
+func familyRecordTypes(family string) string {
+	switch family {
+	case familyIPv4:
+		return "A"
+	case familyIPv6:
+		return "AAAA"
+	default:
+		return "A/AAAA"
+	}
+}
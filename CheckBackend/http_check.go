@@ -0,0 +1,231 @@
+package CheckBackend
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"telegram-auto-switch-dns-bot/middleware"
+	"telegram-auto-switch-dns-bot/utils"
+	"time"
+)
+
+// httpBodyReadLimit 校验响应体时最多读取的字节数
+const httpBodyReadLimit = 64 * 1024
+
+// HTTPCheckRequest HTTP(S) 应用层探测请求参数
+type HTTPCheckRequest struct {
+	Target       string `json:"target" binding:"required"`
+	Port         int    `json:"port" binding:"required"`
+	Key          string `json:"key"`
+	Scheme       string `json:"scheme"`        // http / https，默认 http
+	Method       string `json:"method"`        // GET / HEAD，默认 GET
+	Host         string `json:"host"`          // Host 头（https 同时作为 SNI），默认使用 target
+	Path         string `json:"path"`          // 请求路径，默认 /
+	StatusMin    int    `json:"status_min"`    // 期望状态码下限，默认 200
+	StatusMax    int    `json:"status_max"`    // 期望状态码上限，默认 399
+	BodyContains string `json:"body_contains"` // 响应体需包含的字符串（可选）
+	BodyRegex    string `json:"body_regex"`    // 响应体需匹配的正则（可选）
+	Resolver     string `json:"resolver"`      // 解析目标使用的解析器名称，为空时使用 default_resolver
+	Family       string `json:"family"`        // 探测的地址族: ipv4 / ipv6 / both，默认 both
+}
+
+// HTTPCheckResponse HTTP(S) 探测结果
+type HTTPCheckResponse struct {
+	Result          bool            `json:"result"`              // true / false
+	Target          string          `json:"target"`              // 检测目标
+	TargetIp        string          `json:"target_ip"`           // 检测目标ip
+	Message         string          `json:"message"`             // 检测返回的消息
+	BackendPublicIP string          `json:"backend_public_ip"`   // 本机公网 IP
+	StatusCode      int             `json:"status_code"`         // 最后一次请求的状态码
+	Latency         *LatencyStats   `json:"latency,omitempty"`   // 请求耗时统计
+	Addresses       []AddressResult `json:"addresses,omitempty"` // 目标解析到的每个 IP 的结果
+	Resolver        string          `json:"resolver"`            // 应答的解析器名称
+	TTL             uint32          `json:"ttl"`                 // 解析应答的 TTL（秒），系统解析器为 0
+}
+
+func httpCheckHandler(c *gin.Context) {
+	var req HTTPCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Logger.Error("HTTP检测接口绑定JSON请求体错误:", err)
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: 400, Message: "请求参数错误：" + err.Error()})
+		return
+	}
+
+	logged := req
+	logged.Key = redactKey(req.Key)
+	utils.Logger.Infof("HTTP检测请求体数据: %+v", logged)
+
+	// 校验通信密钥
+	if !middleware.ValidateBackendKey(req.Key, c) {
+		return
+	}
+
+	bodyRegex, err := normalizeHTTPCheckRequest(&req)
+	if err == nil {
+		err = validateResolveParams(req.Resolver, &req.Family)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: 400, Message: "请求参数错误：" + err.Error()})
+		return
+	}
+
+	streamProbe(c, req.Target, func(progress progressFunc) HTTPCheckResponse {
+		return runHTTPProbe(req, bodyRegex, progress)
+	})
+}
+
+// normalizeHTTPCheckRequest 填充默认值并校验参数，返回编译好的响应体正则（可为 nil）
+func normalizeHTTPCheckRequest(req *HTTPCheckRequest) (*regexp.Regexp, error) {
+	req.Scheme = strings.ToLower(strings.TrimSpace(req.Scheme))
+	if req.Scheme == "" {
+		req.Scheme = "http"
+	}
+	if req.Scheme != "http" && req.Scheme != "https" {
+		return nil, fmt.Errorf("不支持的协议: %s", req.Scheme)
+	}
+
+	req.Method = strings.ToUpper(strings.TrimSpace(req.Method))
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return nil, fmt.Errorf("不支持的请求方法: %s", req.Method)
+	}
+
+	if req.Host == "" {
+		req.Host = req.Target
+	}
+	if !strings.HasPrefix(req.Path, "/") {
+		req.Path = "/" + req.Path
+	}
+	if req.StatusMin <= 0 {
+		req.StatusMin = 200
+	}
+	if req.StatusMax <= 0 {
+		req.StatusMax = 399
+	}
+	if req.StatusMin > req.StatusMax {
+		return nil, fmt.Errorf("状态码范围错误: %d-%d", req.StatusMin, req.StatusMax)
+	}
+
+	if req.BodyRegex == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(req.BodyRegex)
+	if err != nil {
+		return nil, fmt.Errorf("响应体正则错误: %w", err)
+	}
+	return re, nil
+}
+
+// runHTTPProbe 解析目标并向每个解析 IP 发起 HTTP(S) 请求，返回最佳 IP 的结果
+func runHTTPProbe(req HTTPCheckRequest, bodyRegex *regexp.Regexp, progress progressFunc) HTTPCheckResponse {
+	res, message := resolveProbeTargets(req.Target, req.Port, req.Resolver, req.Family)
+	if len(res.IPs) == 0 {
+		return HTTPCheckResponse{
+			Result:          false,
+			Target:          req.Target,
+			Message:         message,
+			BackendPublicIP: getPublicIP(),
+			Resolver:        res.Resolver,
+		}
+	}
+
+	best, addresses := probeEachAddress(res.IPs, progress, func(ip string, progress progressFunc) HTTPCheckResponse {
+		return probeHTTPAddress(req, bodyRegex, ip, progress)
+	})
+	best.Addresses = addresses
+	best.Resolver, best.TTL = res.Resolver, res.TTL
+	best.BackendPublicIP = getPublicIP()
+	return best
+}
+
+// probeHTTPAddress 向单个 IP 发起 HTTP(S) 请求，校验状态码与响应体
+func probeHTTPAddress(req HTTPCheckRequest, bodyRegex *regexp.Regexp, targetIP string, progress progressFunc) HTTPCheckResponse {
+
+	addr := net.JoinHostPort(targetIP, strconv.Itoa(req.Port))
+	client := newPinnedHTTPClient(addr, req.Host)
+	// URL 使用 Host 头中的域名，实际连接始终指向已解析的目标地址
+	url := fmt.Sprintf("%s://%s%s", req.Scheme, net.JoinHostPort(req.Host, strconv.Itoa(req.Port)), req.Path)
+
+	statusCode := 0
+	result, message, latency := attemptProbe(addr, progress, func() error {
+		code, err := doHTTPProbe(client, req, url, bodyRegex)
+		statusCode = code
+		return err
+	})
+
+	return HTTPCheckResponse{
+		Result:     result,
+		Target:     req.Target,
+		TargetIp:   targetIP,
+		Message:    message,
+		StatusCode: statusCode,
+		Latency:    latency,
+	}
+}
+
+// newPinnedHTTPClient 创建始终连接 addr 的 HTTP 客户端（不跟随重定向）
+func newPinnedHTTPClient(addr string, serverName string) *http.Client {
+	dialer := &net.Dialer{Timeout: 2 * time.Second}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		// 证书有效性由 TLS 探测负责，这里只关心应用层响应
+		TLSClientConfig:       &tls.Config{ServerName: serverName, InsecureSkipVerify: true},
+		TLSHandshakeTimeout:   2 * time.Second,
+		ResponseHeaderTimeout: 3 * time.Second,
+		DisableKeepAlives:     true,
+	}
+	return &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// doHTTPProbe 执行一次 HTTP 请求，返回状态码；状态码或响应体不符合预期时返回 errVerifyFailed
+func doHTTPProbe(client *http.Client, req HTTPCheckRequest, url string, bodyRegex *regexp.Regexp) (int, error) {
+	httpReq, err := http.NewRequest(req.Method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	httpReq.Host = req.Host
+	httpReq.Header.Set("User-Agent", "telegram-auto-switch-dns-bot/probe")
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < req.StatusMin || resp.StatusCode > req.StatusMax {
+		return resp.StatusCode, fmt.Errorf("%w: 状态码 %d 不在 %d-%d 范围内", errVerifyFailed, resp.StatusCode, req.StatusMin, req.StatusMax)
+	}
+
+	if req.Method == http.MethodHead || (req.BodyContains == "" && bodyRegex == nil) {
+		return resp.StatusCode, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, httpBodyReadLimit))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if req.BodyContains != "" && !strings.Contains(string(body), req.BodyContains) {
+		return resp.StatusCode, fmt.Errorf("%w: 响应体不包含 %q", errVerifyFailed, req.BodyContains)
+	}
+	if bodyRegex != nil && !bodyRegex.Match(body) {
+		return resp.StatusCode, fmt.Errorf("%w: 响应体不匹配正则 %q", errVerifyFailed, req.BodyRegex)
+	}
+	return resp.StatusCode, nil
+}
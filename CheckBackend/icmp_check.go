@@ -0,0 +1,125 @@
+package CheckBackend
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// icmpEchoData ICMP 回显请求携带的数据，用于识别自己的回包
+var icmpEchoData = []byte("telegram-auto-switch-dns-bot")
+
+// icmpEchoIDs Echo ID 计数；原始套接字会收到本机全部 ICMP 回包，每次探测使用不同的 ID 区分并发探测
+var icmpEchoIDs atomic.Uint32
+
+// nextICMPEchoID 返回本次探测使用的 Echo ID
+func nextICMPEchoID() int {
+	return int((uint32(os.Getpid()) + icmpEchoIDs.Add(1)) & 0xffff)
+}
+
+// probeICMP 向 ip 发送一次 ICMP Echo 并等待对应的 Echo Reply
+func probeICMP(ip net.IP, seq int) error {
+	isV6 := ip.To4() == nil
+	conn, privileged, err := listenICMP(isV6)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	proto := 1 // iana.ProtocolICMP
+	if isV6 {
+		echoType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+		proto = 58 // iana.ProtocolIPv6ICMP
+	}
+
+	id := nextICMPEchoID()
+	msg := icmp.Message{
+		Type: echoType,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: icmpEchoData},
+	}
+	wb, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+
+	// 非特权 ping 套接字使用 UDPAddr，原始套接字使用 IPAddr
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	if privileged {
+		dst = &net.IPAddr{IP: ip}
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(1 * time.Second)); err != nil {
+		return err
+	}
+	if _, err := conn.WriteTo(wb, dst); err != nil {
+		return err
+	}
+
+	rb := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(rb)
+		if err != nil {
+			return err
+		}
+		if !sameIP(peer, ip) {
+			continue
+		}
+		rm, err := icmp.ParseMessage(proto, rb[:n])
+		if err != nil {
+			continue
+		}
+		if isEchoReply(rm, replyType, id, seq, privileged) {
+			return nil
+		}
+	}
+}
+
+// isEchoReply 判断回包是否为本次探测的 Echo Reply
+// 原始套接字校验 ID、序号与数据；非特权套接字的 ID 由内核改写且只收到本套接字的回包，只校验序号与数据
+func isEchoReply(rm *icmp.Message, replyType icmp.Type, id int, seq int, checkID bool) bool {
+	if rm.Type != replyType {
+		return false
+	}
+	echo, ok := rm.Body.(*icmp.Echo)
+	if !ok || echo.Seq != seq || !bytes.Equal(echo.Data, icmpEchoData) {
+		return false
+	}
+	return !checkID || echo.ID == id
+}
+
+// listenICMP 优先使用非特权 ping 套接字（需 net.ipv4.ping_group_range 允许），失败时回退到原始套接字
+func listenICMP(isV6 bool) (*icmp.PacketConn, bool, error) {
+	udpNetwork, rawNetwork, address := "udp4", "ip4:icmp", "0.0.0.0"
+	if isV6 {
+		udpNetwork, rawNetwork, address = "udp6", "ip6:ipv6-icmp", "::"
+	}
+
+	conn, err := icmp.ListenPacket(udpNetwork, address)
+	if err == nil {
+		return conn, false, nil
+	}
+	rawConn, rawErr := icmp.ListenPacket(rawNetwork, address)
+	if rawErr == nil {
+		return rawConn, true, nil
+	}
+	return nil, false, fmt.Errorf("无法创建 ICMP 套接字: %w", errors.Join(err, rawErr))
+}
+
+// sameIP 判断回包来源地址是否为探测目标
+func sameIP(addr net.Addr, ip net.IP) bool {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP.Equal(ip)
+	case *net.IPAddr:
+		return a.IP.Equal(ip)
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package CheckBackend
+
+import (
+	"testing"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+func TestIsEchoReply(t *testing.T) {
+	reply := func(id, seq int, data []byte) *icmp.Message {
+		return &icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: &icmp.Echo{ID: id, Seq: seq, Data: data}}
+	}
+
+	tests := []struct {
+		name    string
+		msg     *icmp.Message
+		checkID bool
+		want    bool
+	}{
+		{name: "原始套接字匹配", msg: reply(100, 3, icmpEchoData), checkID: true, want: true},
+		{name: "原始套接字其他探测的 ID", msg: reply(101, 3, icmpEchoData), checkID: true, want: false},
+		{name: "原始套接字序号不同", msg: reply(100, 4, icmpEchoData), checkID: true, want: false},
+		{name: "非特权套接字忽略 ID", msg: reply(7, 3, icmpEchoData), checkID: false, want: true},
+		{name: "非特权套接字序号不同", msg: reply(7, 2, icmpEchoData), checkID: false, want: false},
+		{name: "数据不同", msg: reply(100, 3, []byte("other ping")), checkID: true, want: false},
+		{name: "不是 Echo Reply", msg: &icmp.Message{Type: ipv4.ICMPTypeEcho, Body: &icmp.Echo{ID: 100, Seq: 3, Data: icmpEchoData}}, checkID: true, want: false},
+		{name: "目标不可达", msg: &icmp.Message{Type: ipv4.ICMPTypeDestinationUnreachable, Body: &icmp.DstUnreach{}}, checkID: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isEchoReply(tt.msg, ipv4.ICMPTypeEchoReply, 100, 3, tt.checkID); got != tt.want {
+				t.Errorf("isEchoReply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextICMPEchoID(t *testing.T) {
+	seen := make(map[int]bool)
+	for i := 0; i < 1000; i++ {
+		id := nextICMPEchoID()
+		if id < 0 || id > 0xffff {
+			t.Fatalf("nextICMPEchoID() = %d, out of range", id)
+		}
+		if seen[id] {
+			t.Fatalf("nextICMPEchoID() returned duplicate %d", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,79 @@
+package CheckBackend
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/middleware"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// startedAt 检测后端启动时间
+var startedAt = time.Now()
+
+// BackendInfoResponse 检测后端的版本、运行状态与能力
+type BackendInfoResponse struct {
+	Version         string    `json:"version"`
+	StartedAt       time.Time `json:"started_at"`
+	Uptime          int64     `json:"uptime"`           // 运行时长（秒）
+	Probes          []string  `json:"probes"`           // 支持的探测类型
+	ICMPPrivileged  bool      `json:"icmp_privileged"`  // ICMP 是否使用原始套接字（否则为非特权 ping 套接字）
+	Resolvers       []string  `json:"resolvers"`        // 可选的上游解析器名称
+	DefaultResolver string    `json:"default_resolver"` // 默认解析器，为空表示系统解析器
+	TLS             bool      `json:"tls"`              // 是否以 HTTPS 提供服务
+	MutualTLS       bool      `json:"mutual_tls"`       // 是否要求客户端证书
+	MaxInflight     int       `json:"max_inflight"`     // 同时进行的单次探测上限，0 表示不限制
+	Inflight        int       `json:"inflight"`         // 正在进行的单次探测数
+	MaxBatchTargets int       `json:"max_batch_targets"`
+	MaxBatchWorkers int       `json:"max_batch_workers"`
+	MaxAddresses    int       `json:"max_addresses"` // 单个目标最多检测的解析 IP 数量
+	PublicIP        string    `json:"public_ip"`     // 检测出口 IP
+}
+
+// supportedProbes 返回本机支持的探测类型；无法创建 ICMP 套接字时不包含 icmp
+func supportedProbes() ([]string, bool) {
+	probes := []string{"tcp", "udp", "http", "https", "tls"}
+	conn, privileged, err := listenICMP(false)
+	if err != nil {
+		return probes, false
+	}
+	_ = conn.Close()
+	return append(probes, "icmp"), privileged
+}
+
+// infoHandler 返回检测后端的版本、运行时长、支持的探测类型、并发上限与出口 IP（GET /api/v1/info）
+func infoHandler(c *gin.Context) {
+	// 校验通信密钥（GET 请求无请求体，兼容模式下密钥通过请求头传递）
+	if !middleware.ValidateBackendKey(c.GetHeader(middleware.HeaderBackendKey), c) {
+		return
+	}
+
+	lc := config.Global.BackendListen
+	resolvers := make([]string, 0, len(lc.Resolvers))
+	for _, rc := range lc.Resolvers {
+		resolvers = append(resolvers, rc.Name)
+	}
+	probes, icmpPrivileged := supportedProbes()
+
+	info := BackendInfoResponse{
+		Version:         config.Version,
+		StartedAt:       startedAt,
+		Uptime:          int64(time.Since(startedAt).Seconds()),
+		Probes:          probes,
+		ICMPPrivileged:  icmpPrivileged,
+		Resolvers:       resolvers,
+		DefaultResolver: lc.DefaultResolver,
+		TLS:             serverTLSEnabled(),
+		MutualTLS:       serverTLSEnabled() && lc.ClientCAFile != "",
+		MaxInflight:     lc.RateLimit.MaxInflight,
+		Inflight:        middleware.InflightCount(),
+		MaxBatchTargets: maxBatchTargets,
+		MaxBatchWorkers: maxBatchConcurrency,
+		MaxAddresses:    maxProbeAddresses,
+		PublicIP:        getPublicIP(),
+	}
+	utils.Logger.Infof("后端信息请求 (%s)", c.ClientIP())
+	c.JSON(http.StatusOK, APIResponse[BackendInfoResponse]{Code: 0, Message: "success", Data: info})
+}
@@ -0,0 +1,334 @@
+package CheckBackend
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"golang.org/x/net/dns/dnsmessage"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"telegram-auto-switch-dns-bot/config"
+	"time"
+)
+
+// systemResolver 系统解析器名称
+const systemResolver = "system"
+
+// dnsMessageLimit DNS 应答最大长度
+const dnsMessageLimit = 65535
+
+// resolution 目标解析结果
+type resolution struct {
+	IPs      []string // 解析到的 IP（去重）
+	Resolver string   // 应答的解析器名称
+	TTL      uint32   // 应答记录的最小 TTL（秒），系统解析器无法获取时为 0
+}
+
+// findResolver 按名称查找配置的解析器，名称为空时使用 default_resolver；返回 nil 表示使用系统解析器
+func findResolver(name string) (*config.ResolverConfig, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = strings.TrimSpace(config.Global.BackendListen.DefaultResolver)
+	}
+	if name == "" || name == systemResolver {
+		return nil, nil
+	}
+
+	for i, r := range config.Global.BackendListen.Resolvers {
+		if r.Name == name {
+			return &config.Global.BackendListen.Resolvers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("未配置的解析器: %s", name)
+}
+
+// lookupTarget 使用指定解析器解析目标属于 family 地址族的 A/AAAA 记录
+func lookupTarget(target string, resolverName string, family string) (resolution, error) {
+	rc, err := findResolver(resolverName)
+	if err != nil {
+		return resolution{Resolver: resolverName}, err
+	}
+
+	// IP 直接返回，不经过解析器
+	if ip := net.ParseIP(target); ip != nil {
+		res := resolution{Resolver: systemResolver}
+		if rc != nil {
+			res.Resolver = rc.Name
+		}
+		if !familyMatches(ip, family) {
+			return res, fmt.Errorf("目标 IP %s 不属于地址族 %s", ip, family)
+		}
+		res.IPs = []string{ip.String()}
+		return res, nil
+	}
+
+	if rc == nil {
+		ips, err := net.DefaultResolver.LookupIP(context.Background(), familyNetwork(family), target)
+		if err != nil {
+			return resolution{Resolver: systemResolver}, err
+		}
+		res := resolution{Resolver: systemResolver}
+		for _, ip := range ips {
+			res.IPs = append(res.IPs, ip.String())
+		}
+		return res, nil
+	}
+
+	// 按地址族并发查询 A / AAAA，任一类型有记录即视为成功
+	var wg sync.WaitGroup
+	types := familyQueryTypes(family)
+	answers := make([][]net.IP, len(types))
+	ttls := make([]uint32, len(types))
+	errs := make([]error, len(types))
+	for i, qtype := range types {
+		wg.Add(1)
+		go func(i int, qtype dnsmessage.Type) {
+			defer wg.Done()
+			answers[i], ttls[i], errs[i] = queryResolver(rc, target, qtype)
+		}(i, qtype)
+	}
+	wg.Wait()
+
+	res := resolution{Resolver: rc.Name}
+	for i := range types {
+		if errs[i] != nil || len(answers[i]) == 0 {
+			continue
+		}
+		for _, ip := range answers[i] {
+			res.IPs = append(res.IPs, ip.String())
+		}
+		if res.TTL == 0 || ttls[i] < res.TTL {
+			res.TTL = ttls[i]
+		}
+	}
+	if len(res.IPs) == 0 {
+		for _, err := range errs {
+			if err != nil {
+				return res, err
+			}
+		}
+		return res, fmt.Errorf("解析器 %s 未返回 %s 的 %s 记录", rc.Name, target, familyRecordTypes(family))
+	}
+	return res, nil
+}
+
+// queryResolver 向上游解析器查询单个类型的记录，返回 IP 与最小 TTL
+func queryResolver(rc *config.ResolverConfig, host string, qtype dnsmessage.Type) ([]net.IP, uint32, error) {
+	fqdn := host
+	if !strings.HasSuffix(fqdn, ".") {
+		fqdn += "."
+	}
+	name, err := dnsmessage.NewName(fqdn)
+	if err != nil {
+		return nil, 0, fmt.Errorf("域名格式错误: %w", err)
+	}
+
+	var id uint16
+	if !strings.EqualFold(rc.Type, "doh") {
+		// DoH 建议使用 ID 0 便于 HTTP 缓存，其余传输使用随机 ID
+		var b [2]byte
+		_, _ = rand.Read(b[:])
+		id = binary.BigEndian.Uint16(b[:])
+	}
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, 0, fmt.Errorf("构建查询失败: %w", err)
+	}
+
+	timeout := time.Duration(rc.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+
+	var reply []byte
+	switch strings.ToLower(rc.Type) {
+	case "", "udp":
+		reply, err = exchangeUDP(resolverAddr(rc.Address, "53"), packed, timeout)
+		if (err == nil && truncated(reply)) || isTimeout(err) {
+			// 应答被截断或 UDP 查询超时（UDP 53 常被丢弃）时改用 TCP 重新查询
+			reply, err = exchangeStream(resolverAddr(rc.Address, "53"), nil, packed, timeout)
+		}
+	case "tcp":
+		reply, err = exchangeStream(resolverAddr(rc.Address, "53"), nil, packed, timeout)
+	case "dot":
+		addr := resolverAddr(rc.Address, "853")
+		serverName := rc.ServerName
+		if serverName == "" {
+			serverName, _, _ = net.SplitHostPort(addr)
+		}
+		reply, err = exchangeStream(addr, &tls.Config{ServerName: serverName}, packed, timeout)
+	case "doh":
+		reply, err = exchangeDoH(rc.Address, packed, timeout)
+	default:
+		return nil, 0, fmt.Errorf("不支持的解析器类型: %s", rc.Type)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("解析器 %s 查询失败: %w", rc.Name, err)
+	}
+
+	return parseDNSReply(reply, id, qtype)
+}
+
+// resolverAddr 为未带端口的解析器地址补充默认端口
+func resolverAddr(address string, defaultPort string) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(strings.Trim(address, "[]"), defaultPort)
+}
+
+// truncated 判断应答是否设置了 TC 位
+func truncated(reply []byte) bool {
+	var p dnsmessage.Parser
+	h, err := p.Start(reply)
+	return err == nil && h.Truncated
+}
+
+// isTimeout 判断是否为网络超时错误
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// exchangeUDP 通过 UDP 发送查询并读取应答
+func exchangeUDP(addr string, query []byte, timeout time.Duration) ([]byte, error) {
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, dnsMessageLimit)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// exchangeStream 通过 TCP（tlsConfig 不为空时为 DoT）发送带长度前缀的查询并读取应答
+func exchangeStream(addr string, tlsConfig *tls.Config, query []byte, timeout time.Duration) ([]byte, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	reply := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// exchangeDoH 按 RFC 8484 以 POST 方式发送 DNS-over-HTTPS 查询
+func exchangeDoH(url string, query []byte, timeout time.Duration) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, dnsMessageLimit))
+}
+
+// parseDNSReply 校验应答并提取对应类型的 IP 与最小 TTL
+func parseDNSReply(reply []byte, id uint16, qtype dnsmessage.Type) ([]net.IP, uint32, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(reply)
+	if err != nil {
+		return nil, 0, fmt.Errorf("应答格式错误: %w", err)
+	}
+	if h.ID != id {
+		return nil, 0, fmt.Errorf("应答 ID 不匹配")
+	}
+	if h.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, fmt.Errorf("应答错误: %s", h.RCode)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, 0, fmt.Errorf("应答格式错误: %w", err)
+	}
+
+	var ips []net.IP
+	var ttl uint32
+	for {
+		rh, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("应答格式错误: %w", err)
+		}
+		if rh.Type != qtype || rh.Class != dnsmessage.ClassINET {
+			// CNAME 等记录直接跳过，递归解析器会一并返回最终的地址记录
+			if err := p.SkipAnswer(); err != nil {
+				return nil, 0, fmt.Errorf("应答格式错误: %w", err)
+			}
+			continue
+		}
+
+		switch qtype {
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				return nil, 0, fmt.Errorf("应答格式错误: %w", err)
+			}
+			ips = append(ips, net.IP(r.A[:]))
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				return nil, 0, fmt.Errorf("应答格式错误: %w", err)
+			}
+			ips = append(ips, net.IP(r.AAAA[:]))
+		}
+		if ttl == 0 || rh.TTL < ttl {
+			ttl = rh.TTL
+		}
+	}
+	return ips, ttl, nil
+}
@@ -0,0 +1,264 @@
+package CheckBackend
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"telegram-auto-switch-dns-bot/config"
+)
+
+// testZone 本地解析服务器的记录
+var testZone = map[dnsmessage.Type]struct {
+	ips []net.IP
+	ttl uint32
+}{
+	dnsmessage.TypeA:    {ips: []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2")}, ttl: 60},
+	dnsmessage.TypeAAAA: {ips: []net.IP{net.ParseIP("2001:db8::1")}, ttl: 120},
+}
+
+// UDP 应答方式
+const (
+	udpAnswer   = "answer"   // 正常应答
+	udpSilent   = "silent"   // 不应答，模拟 UDP 被丢弃
+	udpTruncate = "truncate" // 返回设置 TC 位的空应答
+)
+
+// dnsReply 按 testZone 构建查询的应答，truncate 为 true 时只返回设置 TC 位的空应答
+func dnsReply(t *testing.T, query []byte, truncate bool) []byte {
+	t.Helper()
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil {
+		t.Errorf("解析查询失败: %v", err)
+		return nil
+	}
+
+	reply := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: msg.Header.ID, Response: true, RecursionAvailable: true, Truncated: truncate},
+		Questions: msg.Questions,
+	}
+	if !truncate {
+		for _, q := range msg.Questions {
+			zone := testZone[q.Type]
+			for _, ip := range zone.ips {
+				h := dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: dnsmessage.ClassINET, TTL: zone.ttl}
+				if q.Type == dnsmessage.TypeA {
+					var a dnsmessage.AResource
+					copy(a.A[:], ip.To4())
+					reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: h, Body: &a})
+				} else {
+					var aaaa dnsmessage.AAAAResource
+					copy(aaaa.AAAA[:], ip.To16())
+					reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: h, Body: &aaaa})
+				}
+			}
+		}
+	}
+
+	packed, err := reply.Pack()
+	if err != nil {
+		t.Errorf("构建应答失败: %v", err)
+		return nil
+	}
+	return packed
+}
+
+// startDNSServer 在 127.0.0.1 的同一端口启动 UDP 与 TCP 解析服务，withTCP 为 false 时不监听 TCP，返回地址
+func startDNSServer(t *testing.T, udpMode string, withTCP bool) string {
+	t.Helper()
+
+	var udp net.PacketConn
+	var tcp net.Listener
+	for i := 0; udp == nil; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("监听 TCP 失败: %v", err)
+		}
+		pc, err := net.ListenPacket("udp", ln.Addr().String())
+		if err != nil {
+			ln.Close()
+			if i == 10 {
+				t.Fatalf("监听 UDP 失败: %v", err)
+			}
+			continue
+		}
+		udp, tcp = pc, ln
+	}
+	addr := tcp.Addr().String()
+	t.Cleanup(func() {
+		udp.Close()
+		tcp.Close()
+	})
+	if !withTCP {
+		tcp.Close()
+	}
+
+	go func() {
+		buf := make([]byte, dnsMessageLimit)
+		for {
+			n, from, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if udpMode == udpSilent {
+				continue
+			}
+			if reply := dnsReply(t, buf[:n], udpMode == udpTruncate); reply != nil {
+				_, _ = udp.WriteTo(reply, from)
+			}
+		}
+	}()
+
+	if withTCP {
+		go func() {
+			for {
+				conn, err := tcp.Accept()
+				if err != nil {
+					return
+				}
+				go func(conn net.Conn) {
+					defer conn.Close()
+					var length [2]byte
+					if _, err := io.ReadFull(conn, length[:]); err != nil {
+						return
+					}
+					query := make([]byte, binary.BigEndian.Uint16(length[:]))
+					if _, err := io.ReadFull(conn, query); err != nil {
+						return
+					}
+					reply := dnsReply(t, query, false)
+					msg := make([]byte, 2+len(reply))
+					binary.BigEndian.PutUint16(msg, uint16(len(reply)))
+					copy(msg[2:], reply)
+					_, _ = conn.Write(msg)
+				}(conn)
+			}
+		}()
+	}
+	return addr
+}
+
+// setResolvers 设置测试使用的解析器配置
+func setResolvers(t *testing.T, defaultResolver string, resolvers ...config.ResolverConfig) {
+	t.Helper()
+	old := config.Global
+	config.Global = &config.Config{BackendListen: config.BackendListenConfig{
+		DefaultResolver: defaultResolver,
+		Resolvers:       resolvers,
+	}}
+	t.Cleanup(func() { config.Global = old })
+}
+
+func TestFindResolver(t *testing.T) {
+	setResolvers(t, "local",
+		config.ResolverConfig{Name: "local", Type: "udp", Address: "127.0.0.1"},
+		config.ResolverConfig{Name: "tcp", Type: "tcp", Address: "127.0.0.1"},
+	)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string // 为空表示系统解析器
+		wantErr bool
+	}{
+		{name: "未指定时使用默认解析器", input: "", want: "local"},
+		{name: "按名称选择", input: "tcp", want: "tcp"},
+		{name: "去除空白", input: " tcp ", want: "tcp"},
+		{name: "系统解析器", input: systemResolver, want: ""},
+		{name: "未配置的解析器", input: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, err := findResolver(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findResolver(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			got := ""
+			if rc != nil {
+				got = rc.Name
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("findResolver(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupTarget(t *testing.T) {
+	udpAddr := startDNSServer(t, udpAnswer, true)
+	silentAddr := startDNSServer(t, udpSilent, true)
+	truncAddr := startDNSServer(t, udpTruncate, true)
+	deadAddr := startDNSServer(t, udpSilent, false)
+
+	setResolvers(t, "",
+		config.ResolverConfig{Name: "udp", Type: "udp", Address: udpAddr, Timeout: 1},
+		config.ResolverConfig{Name: "tcp", Type: "tcp", Address: silentAddr, Timeout: 1},
+		config.ResolverConfig{Name: "udp-silent", Type: "udp", Address: silentAddr, Timeout: 1},
+		config.ResolverConfig{Name: "udp-truncate", Type: "udp", Address: truncAddr, Timeout: 1},
+		config.ResolverConfig{Name: "dead", Type: "udp", Address: deadAddr, Timeout: 1},
+	)
+
+	tests := []struct {
+		name     string
+		target   string
+		resolver string
+		family   string
+		wantIPs  []string
+		wantTTL  uint32
+		wantErr  bool
+	}{
+		{name: "UDP 查询 A 记录", target: "example.test", resolver: "udp", family: familyIPv4, wantIPs: []string{"192.0.2.1", "192.0.2.2"}, wantTTL: 60},
+		{name: "UDP 查询 AAAA 记录", target: "example.test", resolver: "udp", family: familyIPv6, wantIPs: []string{"2001:db8::1"}, wantTTL: 120},
+		{name: "双栈取最小 TTL", target: "example.test.", resolver: "udp", family: familyBoth, wantIPs: []string{"192.0.2.1", "192.0.2.2", "2001:db8::1"}, wantTTL: 60},
+		{name: "TCP 查询", target: "example.test", resolver: "tcp", family: familyIPv4, wantIPs: []string{"192.0.2.1", "192.0.2.2"}, wantTTL: 60},
+		{name: "UDP 超时改用 TCP", target: "example.test", resolver: "udp-silent", family: familyIPv4, wantIPs: []string{"192.0.2.1", "192.0.2.2"}, wantTTL: 60},
+		{name: "UDP 应答截断改用 TCP", target: "example.test", resolver: "udp-truncate", family: familyIPv6, wantIPs: []string{"2001:db8::1"}, wantTTL: 120},
+		{name: "UDP 超时且 TCP 不可用", target: "example.test", resolver: "dead", family: familyIPv4, wantErr: true},
+		{name: "IP 目标不经过解析器", target: "198.51.100.7", resolver: "dead", family: familyBoth, wantIPs: []string{"198.51.100.7"}},
+		{name: "IP 目标地址族不匹配", target: "198.51.100.7", resolver: "udp", family: familyIPv6, wantErr: true},
+		{name: "未配置的解析器", target: "example.test", resolver: "missing", family: familyIPv4, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := lookupTarget(tt.target, tt.resolver, tt.family)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookupTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if res.Resolver != tt.resolver {
+				t.Errorf("Resolver = %q, want %q", res.Resolver, tt.resolver)
+			}
+			got := slices.Clone(res.IPs)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.wantIPs) {
+				t.Errorf("IPs = %v, want %v", res.IPs, tt.wantIPs)
+			}
+			if res.TTL != tt.wantTTL {
+				t.Errorf("TTL = %d, want %d", res.TTL, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestResolverAddr(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{address: "127.0.0.1", want: "127.0.0.1:53"},
+		{address: "127.0.0.1:5353", want: "127.0.0.1:5353"},
+		{address: "::1", want: net.JoinHostPort("::1", "53")},
+		{address: "[::1]", want: net.JoinHostPort("::1", "53")},
+		{address: "[::1]:5353", want: "[::1]:5353"},
+	}
+	for _, tt := range tests {
+		if got := resolverAddr(tt.address, "53"); got != tt.want {
+			t.Errorf("resolverAddr(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
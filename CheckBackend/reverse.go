@@ -0,0 +1,88 @@
+package CheckBackend
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/middleware"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// reverseEnabled 是否配置了反向连接
+func reverseEnabled() bool {
+	return config.Global.BackendListen.Reverse.URL != ""
+}
+
+// StartReverseConnect 主动连接 Bot 的反向连接地址并注册，在该连接上接收检测任务交由 handler 执行
+// 断开后按指数退避重连
+func StartReverseConnect(handler http.Handler) {
+	rc := config.Global.BackendListen.Reverse
+	if rc.Name == "" {
+		utils.Logger.Error("反向连接未配置后端名称 (backend_listen.reverse.name)")
+		return
+	}
+
+	backoff := WSMinBackoff
+	for {
+		start := time.Now()
+		conn, err := dialReverse(rc)
+		if err == nil {
+			utils.Logger.Infof("🔌 已反向连接 Bot 并注册为 %s", rc.Name)
+			ServeJobChannel(conn, handler, conn.RemoteAddr().String())
+			err = fmt.Errorf("连接已断开")
+		}
+		// 连接保持足够久后重新从最短退避开始
+		if time.Since(start) > WSMaxBackoff {
+			backoff = WSMinBackoff
+		}
+		utils.Logger.Warnf("⚠️ 反向连接 Bot 失败: %v，%s 后重连", err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > WSMaxBackoff {
+			backoff = WSMaxBackoff
+		}
+	}
+}
+
+// dialReverse 连接 <url>/api/v1/reverse/<name>，请求使用通信密钥签名
+func dialReverse(rc config.ReverseConfig) (*websocket.Conn, error) {
+	wsURL := strings.TrimRight(rc.URL, "/") + "/api/v1/reverse/" + url.PathEscape(rc.Name)
+	req, err := http.NewRequest(http.MethodGet, wsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("反向连接地址错误: %w", err)
+	}
+	middleware.SignBackendRequest(req, nil, config.Global.BackendListen.Key)
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 10 * time.Second,
+	}
+	if rc.CAFile != "" {
+		pem, err := os.ReadFile(rc.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 Bot CA 失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Bot CA 文件中没有有效证书: %s", rc.CAFile)
+		}
+		dialer.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	conn, resp, err := dialer.Dial(wsURL, req.Header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("%w (HTTP %d)", err, resp.StatusCode)
+		}
+		return nil, err
+	}
+	return conn, nil
+}
@@ -0,0 +1,49 @@
+package CheckBackend
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"telegram-auto-switch-dns-bot/config"
+)
+
+// serverTLSEnabled 是否以 HTTPS 提供检测接口
+func serverTLSEnabled() bool {
+	return config.Global.BackendListen.CertFile != "" && config.Global.BackendListen.KeyFile != ""
+}
+
+// buildServerTLSConfig 构造检测后端的 TLS 配置；配置了 client_ca_file 时要求并校验客户端证书
+func buildServerTLSConfig() (*tls.Config, error) {
+	lc := config.Global.BackendListen
+	if !serverTLSEnabled() {
+		if lc.ClientCAFile != "" {
+			return nil, fmt.Errorf("已配置 client_ca_file 但未配置 cert_file/key_file，无法启用双向认证")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(lc.CertFile, lc.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载服务端证书失败: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if lc.ClientCAFile != "" {
+		pem, err := os.ReadFile(lc.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取客户端 CA 失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("客户端 CA 文件中没有有效证书: %s", lc.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,152 @@
+package CheckBackend
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"telegram-auto-switch-dns-bot/middleware"
+	"telegram-auto-switch-dns-bot/utils"
+	"time"
+)
+
+// TLSCheckRequest TLS 握手与证书校验请求参数
+type TLSCheckRequest struct {
+	Target     string `json:"target" binding:"required"`
+	Port       int    `json:"port" binding:"required"`
+	Key        string `json:"key"`
+	ServerName string `json:"server_name"` // SNI 及证书校验域名（一般为主域名），默认使用 target
+	Resolver   string `json:"resolver"`    // 解析目标使用的解析器名称，为空时使用 default_resolver
+	Family     string `json:"family"`      // 探测的地址族: ipv4 / ipv6 / both，默认 both
+}
+
+// TLSCheckResponse TLS 探测结果
+type TLSCheckResponse struct {
+	Result          bool            `json:"result"`              // 握手成功且证书有效、SAN 匹配
+	Target          string          `json:"target"`              // 检测目标
+	TargetIp        string          `json:"target_ip"`           // 检测目标ip
+	Message         string          `json:"message"`             // 检测返回的消息
+	BackendPublicIP string          `json:"backend_public_ip"`   // 本机公网 IP
+	CertValid       bool            `json:"cert_valid"`          // 证书链可信且在有效期内
+	SANMatch        bool            `json:"san_match"`           // 证书 SAN 是否匹配 ServerName
+	DaysLeft        int             `json:"days_left"`           // 证书剩余有效天数（已过期为负数）
+	NotAfter        int64           `json:"not_after"`           // 证书到期时间戳，0 表示未获取到证书
+	Issuer          string          `json:"issuer"`              // 证书颁发者
+	Protocol        string          `json:"protocol"`            // 协商的 TLS 版本
+	Latency         *LatencyStats   `json:"latency,omitempty"`   // 握手耗时统计
+	Addresses       []AddressResult `json:"addresses,omitempty"` // 目标解析到的每个 IP 的结果
+	Resolver        string          `json:"resolver"`            // 应答的解析器名称
+	TTL             uint32          `json:"ttl"`                 // 解析应答的 TTL（秒），系统解析器为 0
+}
+
+func tlsCheckHandler(c *gin.Context) {
+	var req TLSCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Logger.Error("TLS检测接口绑定JSON请求体错误:", err)
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: 400, Message: "请求参数错误：" + err.Error()})
+		return
+	}
+
+	logged := req
+	logged.Key = redactKey(req.Key)
+	utils.Logger.Infof("TLS检测请求体数据: %+v", logged)
+
+	// 校验通信密钥
+	if !middleware.ValidateBackendKey(req.Key, c) {
+		return
+	}
+
+	if err := validateResolveParams(req.Resolver, &req.Family); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: 400, Message: "请求参数错误：" + err.Error()})
+		return
+	}
+
+	if req.ServerName == "" {
+		req.ServerName = req.Target
+	}
+
+	streamProbe(c, req.Target, func(progress progressFunc) TLSCheckResponse {
+		return runTLSProbe(req, progress)
+	})
+}
+
+// runTLSProbe 解析目标并对每个解析 IP 进行 TLS 探测，返回最佳 IP 的结果
+func runTLSProbe(req TLSCheckRequest, progress progressFunc) TLSCheckResponse {
+	res, message := resolveProbeTargets(req.Target, req.Port, req.Resolver, req.Family)
+	if len(res.IPs) == 0 {
+		return TLSCheckResponse{
+			Result:          false,
+			Target:          req.Target,
+			Message:         message,
+			BackendPublicIP: getPublicIP(),
+			Resolver:        res.Resolver,
+		}
+	}
+
+	best, addresses := probeEachAddress(res.IPs, progress, func(ip string, progress progressFunc) TLSCheckResponse {
+		return probeTLSAddress(req, ip, progress)
+	})
+	best.Addresses = addresses
+	best.Resolver, best.TTL = res.Resolver, res.TTL
+	best.BackendPublicIP = getPublicIP()
+	return best
+}
+
+// probeTLSAddress 以 ServerName 作为 SNI 与单个 IP 握手，并校验证书链、SAN 与有效期
+func probeTLSAddress(req TLSCheckRequest, targetIP string, progress progressFunc) TLSCheckResponse {
+	resp := TLSCheckResponse{
+		Target:   req.Target,
+		TargetIp: targetIP,
+	}
+	addr := net.JoinHostPort(targetIP, strconv.Itoa(req.Port))
+	resp.Result, resp.Message, resp.Latency = attemptProbe(addr, progress, func() error {
+		return doTLSProbe(addr, req.ServerName, &resp)
+	})
+	return resp
+}
+
+// doTLSProbe 执行一次 TLS 握手并填充证书信息；证书无效或 SAN 不匹配时返回 errVerifyFailed
+func doTLSProbe(addr string, serverName string, resp *TLSCheckResponse) error {
+	dialer := &net.Dialer{Timeout: 2 * time.Second}
+	// 证书由下面手动校验，以便在校验失败时仍能返回证书信息
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("%w: 服务端未提供证书", errVerifyFailed)
+	}
+
+	leaf := state.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	resp.Protocol = tls.VersionName(state.Version)
+	resp.Issuer = leaf.Issuer.CommonName
+	resp.NotAfter = leaf.NotAfter.Unix()
+	resp.DaysLeft = int(math.Floor(time.Until(leaf.NotAfter).Hours() / 24))
+	resp.SANMatch = leaf.VerifyHostname(serverName) == nil
+
+	_, verifyErr := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates})
+	resp.CertValid = verifyErr == nil
+
+	if !resp.CertValid {
+		return fmt.Errorf("%w: 证书无效: %v", errVerifyFailed, verifyErr)
+	}
+	if !resp.SANMatch {
+		return fmt.Errorf("%w: 证书 SAN 不匹配 %s", errVerifyFailed, serverName)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package CheckBackend
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"telegram-auto-switch-dns-bot/middleware"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+const (
+	defaultTraceHops = 30              // 默认最大跳数
+	maxTraceHops     = 64              // 最大跳数上限
+	traceHopTimeout  = 1 * time.Second // 单次探测等待回包的时间
+	traceHopTries    = 2               // 每跳最多探测次数
+)
+
+// TraceRequest 路由追踪请求参数
+type TraceRequest struct {
+	Target   string `json:"target" binding:"required"`
+	Port     int    `json:"port" binding:"required"`
+	Key      string `json:"key"`
+	Protocol string `json:"protocol"` // 探测协议: udp（默认）/ tcp
+	MaxHops  int    `json:"max_hops"` // 最大跳数，默认 30
+	Resolver string `json:"resolver"` // 解析目标使用的解析器名称，为空时使用 default_resolver
+	Family   string `json:"family"`   // 地址族: ipv4 / ipv6 / both，默认 both（取第一个解析结果）
+}
+
+// TraceHop 单跳结果
+type TraceHop struct {
+	TTL     int     `json:"ttl"`
+	IP      string  `json:"ip,omitempty"`   // 应答节点，超时为空
+	RTT     float64 `json:"rtt"`            // 往返耗时（毫秒）
+	Reached bool    `json:"reached"`        // 是否已到达目标
+	Timeout bool    `json:"timeout"`        // 该跳无应答
+	Note    string  `json:"note,omitempty"` // 附加说明，如 !H（主机不可达）、端口开放/关闭
+	MTU     int     `json:"mtu,omitempty"`  // 探测到该跳时的路径 MTU
+}
+
+// TraceResponse 路由追踪最终结果
+type TraceResponse struct {
+	Result          bool       `json:"result"` // 是否到达目标
+	Target          string     `json:"target"`
+	TargetIp        string     `json:"target_ip"`
+	Protocol        string     `json:"protocol"`
+	Method          string     `json:"method"`   // 获取中间节点的方式: recverr（非特权错误队列）/ none（平台不支持）
+	PathMTU         int        `json:"path_mtu"` // 路径 MTU，0 表示未探测（TCP 模式）
+	Hops            []TraceHop `json:"hops"`
+	Message         string     `json:"message"`
+	BackendPublicIP string     `json:"backend_public_ip"`
+	Resolver        string     `json:"resolver"`
+}
+
+// traceSummary 追踪过程的汇总信息
+type traceSummary struct {
+	Reached bool
+	Method  string
+	PathMTU int
+}
+
+// traceHandler 对 target:port 进行 UDP 或 TCP-SYN 路由追踪，逐行返回每跳结果（Code=1）与最终结果（Code=0）
+func traceHandler(c *gin.Context) {
+	var req TraceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Logger.Error("路由追踪接口绑定JSON请求体错误:", err)
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: 400, Message: "请求参数错误：" + err.Error()})
+		return
+	}
+
+	logged := req
+	logged.Key = redactKey(req.Key)
+	utils.Logger.Infof("路由追踪请求体数据: %+v", logged)
+
+	// 校验通信密钥
+	if !middleware.ValidateBackendKey(req.Key, c) {
+		return
+	}
+
+	req.Protocol = strings.ToLower(strings.TrimSpace(req.Protocol))
+	if req.Protocol == "" {
+		req.Protocol = "udp"
+	}
+	err := validateResolveParams(req.Resolver, &req.Family)
+	if err == nil && req.Protocol != "udp" && req.Protocol != "tcp" {
+		err = fmt.Errorf("不支持的追踪协议: %s", req.Protocol)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: 400, Message: "请求参数错误：" + err.Error()})
+		return
+	}
+	if req.MaxHops <= 0 {
+		req.MaxHops = defaultTraceHops
+	}
+	if req.MaxHops > maxTraceHops {
+		req.MaxHops = maxTraceHops
+	}
+
+	// 每跳结果数量不超过最大跳数，缓冲区足够时客户端断开也不会阻塞追踪协程
+	hopChan := make(chan TraceHop, req.MaxHops)
+	resultChan := make(chan TraceResponse, 1)
+	go func() {
+		defer close(hopChan)
+		resultChan <- runTrace(req, func(hop TraceHop) { hopChan <- hop })
+	}()
+
+	c.Stream(func(w io.Writer) bool {
+		if hop, ok := <-hopChan; ok {
+			writeStreamLine(w, APIResponse[TraceHop]{
+				Code:    1, // Code=1 表示单跳结果
+				Message: "hop",
+				Data:    hop,
+			})
+			return true
+		}
+
+		writeStreamLine(w, APIResponse[TraceResponse]{
+			Code:    0,
+			Message: "success",
+			Data:    <-resultChan,
+		})
+		return false
+	})
+}
+
+// runTrace 解析目标并对第一个允许检测的 IP 进行路由追踪
+func runTrace(req TraceRequest, onHop func(TraceHop)) TraceResponse {
+	resp := TraceResponse{
+		Target:          req.Target,
+		Protocol:        req.Protocol,
+		BackendPublicIP: getPublicIP(),
+		Hops:            []TraceHop{},
+	}
+
+	res, message := resolveProbeTargets(req.Target, req.Port, req.Resolver, req.Family)
+	resp.Resolver = res.Resolver
+	if len(res.IPs) == 0 {
+		resp.Message = message
+		return resp
+	}
+	resp.TargetIp = res.IPs[0]
+	ip := net.ParseIP(resp.TargetIp)
+
+	record := func(hop TraceHop) {
+		resp.Hops = append(resp.Hops, hop)
+		onHop(hop)
+	}
+
+	var summary traceSummary
+	var err error
+	if req.Protocol == "tcp" {
+		summary, err = traceTCP(ip, req.Port, req.MaxHops, record)
+	} else {
+		summary, err = traceUDP(ip, req.Port, req.MaxHops, record)
+	}
+	resp.Result, resp.Method, resp.PathMTU = summary.Reached, summary.Method, summary.PathMTU
+
+	switch {
+	case err != nil:
+		utils.Logger.Warnf("⚠️ 路由追踪 %s (%s) 失败: %v", req.Target, resp.TargetIp, err)
+		resp.Message = fmt.Sprintf("路由追踪失败: %v", err)
+	case summary.Reached:
+		resp.Message = fmt.Sprintf("已到达目标，共 %d 跳", len(resp.Hops))
+	default:
+		resp.Message = fmt.Sprintf("%d 跳内未到达目标", req.MaxHops)
+	}
+	return resp
+}
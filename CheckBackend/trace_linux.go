@@ -0,0 +1,378 @@
+//go:build linux
+
+package CheckBackend
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// traceSockOpts 按地址族区分的套接字选项
+type traceSockOpts struct {
+	domain      int
+	level       int
+	recvErr     int
+	ttl         int
+	mtuDiscover int
+	pmtuDo      int
+	mtu         int
+	headerLen   int // IP 头 + UDP 头
+	v6          bool
+}
+
+func newTraceSockOpts(ip net.IP) traceSockOpts {
+	if ip.To4() != nil {
+		return traceSockOpts{
+			domain: unix.AF_INET, level: unix.IPPROTO_IP, recvErr: unix.IP_RECVERR, ttl: unix.IP_TTL,
+			mtuDiscover: unix.IP_MTU_DISCOVER, pmtuDo: unix.IP_PMTUDISC_DO, mtu: unix.IP_MTU, headerLen: 28,
+		}
+	}
+	return traceSockOpts{
+		domain: unix.AF_INET6, level: unix.IPPROTO_IPV6, recvErr: unix.IPV6_RECVERR, ttl: unix.IPV6_UNICAST_HOPS,
+		mtuDiscover: unix.IPV6_MTU_DISCOVER, pmtuDo: unix.IPV6_PMTUDISC_DO, mtu: unix.IPV6_MTU, headerLen: 48, v6: true,
+	}
+}
+
+func traceSockaddr(ip net.IP, port int) unix.Sockaddr {
+	if ip4 := ip.To4(); ip4 != nil {
+		sa := &unix.SockaddrInet4{Port: port}
+		copy(sa.Addr[:], ip4)
+		return sa
+	}
+	sa := &unix.SockaddrInet6{Port: port}
+	copy(sa.Addr[:], ip.To16())
+	return sa
+}
+
+// queuedError 从错误队列读取的 ICMP 错误（struct sock_extended_err 与应答节点地址）
+type queuedError struct {
+	errno    unix.Errno
+	origin   uint8
+	icmpType uint8
+	icmpCode uint8
+	info     uint32
+	offender net.IP
+}
+
+// readErrQueue 读取一条错误队列消息，payload 为触发错误的原始数据（TCP 为空）
+func readErrQueue(fd int, buf []byte, oob []byte) (*queuedError, []byte, error) {
+	n, oobn, _, _, err := unix.Recvmsg(fd, buf, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+	if err != nil {
+		return nil, nil, err
+	}
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, m := range msgs {
+		isErr := (m.Header.Level == unix.IPPROTO_IP && m.Header.Type == unix.IP_RECVERR) ||
+			(m.Header.Level == unix.IPPROTO_IPV6 && m.Header.Type == unix.IPV6_RECVERR)
+		if !isErr || len(m.Data) < 16 {
+			continue
+		}
+		qe := &queuedError{
+			errno:    unix.Errno(binary.NativeEndian.Uint32(m.Data[0:4])),
+			origin:   m.Data[4],
+			icmpType: m.Data[5],
+			icmpCode: m.Data[6],
+			info:     binary.NativeEndian.Uint32(m.Data[8:12]),
+		}
+		// SO_EE_OFFENDER：紧随其后的 sockaddr
+		if sa := m.Data[16:]; len(sa) >= 2 {
+			switch binary.NativeEndian.Uint16(sa[0:2]) {
+			case unix.AF_INET:
+				if len(sa) >= 8 {
+					qe.offender = net.IP(append([]byte(nil), sa[4:8]...))
+				}
+			case unix.AF_INET6:
+				if len(sa) >= 24 {
+					qe.offender = net.IP(append([]byte(nil), sa[8:24]...))
+				}
+			}
+		}
+		return qe, buf[:n], nil
+	}
+	return nil, buf[:n], errors.New("错误队列中没有 ICMP 错误信息")
+}
+
+// traceVerdict 单次探测的结论
+type traceVerdict int
+
+const (
+	verdictTransit traceVerdict = iota // 中间节点（TTL 超时）
+	verdictReached                     // 已到达目标
+	verdictStop                        // 不可达，停止追踪
+	verdictMTU                         // 需要降低 MTU 后重试
+)
+
+// classifyQueuedError 根据 ICMP 类型判断探测结论与附加说明
+func classifyQueuedError(qe *queuedError, v6 bool) (traceVerdict, string) {
+	if qe.errno == unix.EMSGSIZE {
+		return verdictMTU, ""
+	}
+	if qe.origin != unix.SO_EE_ORIGIN_ICMP && qe.origin != unix.SO_EE_ORIGIN_ICMP6 {
+		return verdictStop, qe.errno.Error()
+	}
+	if !v6 {
+		switch {
+		case qe.icmpType == 11:
+			return verdictTransit, ""
+		case qe.icmpType == 3 && qe.icmpCode == 3:
+			return verdictReached, "端口不可达"
+		case qe.icmpType == 3:
+			return verdictStop, unreachNote(qe.icmpCode, map[uint8]string{0: "!N", 1: "!H", 2: "!P", 9: "!X", 10: "!X", 13: "!X"})
+		}
+	} else {
+		switch {
+		case qe.icmpType == 3:
+			return verdictTransit, ""
+		case qe.icmpType == 1 && qe.icmpCode == 4:
+			return verdictReached, "端口不可达"
+		case qe.icmpType == 1:
+			return verdictStop, unreachNote(qe.icmpCode, map[uint8]string{0: "!N", 1: "!X", 3: "!H"})
+		}
+	}
+	return verdictStop, fmt.Sprintf("ICMP type=%d code=%d", qe.icmpType, qe.icmpCode)
+}
+
+func unreachNote(code uint8, notes map[uint8]string) string {
+	if note, ok := notes[code]; ok {
+		return note
+	}
+	return fmt.Sprintf("!<%d>", code)
+}
+
+// pollFd 等待套接字事件，返回发生的事件；超时返回 0
+func pollFd(fd int, events int16, timeout time.Duration) (int16, error) {
+	ms := int(timeout / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	fds := []unix.PollFd{{Fd: int32(fd), Events: events}}
+	for {
+		n, err := unix.Poll(fds, ms)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil || n == 0 {
+			return 0, err
+		}
+		return fds[0].Revents, nil
+	}
+}
+
+// drainErrQueue 丢弃错误队列中迟到的错误，避免影响后续探测
+func drainErrQueue(fd int, buf []byte, oob []byte) {
+	for {
+		if _, _, err := readErrQueue(fd, buf, oob); err != nil {
+			return
+		}
+	}
+}
+
+// traceUDP 发送逐跳递增 TTL 的 UDP 探测包，通过 IP_RECVERR 错误队列（无需特权）获取中间节点，
+// 同时设置 DF 并根据 EMSGSIZE 逐步降低包长探测路径 MTU
+func traceUDP(ip net.IP, port int, maxHops int, onHop func(TraceHop)) (traceSummary, error) {
+	o := newTraceSockOpts(ip)
+	summary := traceSummary{Method: "recverr"}
+
+	fd, err := unix.Socket(o.domain, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return summary, fmt.Errorf("创建 UDP 套接字失败: %w", err)
+	}
+	defer unix.Close(fd)
+	if err := unix.SetsockoptInt(fd, o.level, o.recvErr, 1); err != nil {
+		return summary, fmt.Errorf("开启错误队列失败: %w", err)
+	}
+	if err := unix.SetsockoptInt(fd, o.level, o.mtuDiscover, o.pmtuDo); err != nil {
+		return summary, fmt.Errorf("设置路径 MTU 探测失败: %w", err)
+	}
+	if err := unix.Connect(fd, traceSockaddr(ip, port)); err != nil {
+		return summary, fmt.Errorf("连接目标失败: %w", err)
+	}
+
+	mtu := 1500
+	if v, err := unix.GetsockoptInt(fd, o.level, o.mtu); err == nil && v > o.headerLen {
+		mtu = v
+	}
+	buf := make([]byte, 65536)
+	oob := make([]byte, 512)
+
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		if err := unix.SetsockoptInt(fd, o.level, o.ttl, ttl); err != nil {
+			return summary, fmt.Errorf("设置 TTL 失败: %w", err)
+		}
+
+		hop := TraceHop{TTL: ttl, Timeout: true}
+		verdict := verdictTransit
+		for try := 0; try < traceHopTries && hop.Timeout; try++ {
+			payload := make([]byte, mtu-o.headerLen)
+			payload[0], payload[1] = byte(ttl), byte(try)
+
+			drainErrQueue(fd, buf, oob)
+			start := time.Now()
+			if _, err := unix.Write(fd, payload); err != nil {
+				if errors.Is(err, unix.EMSGSIZE) {
+					// 本地已知的路径 MTU 更小，降低包长后重试
+					if v, gerr := unix.GetsockoptInt(fd, o.level, o.mtu); gerr == nil && v > o.headerLen && v < mtu {
+						mtu = v
+						try--
+						continue
+					}
+				}
+				return summary, fmt.Errorf("发送探测包失败: %w", err)
+			}
+
+			deadline := start.Add(traceHopTimeout)
+			for hop.Timeout {
+				remaining := time.Until(deadline)
+				if remaining <= 0 {
+					break
+				}
+				revents, err := pollFd(fd, unix.POLLIN|unix.POLLERR, remaining)
+				if err != nil {
+					return summary, fmt.Errorf("等待回包失败: %w", err)
+				}
+				if revents == 0 {
+					break
+				}
+
+				if revents&unix.POLLIN != 0 {
+					// 目标端口有应答
+					if _, err := unix.Read(fd, buf); err == nil {
+						hop = TraceHop{TTL: ttl, IP: ip.String(), RTT: msSince(start), Reached: true, Note: "端口有应答"}
+						verdict = verdictReached
+						break
+					}
+				}
+				if revents&unix.POLLERR == 0 {
+					continue
+				}
+
+				qe, orig, err := readErrQueue(fd, buf, oob)
+				if err != nil || len(orig) < 2 || orig[0] != byte(ttl) || orig[1] != byte(try) {
+					// 上一次探测迟到的错误
+					continue
+				}
+				v, note := classifyQueuedError(qe, o.v6)
+				if v == verdictMTU {
+					// 沿途节点要求分片，按返回的 MTU 降低包长后重试本次探测
+					if qe.info > uint32(o.headerLen) && int(qe.info) < mtu {
+						mtu = int(qe.info)
+						try--
+					}
+					break
+				}
+				verdict = v
+				hop = TraceHop{TTL: ttl, RTT: msSince(start), Reached: v == verdictReached, Note: note}
+				if qe.offender != nil {
+					hop.IP = qe.offender.String()
+				}
+			}
+		}
+
+		hop.MTU = mtu
+		onHop(hop)
+		if verdict == verdictReached || verdict == verdictStop {
+			summary.Reached = verdict == verdictReached || hop.IP == ip.String()
+			break
+		}
+	}
+	summary.PathMTU = mtu
+	return summary, nil
+}
+
+// traceTCP 逐跳递增 TTL 发起 TCP 连接（SYN），SYN 超时产生的 ICMP 错误通过 IP_RECVERR 错误队列获取，
+// 连接成功或被拒绝（RST）即视为到达目标
+func traceTCP(ip net.IP, port int, maxHops int, onHop func(TraceHop)) (traceSummary, error) {
+	o := newTraceSockOpts(ip)
+	summary := traceSummary{Method: "recverr"}
+	buf := make([]byte, 512)
+	oob := make([]byte, 512)
+
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		hop := TraceHop{TTL: ttl, Timeout: true}
+		verdict := verdictTransit
+		for try := 0; try < traceHopTries && hop.Timeout; try++ {
+			var err error
+			hop, verdict, err = tcpTraceProbe(o, ip, port, ttl, buf, oob)
+			if err != nil {
+				return summary, err
+			}
+		}
+
+		onHop(hop)
+		if verdict == verdictReached || verdict == verdictStop {
+			summary.Reached = verdict == verdictReached || hop.IP == ip.String()
+			break
+		}
+	}
+	return summary, nil
+}
+
+// tcpTraceProbe 以指定 TTL 发起一次非阻塞 TCP 连接并等待结果
+func tcpTraceProbe(o traceSockOpts, ip net.IP, port int, ttl int, buf []byte, oob []byte) (TraceHop, traceVerdict, error) {
+	hop := TraceHop{TTL: ttl, Timeout: true}
+	fd, err := unix.Socket(o.domain, unix.SOCK_STREAM|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return hop, verdictTransit, fmt.Errorf("创建 TCP 套接字失败: %w", err)
+	}
+	defer unix.Close(fd)
+	if err := unix.SetsockoptInt(fd, o.level, o.recvErr, 1); err != nil {
+		return hop, verdictTransit, fmt.Errorf("开启错误队列失败: %w", err)
+	}
+	if err := unix.SetsockoptInt(fd, o.level, o.ttl, ttl); err != nil {
+		return hop, verdictTransit, fmt.Errorf("设置 TTL 失败: %w", err)
+	}
+
+	start := time.Now()
+	if err := unix.Connect(fd, traceSockaddr(ip, port)); err != nil && !errors.Is(err, unix.EINPROGRESS) {
+		return hop, verdictTransit, fmt.Errorf("发起连接失败: %w", err)
+	}
+
+	revents, err := pollFd(fd, unix.POLLOUT, traceHopTimeout)
+	if err != nil {
+		return hop, verdictTransit, fmt.Errorf("等待连接结果失败: %w", err)
+	}
+	if revents == 0 {
+		return hop, verdictTransit, nil
+	}
+	rtt := msSince(start)
+
+	if revents&unix.POLLERR != 0 {
+		if qe, _, err := readErrQueue(fd, buf, oob); err == nil {
+			v, note := classifyQueuedError(qe, o.v6)
+			hop = TraceHop{TTL: ttl, RTT: rtt, Reached: v == verdictReached, Note: note}
+			if qe.offender != nil {
+				hop.IP = qe.offender.String()
+			}
+			return hop, v, nil
+		}
+	}
+
+	soErr, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_ERROR)
+	if err != nil {
+		return hop, verdictTransit, fmt.Errorf("获取连接结果失败: %w", err)
+	}
+	switch unix.Errno(soErr) {
+	case 0:
+		return TraceHop{TTL: ttl, IP: ip.String(), RTT: rtt, Reached: true, Note: "端口开放"}, verdictReached, nil
+	case unix.ECONNREFUSED:
+		return TraceHop{TTL: ttl, IP: ip.String(), RTT: rtt, Reached: true, Note: "端口关闭"}, verdictReached, nil
+	case unix.EHOSTUNREACH:
+		// 未取得应答节点地址的 TTL 超时
+		return TraceHop{TTL: ttl, RTT: rtt}, verdictTransit, nil
+	default:
+		return TraceHop{TTL: ttl, RTT: rtt, Note: unix.Errno(soErr).Error()}, verdictStop, nil
+	}
+}
+
+// msSince 返回距 start 的毫秒数
+func msSince(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package CheckBackend
+
+import (
+	"errors"
+	"net"
+)
+
+// errTraceUnsupported 非 Linux 平台缺少无特权获取 ICMP 错误的方式
+var errTraceUnsupported = errors.New("路由追踪仅支持 Linux 检测后端")
+
+func traceUDP(_ net.IP, _ int, _ int, _ func(TraceHop)) (traceSummary, error) {
+	return traceSummary{Method: "none"}, errTraceUnsupported
+}
+
+func traceTCP(_ net.IP, _ int, _ int, _ func(TraceHop)) (traceSummary, error) {
+	return traceSummary{Method: "none"}, errTraceUnsupported
+}
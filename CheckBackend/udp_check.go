@@ -0,0 +1,38 @@
+package CheckBackend
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"time"
+)
+
+// udpReadBufferSize UDP 回包读取缓冲区大小
+const udpReadBufferSize = 2048
+
+// probeUDP 向 addr 发送一次载荷并等待回包；expect 非空时回包需包含 expect
+// UDP 无连接，收不到回包（超时或 ICMP 端口不可达）即视为无法连接
+func probeUDP(addr string, payload []byte, expect []byte) error {
+	conn, err := net.DialTimeout("udp", addr, 1*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		return err
+	}
+	if _, err := conn.Write(payload); err != nil {
+		return err
+	}
+
+	buf := make([]byte, udpReadBufferSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return err
+	}
+	if len(expect) > 0 && !bytes.Contains(buf[:n], expect) {
+		return fmt.Errorf("%w: 回包不包含期望内容 %x", errVerifyFailed, expect)
+	}
+	return nil
+}
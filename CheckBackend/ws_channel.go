@@ -0,0 +1,274 @@
+package CheckBackend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"telegram-auto-switch-dns-bot/middleware"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// WebSocket 任务通道的帧类型
+const (
+	WSFrameJob    = "job"    // 前端 → 后端：提交任务
+	WSFrameCancel = "cancel" // 前端 → 后端：取消任务
+	WSFrameHead   = "head"   // 后端 → 前端：任务响应状态码
+	WSFrameLine   = "line"   // 后端 → 前端：一行响应（与 HTTP 流式响应的 NDJSON 行一致）
+	WSFrameDone   = "done"   // 后端 → 前端：任务结束
+)
+
+// WebSocket 通道保活参数
+const (
+	WSPingPeriod   = 30 * time.Second // 发送 ping 的间隔
+	WSPongWait     = 90 * time.Second // 超过该时间未收到任何消息视为连接断开
+	WSWriteTimeout = 10 * time.Second // 单帧写超时
+	wsReadLimit    = 4 << 20          // 单帧大小上限
+)
+
+// WebSocket 通道断线重连的指数退避范围
+const (
+	WSMinBackoff = 1 * time.Second
+	WSMaxBackoff = 60 * time.Second
+)
+
+// WSFrame 前端与检测后端之间 WebSocket 任务通道的消息帧，一条连接上的多个任务按 ID 复用
+type WSFrame struct {
+	Type   string          `json:"type"`
+	ID     string          `json:"id"`
+	Method string          `json:"method,omitempty"` // job: 请求方法
+	Path   string          `json:"path,omitempty"`   // job: 接口路径，如 /api/v1/tcp_checks
+	Body   json.RawMessage `json:"body,omitempty"`   // job: 请求体
+	Status int             `json:"status,omitempty"` // head: HTTP 状态码
+	Line   string          `json:"line,omitempty"`   // line: 一行响应
+	Error  string          `json:"error,omitempty"`  // done: 任务异常
+}
+
+// KeepAlive 为 WebSocket 连接设置读限制与保活：收到任何消息或 ping/pong 都会延长读超时，并定时发送 ping
+// 返回的函数用于停止发送 ping
+func KeepAlive(conn *websocket.Conn, writeMu *sync.Mutex) func() {
+	conn.SetReadLimit(wsReadLimit)
+	_ = conn.SetReadDeadline(time.Now().Add(WSPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(WSPongWait))
+	})
+	conn.SetPingHandler(func(data string) error {
+		_ = conn.SetReadDeadline(time.Now().Add(WSPongWait))
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(WSWriteTimeout))
+	})
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(WSPingPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				writeMu.Lock()
+				err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(WSWriteTimeout))
+				writeMu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
+
+var wsUpgrader = websocket.Upgrader{
+	HandshakeTimeout: 10 * time.Second,
+	ReadBufferSize:   4096,
+	WriteBufferSize:  4096,
+}
+
+// wsHandler 将已认证的连接升级为 WebSocket 任务通道（GET /api/v1/ws），通道内的任务交由 handler 执行
+func wsHandler(handler http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 校验通信密钥（升级请求无请求体，兼容模式下密钥通过请求头传递）
+		if !middleware.ValidateBackendKey(c.GetHeader(middleware.HeaderBackendKey), c) {
+			return
+		}
+
+		conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			utils.Logger.Warnf("⚠️ WebSocket 升级失败 (%s): %v", c.ClientIP(), err)
+			return
+		}
+		// 清除 HTTP 服务器设置的读写超时，改由保活机制维护
+		_ = conn.UnderlyingConn().SetDeadline(time.Time{})
+
+		utils.Logger.Infof("🔌 WebSocket 任务通道已建立: %s", c.ClientIP())
+		ServeJobChannel(conn, handler, c.Request.RemoteAddr)
+		utils.Logger.Infof("🔌 WebSocket 任务通道已断开: %s", c.ClientIP())
+	}
+}
+
+// jobChannel 后端一侧的任务通道
+type jobChannel struct {
+	conn       *websocket.Conn
+	handler    http.Handler
+	remoteAddr string
+	writeMu    sync.Mutex
+
+	mu   sync.Mutex
+	jobs map[string]context.CancelFunc
+}
+
+// ServeJobChannel 在 WebSocket 连接上接收任务并并发执行，直到连接断开
+// 每个任务作为一次内部请求交给 handler（检测接口路由），响应按行回传
+func ServeJobChannel(conn *websocket.Conn, handler http.Handler, remoteAddr string) {
+	ch := &jobChannel{
+		conn:       conn,
+		handler:    handler,
+		remoteAddr: remoteAddr,
+		jobs:       make(map[string]context.CancelFunc),
+	}
+	stopPing := KeepAlive(conn, &ch.writeMu)
+	defer func() {
+		stopPing()
+		_ = conn.Close()
+		ch.mu.Lock()
+		for _, cancel := range ch.jobs {
+			cancel()
+		}
+		ch.mu.Unlock()
+	}()
+
+	for {
+		var frame WSFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				utils.Logger.Warnf("⚠️ WebSocket 任务通道读取失败: %v", err)
+			}
+			return
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(WSPongWait))
+
+		switch frame.Type {
+		case WSFrameJob:
+			ctx, cancel := context.WithCancel(context.Background())
+			ch.mu.Lock()
+			ch.jobs[frame.ID] = cancel
+			ch.mu.Unlock()
+			go ch.runJob(ctx, frame)
+		case WSFrameCancel:
+			ch.mu.Lock()
+			if cancel, ok := ch.jobs[frame.ID]; ok {
+				cancel()
+			}
+			ch.mu.Unlock()
+		}
+	}
+}
+
+// send 写入一帧，多个任务共用连接时串行写入
+func (ch *jobChannel) send(frame WSFrame) error {
+	ch.writeMu.Lock()
+	defer ch.writeMu.Unlock()
+	_ = ch.conn.SetWriteDeadline(time.Now().Add(WSWriteTimeout))
+	return ch.conn.WriteJSON(frame)
+}
+
+// runJob 将任务作为内部请求交给检测接口执行
+func (ch *jobChannel) runJob(ctx context.Context, frame WSFrame) {
+	defer func() {
+		ch.mu.Lock()
+		if cancel, ok := ch.jobs[frame.ID]; ok {
+			cancel()
+			delete(ch.jobs, frame.ID)
+		}
+		ch.mu.Unlock()
+	}()
+
+	w := newJobWriter(ctx, ch, frame.ID)
+	req, err := http.NewRequestWithContext(middleware.WithTrustedChannel(ctx), frame.Method, frame.Path, bytes.NewReader(frame.Body))
+	if err != nil {
+		_ = ch.send(WSFrame{Type: WSFrameDone, ID: frame.ID, Error: err.Error()})
+		return
+	}
+	req.RemoteAddr = ch.remoteAddr
+	if len(frame.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	ch.handler.ServeHTTP(w, req)
+	w.finish()
+	_ = ch.send(WSFrame{Type: WSFrameDone, ID: frame.ID})
+}
+
+// jobWriter 将检测接口的响应按行转为 line 帧的 http.ResponseWriter
+type jobWriter struct {
+	ch       *jobChannel
+	id       string
+	header   http.Header
+	status   int
+	headSent bool
+	buf      []byte
+	gone     chan bool
+}
+
+func newJobWriter(ctx context.Context, ch *jobChannel, id string) *jobWriter {
+	w := &jobWriter{ch: ch, id: id, header: make(http.Header), status: http.StatusOK, gone: make(chan bool)}
+	go func() {
+		<-ctx.Done()
+		close(w.gone)
+	}()
+	return w
+}
+
+func (w *jobWriter) Header() http.Header { return w.header }
+
+func (w *jobWriter) WriteHeader(status int) {
+	if !w.headSent {
+		w.status = status
+	}
+}
+
+// Write 缓存响应数据，每凑满一行发送一帧
+func (w *jobWriter) Write(p []byte) (int, error) {
+	w.sendHead()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.buf[:i])
+		w.buf = w.buf[i+1:]
+		if err := w.ch.send(WSFrame{Type: WSFrameLine, ID: w.id, Line: line}); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush 响应按行实时发送，无需额外刷新
+func (w *jobWriter) Flush() {}
+
+// CloseNotify 任务被取消或通道断开时通知流式响应停止（gin 的 Stream 依赖该接口）
+func (w *jobWriter) CloseNotify() <-chan bool { return w.gone }
+
+func (w *jobWriter) sendHead() {
+	if w.headSent {
+		return
+	}
+	w.headSent = true
+	_ = w.ch.send(WSFrame{Type: WSFrameHead, ID: w.id, Status: w.status})
+}
+
+// finish 发送未以换行结尾的剩余响应（如 c.JSON 返回的错误）
+func (w *jobWriter) finish() {
+	w.sendHead()
+	if len(w.buf) > 0 {
+		_ = w.ch.send(WSFrame{Type: WSFrameLine, ID: w.id, Line: string(w.buf)})
+		w.buf = nil
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/cloudflare/cloudflare-go"
+	"slices"
 	"strings"
 	"telegram-auto-switch-dns-bot/config"
 	"telegram-auto-switch-dns-bot/utils"
@@ -219,22 +220,9 @@ func (c *Client) GetZoneID() string {
 // UpdateDNSRecordByID 通过 DNS 记录 ID 直接更新（使用全局客户端）
 // 如果 zoneId 为空，则通过域名提取根域名并查询 Zone ID
 func (c *Client) UpdateDNSRecordByID(domain string, zoneId string, recordID string, recordType string, name string, content string, ttl int, proxied bool) error {
-	var zoneID string
-	var err error
-
-	// 如果提供了 zoneId，直接使用；否则通过域名提取根域名并查询 Zone ID
-	if zoneId != "" {
-		zoneID = zoneId
-	} else {
-		// 提取根域名（取后两部分）
-		rootDomain := extractRootDomain(domain)
-		utils.Logger.Infof("🔍 从 %s 提取根域名: %s", domain, rootDomain)
-
-		// 通过根域名获取 Zone ID
-		zoneID, err = c.api.ZoneIDByName(rootDomain)
-		if err != nil {
-			return fmt.Errorf("查找域名 %s 的 Zone ID 失败: %w", rootDomain, err)
-		}
+	zoneID, err := c.resolveZoneID(domain, zoneId)
+	if err != nil {
+		return err
 	}
 
 	record := cloudflare.UpdateDNSRecordParams{
@@ -255,6 +243,149 @@ func (c *Client) UpdateDNSRecordByID(domain string, zoneId string, recordID stri
 	return nil
 }
 
+// resolveZoneID 如果提供了 zoneId，直接使用；否则通过域名提取根域名并查询 Zone ID
+func (c *Client) resolveZoneID(domain string, zoneId string) (string, error) {
+	if zoneId != "" {
+		return zoneId, nil
+	}
+
+	// 提取根域名（取后两部分）
+	rootDomain := extractRootDomain(domain)
+	utils.Logger.Infof("🔍 从 %s 提取根域名: %s", domain, rootDomain)
+
+	// 通过根域名获取 Zone ID
+	zoneID, err := c.api.ZoneIDByName(rootDomain)
+	if err != nil {
+		return "", fmt.Errorf("查找域名 %s 的 Zone ID 失败: %w", rootDomain, err)
+	}
+	return zoneID, nil
+}
+
+// SyncDNSRecordSet 将 name 下 recordType 类型的记录集合同步为 contents（使用全局客户端）
+// recordID 对应的记录更新为第一个内容；其余内容复用已有的同名记录或新建，多余的同名同类型记录会被删除
+func (c *Client) SyncDNSRecordSet(domain string, zoneId string, recordID string, recordType string, name string, contents []string, ttl int, proxied bool) error {
+	if len(contents) == 0 {
+		return fmt.Errorf("记录内容为空")
+	}
+
+	zoneID, err := c.resolveZoneID(domain, zoneId)
+	if err != nil {
+		return err
+	}
+
+	// 主记录始终使用第一个内容
+	if err := c.UpdateDNSRecordByID(domain, zoneID, recordID, recordType, name, contents[0], ttl, proxied); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	records, _, err := c.api.ListDNSRecords(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.ListDNSRecordsParams{
+		Name: name,
+		Type: recordType,
+	})
+	if err != nil {
+		return fmt.Errorf("查询 DNS 记录失败: %w", err)
+	}
+
+	wanted := make(map[string]bool, len(contents))
+	for _, content := range contents[1:] {
+		wanted[content] = true
+	}
+	delete(wanted, contents[0])
+
+	// 保留仍需要的附加记录，删除多余的记录
+	for _, r := range records {
+		if r.ID == recordID {
+			continue
+		}
+		if wanted[r.Content] {
+			delete(wanted, r.Content)
+			continue
+		}
+		if err := c.api.DeleteDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), r.ID); err != nil {
+			return fmt.Errorf("删除 DNS 记录失败: %w", err)
+		}
+		utils.Logger.Infof("✅ 已删除多余的 DNS 记录: %s -> %s (ID: %s)", name, r.Content, r.ID)
+	}
+
+	// 创建缺少的附加记录（按 contents 顺序）
+	for _, content := range contents[1:] {
+		if !wanted[content] {
+			continue
+		}
+		delete(wanted, content)
+		resp, err := c.api.CreateDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.CreateDNSRecordParams{
+			Type:    recordType,
+			Name:    name,
+			Content: content,
+			TTL:     ttl,
+			Proxied: &proxied,
+		})
+		if err != nil {
+			return fmt.Errorf("创建 DNS 记录失败: %w", err)
+		}
+		utils.Logger.Infof("✅ 已创建 DNS 记录: %s -> %s (ID: %s, Type: %s)", name, content, resp.ID, recordType)
+	}
+
+	return nil
+}
+
+// DeleteExtraDNSRecords 删除 name 下除 keepID 以外的所有 recordType 类型记录（使用全局客户端）
+func (c *Client) DeleteExtraDNSRecords(domain string, zoneId string, keepID string, recordType string, name string) error {
+	zoneID, err := c.resolveZoneID(domain, zoneId)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	records, _, err := c.api.ListDNSRecords(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.ListDNSRecordsParams{
+		Name: name,
+		Type: recordType,
+	})
+	if err != nil {
+		return fmt.Errorf("查询 DNS 记录失败: %w", err)
+	}
+
+	for _, r := range records {
+		if r.ID == keepID {
+			continue
+		}
+		if err := c.api.DeleteDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), r.ID); err != nil {
+			return fmt.Errorf("删除 DNS 记录失败: %w", err)
+		}
+		utils.Logger.Infof("✅ 已删除多余的 DNS 记录: %s -> %s (ID: %s)", name, r.Content, r.ID)
+	}
+	return nil
+}
+
+// RecordContents 返回 name 下 recordTypes 类型记录的内容，primaryID 对应的记录排在最前（使用全局客户端）
+func (c *Client) RecordContents(domain string, zoneId string, primaryID string, name string, recordTypes ...string) ([]string, error) {
+	zoneID, err := c.resolveZoneID(domain, zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	records, _, err := c.api.ListDNSRecords(context.Background(), cloudflare.ZoneIdentifier(zoneID), cloudflare.ListDNSRecordsParams{
+		Name: name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询 DNS 记录失败: %w", err)
+	}
+
+	var contents []string
+	for _, r := range records {
+		if !slices.Contains(recordTypes, r.Type) {
+			continue
+		}
+		if r.ID == primaryID {
+			contents = append([]string{r.Content}, contents...)
+		} else {
+			contents = append(contents, r.Content)
+		}
+	}
+	return contents, nil
+}
+
 // extractRootDomain 提取根域名（取后两部分）
 func extractRootDomain(domain string) string {
 	parts := strings.Split(domain, ".")
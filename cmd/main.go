@@ -24,7 +24,7 @@ func main() {
 
 	// 打印启动信息
 	color.Cyan("========================================")
-	color.Cyan("  Telegram Auto Switch DNS Bot v1.0.0")
+	color.Cyan("  Telegram Auto Switch DNS Bot v%s", config.Version)
 	color.Cyan("========================================")
 	utils.Logger.Infof("程序启动，当前模式: %d", Config.Start.Models)
 
@@ -10,6 +10,9 @@ import (
 	"time"
 )
 
+// Version 程序版本，前端与检测后端共用
+const Version = "1.0.0"
+
 // =======================
 // 全局配置变量
 // =======================
@@ -51,12 +54,73 @@ type BackendListenConfig struct {
 	WriteTimeout   time.Duration `yaml:"write_timeout"`
 	MaxHeaderBytes int           `yaml:"max_header_bytes"`
 	MaxRetries     int           `yaml:"max_retries"`
+	AllowLegacyKey bool          `yaml:"allow_legacy_key"` // 兼容旧版前端：允许未签名请求通过请求体中的 key 验证
+	MaxClockSkew   int           `yaml:"max_clock_skew"`   // 签名请求允许的时间偏差（秒），默认 300
+	CertFile       string        `yaml:"cert_file"`        // HTTPS 证书文件，与 key_file 同时配置时启用 HTTPS
+	KeyFile        string        `yaml:"key_file"`         // HTTPS 私钥文件
+	ClientCAFile   string        `yaml:"client_ca_file"`   // 客户端证书 CA，配置后要求前端出示由该 CA 签发的证书（mTLS）
+
+	Resolvers       []ResolverConfig `yaml:"resolvers"`        // 自定义上游解析器，请求可按名称选择
+	DefaultResolver string           `yaml:"default_resolver"` // 请求未指定解析器时使用，为空表示系统解析器
+
+	RateLimit    RateLimitConfig    `yaml:"rate_limit"`    // 请求限流与并发上限
+	TargetPolicy TargetPolicyConfig `yaml:"target_policy"` // 允许检测的目标 IP 段与端口
+	EgressIP     EgressIPConfig     `yaml:"egress_ip"`     // 检测出口 IP 的获取方式
+	Reverse      ReverseConfig      `yaml:"reverse"`       // 反向连接：主动连接 Bot 并接收检测任务（适用于 NAT 后的后端）
+}
+
+// ReverseConfig 检测后端反向连接 Bot 的配置，url 为空表示不启用
+type ReverseConfig struct {
+	URL    string `yaml:"url"`     // Bot 反向连接监听地址，如 wss://bot.example.com:8081
+	Name   string `yaml:"name"`    // 注册使用的后端名称，需与 Bot 端 backends 中 reverse 后端的 name 一致
+	CAFile string `yaml:"ca_file"` // 校验 Bot 证书的 CA，为空使用系统根证书
+}
+
+// EgressIPConfig 检测出口 IP 的获取来源，按 static → interface → urls 的顺序取第一个成功的结果
+type EgressIPConfig struct {
+	Static    string   `yaml:"static"`    // 固定出口 IP
+	Interface string   `yaml:"interface"` // 从本机网卡读取，如 eth0
+	URLs      []string `yaml:"urls"`      // 回显请求来源 IP 的地址，支持纯文本或含 ip 字段的 JSON
+	Refresh   int      `yaml:"refresh"`   // 刷新间隔（秒），默认 600
+}
+
+// RateLimitConfig 检测后端请求限流（令牌桶），速率为 0 表示不限制
+type RateLimitConfig struct {
+	IPRate      float64 `yaml:"ip_rate"`      // 每个来源 IP 每秒允许的请求数
+	IPBurst     int     `yaml:"ip_burst"`     // 每个来源 IP 的突发请求数，默认与速率相同
+	KeyRate     float64 `yaml:"key_rate"`     // 每个通信密钥每秒允许的请求数
+	KeyBurst    int     `yaml:"key_burst"`    // 每个通信密钥的突发请求数，默认与速率相同
+	MaxInflight int     `yaml:"max_inflight"` // 同时进行的单次探测（拨号）上限，批量检测按每次拨号计数，0 表示不限制
+}
+
+// TargetPolicyConfig 检测目标访问控制，拒绝列表优先于允许列表
+type TargetPolicyConfig struct {
+	AllowCIDRs   []string `yaml:"allow_cidrs"`   // 允许检测的 IP 段，为空表示不限制（私有地址除外）
+	DenyCIDRs    []string `yaml:"deny_cidrs"`    // 禁止检测的 IP 段
+	AllowPorts   []string `yaml:"allow_ports"`   // 允许检测的端口，支持范围如 "1000-2000"，为空表示不限制
+	DenyPorts    []string `yaml:"deny_ports"`    // 禁止检测的端口
+	AllowPrivate bool     `yaml:"allow_private"` // 是否允许检测私有、回环、链路本地等内网地址，默认禁止
+}
+
+// ResolverConfig 上游解析器
+type ResolverConfig struct {
+	Name       string `yaml:"name"`        // 解析器名称，请求中通过该名称选择
+	Type       string `yaml:"type"`        // 类型: udp / tcp / dot / doh（udp 应答截断或超时时改用 tcp）
+	Address    string `yaml:"address"`     // udp/tcp/dot 为 host[:port]，doh 为完整 URL
+	ServerName string `yaml:"server_name"` // DoT 证书校验域名，默认使用 address 中的主机名
+	Timeout    int    `yaml:"timeout"`     // 单次查询超时（秒），默认 3
 }
 
 // AutoCheckConfig =======================
 type AutoCheckConfig struct {
-	CheckTime int `yaml:"check_time"`
-	ApiFail   int `yaml:"api_fail"`
+	CheckTime    int      `yaml:"check_time"` // 未单独设置检测计划的主域名的检测间隔（分钟）
+	ApiFail      int      `yaml:"api_fail"`
+	CertWarnDays int      `yaml:"cert_warn_days"` // 证书剩余天数低于该值时在报告中预警
+	BanDurations []string `yaml:"ban_durations"`  // 转发域名连续第 N 次封禁的时长（如 10m、1h），超出列表时使用最后一项，默认 24h
+	Workers      int      `yaml:"workers"`        // 自动检测时并发检测的主域名数，默认 4
+	DryRun       bool     `yaml:"dry_run"`        // 模拟运行：完整检测但不修改 DNS、不封禁转发，报告标记为模拟
+	HistoryDays  int      `yaml:"history_days"`   // 检测结果保留天数，默认 7
+	SwitchDays   int      `yaml:"switch_days"`    // DNS 切换事件保留天数，默认 90
 }
 
 // DatabaseConfig =======================
@@ -87,8 +151,30 @@ type TelegramConfig struct {
 
 // BackendURL config for bot calling backend API
 type BackendURL struct {
-	Api     string        `yaml:"api"`
-	Timeout time.Duration `yaml:"timeout"`
+	Api       string            `yaml:"api"`
+	Timeout   time.Duration     `yaml:"timeout"`
+	Backends  []BackendEndpoint `yaml:"backends"`   // 多检测后端（不同地区/运营商），配置后忽略 api
+	Quorum    int               `yaml:"quorum"`     // 判定目标不通所需的后端票数，0 表示过半；响应不足该票数时按检测失败处理
+	InfoPoll  int               `yaml:"info_poll"`  // 轮询后端状态（/api/v1/info）的间隔（秒），默认 60
+	LegacyKey bool              `yaml:"legacy_key"` // 兼容旧版后端：签名之外同时在请求体中携带 key
+	WebSocket bool              `yaml:"websocket"`  // 通过持久 WebSocket 通道（/api/v1/ws）提交检测任务，未连接时回退 HTTP
+
+	ReverseListen   string `yaml:"reverse_listen"`    // 接受反向连接后端注册的监听地址，如 :8081，为空不监听
+	ReverseCertFile string `yaml:"reverse_cert_file"` // 反向连接监听的 HTTPS 证书，与 reverse_key_file 同时配置时启用 wss
+	ReverseKeyFile  string `yaml:"reverse_key_file"`  // 反向连接监听的 HTTPS 私钥
+
+	CAFile     string `yaml:"ca_file"`     // 校验后端 HTTPS 证书的 CA，配置后只信任该 CA
+	CertFile   string `yaml:"cert_file"`   // 客户端证书（后端开启 mTLS 时使用）
+	KeyFile    string `yaml:"key_file"`    // 客户端私钥
+	ServerName string `yaml:"server_name"` // 校验后端证书使用的域名，默认取 api 中的主机名
+}
+
+// BackendEndpoint 单个检测后端
+type BackendEndpoint struct {
+	Name    string `yaml:"name"`    // 后端名称，如 电信/联通/香港
+	Api     string `yaml:"api"`     // 后端地址
+	Reverse bool   `yaml:"reverse"` // 反向连接后端：由后端主动连接 reverse_listen 注册，无需配置 api
+	Key     string `yaml:"key"`     // 反向连接后端注册使用的通信密钥，默认 backend_listen.key
 }
 
 // NetworkConfig =======================
@@ -43,6 +43,10 @@ func InitDB() error {
 			// 启用 SQLite 外键约束（默认禁用）
 			DB.Exec("PRAGMA foreign_keys = ON;")
 			utils.Logger.Infof("SQLite 外键约束已启用")
+			// SQLite 只允许单个写入者，主域名并发检测时串行化连接，避免 database is locked
+			if sqlDB, err := DB.DB(); err == nil {
+				sqlDB.SetMaxOpenConns(1)
+			}
 		}
 	default:
 		errMsg := fmt.Sprintf("不支持的数据库类型: %v", cfg.Type)
@@ -86,6 +90,9 @@ func AutoMigrate() error {
 		&models.DomainRecord{},
 		&models.ForwardRecord{},
 		&models.TelegramAdmins{},
+		&models.MaintenanceWindow{},
+		&models.CheckResult{},
+		&models.SwitchEvent{},
 	)
 	if err != nil {
 		utils.Logger.Errorf("自动迁移失败: %v", err)
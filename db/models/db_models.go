@@ -2,34 +2,118 @@ package models
 
 // ForwardRecord 表示单个转发域名的元信息
 type ForwardRecord struct {
-	ID             uint   `gorm:"primaryKey;autoIncrement" json:"id"`
-	DomainRecordID uint   `gorm:"not null;index" json:"domain_record_id"`        // 外键 -> DomainRecord.ID
-	ForwardDomain  string `gorm:"size:255;not null;index" json:"forward_domain"` // 子域名，如 er.sdj.com
-	IP             string `gorm:"size:64;not null" json:"ip"`                    // 解析 IP
-	ISP            string `gorm:"size:64" json:"isp"`                            // 运营商，可选
-	IsBan          bool   `gorm:"default:false" json:"is_ban"`                   // 是否封禁
-	BanTime        int64  `json:"ban_time,omitempty"`                            // 封禁时间（可空）
-	Weight         int    `gorm:"default:0"`                                     // 权重，默认值为0
-	SortOrder      int    `gorm:"default:0" json:"sort_order"`                   // 排序字段
-	RecordType     string `gorm:"size:16;default:'A'" json:"record_type"`
-	LastResolvedAt int64  `gorm:"default:0" json:"last_resolved_at"`             // 最后解析时间戳
-	ResolveStatus  string `gorm:"size:32;default:'never'" json:"resolve_status"` // 解析状态: never, success, failed
-	CreatedAt      int64  `json:"created_at"`
-	UpdatedAt      int64  `json:"updated_at"`
+	ID             uint    `gorm:"primaryKey;autoIncrement" json:"id"`
+	DomainRecordID uint    `gorm:"not null;index" json:"domain_record_id"`        // 外键 -> DomainRecord.ID
+	ForwardDomain  string  `gorm:"size:255;not null;index" json:"forward_domain"` // 子域名，如 er.sdj.com
+	IP             string  `gorm:"size:64;not null" json:"ip"`                    // 解析 IP
+	ISP            string  `gorm:"size:64" json:"isp"`                            // 运营商，可选
+	IsBan          bool    `gorm:"default:false" json:"is_ban"`                   // 是否封禁
+	BanTime        int64   `json:"ban_time,omitempty"`                            // 封禁时间（可空）
+	Weight         int     `gorm:"default:0"`                                     // 权重，默认值为0
+	SortOrder      int     `gorm:"default:0" json:"sort_order"`                   // 排序字段
+	RecordType     string  `gorm:"size:16;default:'A'" json:"record_type"`        // 记录类型: A, AAAA, CNAME
+	LastResolvedAt int64   `gorm:"default:0" json:"last_resolved_at"`             // 最后解析时间戳
+	ResolveStatus  string  `gorm:"size:32;default:'never'" json:"resolve_status"` // 解析状态: never, success, failed
+	LastLatency    float64 `gorm:"default:0" json:"last_latency"`                 // 最近一次检测的平均连接耗时（毫秒），0 表示未知
+	HealthySince   int64   `gorm:"default:0" json:"healthy_since"`                // 回切探测中持续连通的起始时间戳，0 表示未连通或未探测
+	BanCount       int     `gorm:"default:0" json:"ban_count"`                    // 连续封禁次数，决定下一次封禁时长，检测连通后清零
+	LastBanAt      int64   `gorm:"default:0" json:"last_ban_at"`                  // 最近一次自动封禁的时间戳
+	CreatedAt      int64   `json:"created_at"`
+	UpdatedAt      int64   `json:"updated_at"`
 }
 
 // DomainRecord 表示主域名记录
 type DomainRecord struct {
-	ID             uint            `gorm:"primaryKey;autoIncrement" json:"id"`
-	Domain         string          `gorm:"size:255;not null;uniqueIndex:idx_domain_port" json:"domain"`                             // 主域名，如 main.jkl.com
-	Port           int             `gorm:"default:80;uniqueIndex:idx_domain_port" json:"port"`                                      // 对应端口
-	RecordId       string          `gorm:"size:255" json:"record_id"`                                                               // Cloudflare DNS 记录 ID
-	ZoneId         string          `gorm:"size:255" json:"zone_id"`                                                                 // Cloudflare Zone ID
-	Forwards       []ForwardRecord `gorm:"foreignKey:DomainRecordID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"forwards"` // 一对多关联
-	IsDisableCheck bool            `gorm:"default:false" json:"is_disable_check"`
-	SortOrder      int             `gorm:"default:0" json:"sort_order"` // 排序字段
-	CreatedAt      int64           `json:"created_at"`
-	UpdatedAt      int64           `json:"updated_at"`
+	ID              uint            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Domain          string          `gorm:"size:255;not null;uniqueIndex:idx_domain_port" json:"domain"`                             // 主域名，如 main.jkl.com
+	Port            int             `gorm:"default:80;uniqueIndex:idx_domain_port" json:"port"`                                      // 对应端口
+	RecordId        string          `gorm:"size:255" json:"record_id"`                                                               // Cloudflare DNS 记录 ID（A 或 CNAME 记录）
+	RecordIdV6      string          `gorm:"size:255" json:"record_id_v6"`                                                            // Cloudflare AAAA 记录 ID（IPv6 / 双栈主域名使用）
+	ZoneId          string          `gorm:"size:255" json:"zone_id"`                                                                 // Cloudflare Zone ID
+	Forwards        []ForwardRecord `gorm:"foreignKey:DomainRecordID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"forwards"` // 一对多关联
+	IsDisableCheck  bool            `gorm:"default:false" json:"is_disable_check"`
+	SortOrder       int             `gorm:"default:0" json:"sort_order"`                     // 排序字段
+	ProbeType       string          `gorm:"size:16;default:'tcp'" json:"probe_type"`         // 探测类型: tcp, http, https, tls, udp, icmp
+	HTTPMethod      string          `gorm:"size:8;default:'GET'" json:"http_method"`         // HTTP 探测方法: GET, HEAD
+	HTTPHost        string          `gorm:"size:255" json:"http_host"`                       // HTTP 探测 Host 头，为空时使用主域名
+	HTTPPath        string          `gorm:"size:255;default:'/'" json:"http_path"`           // HTTP 探测路径
+	HTTPStatusMin   int             `gorm:"default:200" json:"http_status_min"`              // 期望状态码下限
+	HTTPStatusMax   int             `gorm:"default:399" json:"http_status_max"`              // 期望状态码上限
+	HTTPBodyMatch   string          `gorm:"size:255" json:"http_body_match"`                 // 响应体需包含的字符串（可选）
+	HTTPBodyRegex   string          `gorm:"size:255" json:"http_body_regex"`                 // 响应体需匹配的正则（可选）
+	UDPPayload      string          `gorm:"size:1024" json:"udp_payload"`                    // UDP 探测载荷（十六进制）
+	UDPExpect       string          `gorm:"size:1024" json:"udp_expect"`                     // UDP 期望回包内容（十六进制，可选）
+	SelectStrategy  string          `gorm:"size:16;default:'weight'" json:"select_strategy"` // 转发选择策略: weight, latency, weight_ceiling
+	LatencyCeiling  int             `gorm:"default:300" json:"latency_ceiling"`              // weight_ceiling 策略的延迟上限（毫秒）
+	PublishMode     string          `gorm:"size:16;default:'best'" json:"publish_mode"`      // A 记录发布方式: best（最佳 IP）, all（全部健康 IP）
+	Resolver        string          `gorm:"size:64" json:"resolver"`                         // 检测后端解析目标使用的解析器名称，为空时使用后端默认
+	IPFamily        string          `gorm:"size:8;default:'ipv4'" json:"ip_family"`          // 地址族: ipv4, ipv6, dual（A 与 AAAA 各自独立切换）
+	DownThreshold   int             `gorm:"default:1" json:"down_threshold"`                 // 连续失败多少次判定主域名不通并切换
+	UpThreshold     int             `gorm:"default:1" json:"up_threshold"`                   // 判定不通后连续成功多少次判定恢复
+	FailStreak      int             `gorm:"default:0" json:"fail_streak"`                    // 当前连续失败次数（IPv4 地址族）
+	SuccessStreak   int             `gorm:"default:0" json:"success_streak"`                 // 当前连续成功次数（IPv4 地址族）
+	IsDown          bool            `gorm:"default:false" json:"is_down"`                    // 是否处于判定不通状态（IPv4 地址族）
+	FailStreakV6    int             `gorm:"default:0" json:"fail_streak_v6"`                 // 当前连续失败次数（IPv6 地址族）
+	SuccessStreakV6 int             `gorm:"default:0" json:"success_streak_v6"`              // 当前连续成功次数（IPv6 地址族）
+	IsDownV6        bool            `gorm:"default:false" json:"is_down_v6"`                 // 是否处于判定不通状态（IPv6 地址族）
+	FailbackEnabled bool            `gorm:"default:false" json:"failback_enabled"`           // 当前转发正常时也探测更高优先级的转发，恢复后自动回切
+	FailbackStable  int             `gorm:"default:600" json:"failback_stable"`              // 高优先级转发持续连通多久（秒）后回切
+	BanDurations    string          `gorm:"size:128" json:"ban_durations"`                   // 转发封禁时长（逗号分隔，如 10m,1h,6h,24h），为空时使用全局 ban_durations
+	CheckInterval   int             `gorm:"default:0" json:"check_interval"`                 // 自动检测间隔（秒），0 表示使用全局 check_time
+	CheckCron       string          `gorm:"size:64" json:"check_cron"`                       // 自动检测 cron 表达式（分 时 日 月 周），设置后优先于检测间隔
+	CheckJitter     int             `gorm:"default:0" json:"check_jitter"`                   // 每次检测时间随机推迟的上限（秒），错开同时到期的主域名
+	DryRun          bool            `gorm:"default:false" json:"dry_run"`                    // 模拟运行：完整检测但不修改 DNS、不封禁转发
+	CreatedAt       int64           `json:"created_at"`
+	UpdatedAt       int64           `json:"updated_at"`
+}
+
+// MaintenanceWindow 表示维护窗口，窗口内的检测只观察结果，不修改 DNS、不封禁转发
+type MaintenanceWindow struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Scope     string `gorm:"size:16;not null;index" json:"scope"` // 范围: global, domain, forward
+	TargetID  uint   `gorm:"default:0;index" json:"target_id"`    // 主域名或转发域名 ID，global 时为 0
+	StartAt   int64  `gorm:"default:0" json:"start_at"`           // 一次性窗口的开始时间戳
+	EndAt     int64  `gorm:"default:0" json:"end_at"`             // 一次性窗口的结束时间戳
+	Cron      string `gorm:"size:64" json:"cron"`                 // 周期窗口每次开始时间的 cron 表达式，为空表示一次性窗口
+	Duration  int    `gorm:"default:0" json:"duration"`           // 周期窗口每次持续的秒数
+	Note      string `gorm:"size:255" json:"note"`                // 备注
+	CreatedBy int64  `gorm:"default:0" json:"created_by"`         // 创建者 UID
+	CreatedAt int64  `json:"created_at"`
+}
+
+// CheckResult 表示一次主域名或转发域名的检测结果
+type CheckResult struct {
+	ID              uint    `gorm:"primaryKey;autoIncrement" json:"id"`
+	DomainRecordID  uint    `gorm:"not null;index" json:"domain_record_id"`   // 主域名 ID
+	ForwardRecordID uint    `gorm:"default:0;index" json:"forward_record_id"` // 转发域名 ID，主域名检测时为 0
+	Target          string  `gorm:"size:255" json:"target"`                   // 检测目标
+	Family          string  `gorm:"size:8" json:"family"`                     // 地址族: ipv4, ipv6
+	Backend         string  `gorm:"size:255" json:"backend"`                  // 参与检测的后端（多后端时附带各后端结果）
+	Verdict         string  `gorm:"size:16" json:"verdict"`                   // 检测结论: up, down, error（接口调用失败）
+	Message         string  `gorm:"size:255" json:"message"`                  // 后端返回的消息或错误
+	TargetIP        string  `gorm:"size:64" json:"target_ip"`                 // 后端解析到的 IP
+	Latency         float64 `gorm:"default:0" json:"latency"`                 // 平均连接耗时（毫秒），0 表示未知
+	Trigger         string  `gorm:"size:16" json:"trigger"`                   // 触发方式: auto, manual, failback
+	CreatedAt       int64   `gorm:"index" json:"created_at"`
+}
+
+// SwitchEvent 表示一次主域名 DNS 切换
+type SwitchEvent struct {
+	ID              uint    `gorm:"primaryKey;autoIncrement" json:"id"`
+	DomainRecordID  uint    `gorm:"not null;index" json:"domain_record_id"` // 主域名 ID
+	ForwardRecordID uint    `gorm:"default:0" json:"forward_record_id"`     // 切换到的转发域名 ID
+	Domain          string  `gorm:"size:255" json:"domain"`                 // 主域名
+	Port            int     `json:"port"`                                   // 主域名端口
+	RecordType      string  `gorm:"size:16" json:"record_type"`             // 记录类型: A, AAAA, CNAME
+	OldForward      string  `gorm:"size:255" json:"old_forward"`            // 切换前的转发域名，未知时为空
+	OldContent      string  `gorm:"size:1024" json:"old_content"`           // 切换前的记录内容，未知时为空
+	NewForward      string  `gorm:"size:255" json:"new_forward"`            // 切换后的转发域名
+	NewContent      string  `gorm:"size:1024" json:"new_content"`           // 切换后的记录内容
+	Latency         float64 `gorm:"default:0" json:"latency"`               // 新转发的平均连接耗时（毫秒）
+	Reason          string  `gorm:"size:255" json:"reason"`                 // 切换原因
+	Trigger         string  `gorm:"size:16" json:"trigger"`                 // 触发方式: auto, manual, failback
+	Simulated       bool    `gorm:"default:false" json:"simulated"`         // 模拟运行，未实际修改 DNS
+	CreatedAt       int64   `gorm:"index" json:"created_at"`
 }
 
 type TelegramAdmins struct {
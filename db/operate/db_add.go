@@ -23,6 +23,33 @@ func extractRootDomain(domain string) string {
 	return domain
 }
 
+// LookupRecordIDs 查找主域名在 Cloudflare 中的记录 ID
+// recordID 优先取 A 记录，没有时取其他类型（如 CNAME）；recordIDV6 取 AAAA 记录；两者都没有时返回错误
+func LookupRecordIDs(cfClient *cloudflare.Client, domain string) (recordID string, recordIDV6 string, err error) {
+	ctx := context.Background()
+	if aaaa, err := cfClient.GetDNSRecordByName(ctx, domain, "AAAA"); err == nil {
+		recordIDV6 = aaaa.ID
+		utils.Logger.Infof("✅ 自动获取 AAAA 记录 ID：%s -> %s (内容: %s)", domain, aaaa.ID, aaaa.Content)
+	}
+
+	record, err := cfClient.GetDNSRecordByName(ctx, domain, "A")
+	if err != nil {
+		record, err = cfClient.GetDNSRecordByName(ctx, domain, "")
+	}
+	if err == nil && record.ID != recordIDV6 {
+		recordID = record.ID
+		utils.Logger.Infof("✅ 自动获取 DNS ID：%s -> %s (类型: %s, 内容: %s)", domain, record.ID, record.Type, record.Content)
+	}
+
+	if recordID == "" && recordIDV6 == "" {
+		if err == nil {
+			err = fmt.Errorf("未找到记录: %s", domain)
+		}
+		return "", "", err
+	}
+	return recordID, recordIDV6, nil
+}
+
 // SaveToDBOnly 仅保存到数据库（已弃用缓存）
 func SaveToDBOnly(DB *gorm.DB, jsonStr string) error {
 	var domains []models.DomainRecord
@@ -43,17 +70,16 @@ func SaveToDBOnly(DB *gorm.DB, jsonStr string) error {
 			return fmt.Errorf("无法连接 Cloudflare (根域名: %s): %w", rootDomain, err)
 		}
 
-		// 检查域名在 Cloudflare 中是否存在对应的 DNS 记录
-		ctx := context.Background()
-		dnsRecord, err := cfClient.GetDNSRecordByName(ctx, d.Domain, "")
+		// 检查域名在 Cloudflare 中是否存在对应的 DNS 记录（A/CNAME 与 AAAA）
+		recordID, recordIDV6, err := LookupRecordIDs(cfClient, d.Domain)
 		if err != nil {
 			utils.Logger.Warnf("⚠️ 未在 Cloudflare 中找到域名: %s, 错误: %v", d.Domain, err)
 			// 不返回错误，继续处理（DNS ID 为空）
 		} else {
 			// 设置 DNS ID 和 Zone ID
-			d.RecordId = dnsRecord.ID
+			d.RecordId = recordID
+			d.RecordIdV6 = recordIDV6
 			d.ZoneId = cfClient.GetZoneID() // 使用客户端的 GetZoneID 方法
-			utils.Logger.Infof("✅ 自动获取 DNS ID：%s -> %s (类型: %s, 内容: %s)", d.Domain, dnsRecord.ID, dnsRecord.Type, dnsRecord.Content)
 			utils.Logger.Infof("✅ 自动获取 Zone ID：%s -> %s", d.Domain, cfClient.GetZoneID())
 		}
 
@@ -133,3 +159,34 @@ func AddAdministrator(DB *gorm.DB, admin models.TelegramAdmins) error {
 	utils.Logger.Infof("✅ 管理员信息已写入数据库 UID=%d", admin.UID)
 	return nil
 }
+
+// AddMaintenanceWindow 新增维护窗口
+func AddMaintenanceWindow(DB *gorm.DB, w *models.MaintenanceWindow) error {
+	w.CreatedAt = time.Now().Unix()
+	if err := DB.Create(w).Error; err != nil {
+		utils.Logger.Errorf("❌ 维护窗口写入数据库失败: %v", err)
+		return err
+	}
+	utils.Logger.Infof("✅ 维护窗口已写入数据库 ID=%d 范围=%s 目标=%d", w.ID, w.Scope, w.TargetID)
+	return nil
+}
+
+// AddCheckResult 记录单次检测结果
+func AddCheckResult(DB *gorm.DB, r *models.CheckResult) error {
+	r.CreatedAt = time.Now().Unix()
+	if err := DB.Create(r).Error; err != nil {
+		utils.Logger.Warnf("⚠️ 保存 %s 的检测结果失败: %v", r.Target, err)
+		return err
+	}
+	return nil
+}
+
+// AddSwitchEvent 记录主域名的一次解析切换
+func AddSwitchEvent(DB *gorm.DB, e *models.SwitchEvent) error {
+	e.CreatedAt = time.Now().Unix()
+	if err := DB.Create(e).Error; err != nil {
+		utils.Logger.Errorf("❌ 保存 %s 的切换记录失败: %v", e.Domain, err)
+		return err
+	}
+	return nil
+}
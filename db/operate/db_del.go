@@ -1 +1,43 @@
 package operate
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"telegram-auto-switch-dns-bot/db/models"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// DeleteMaintenanceWindow 按 ID 删除维护窗口
+func DeleteMaintenanceWindow(DB *gorm.DB, id uint) error {
+	result := DB.Delete(&models.MaintenanceWindow{}, id)
+	if result.Error != nil {
+		utils.Logger.Errorf("❌ 删除维护窗口失败 ID=%d: %v", id, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("维护窗口 ID=%d 不存在", id)
+	}
+	utils.Logger.Infof("✅ 维护窗口已删除 ID=%d", id)
+	return nil
+}
+
+// PruneCheckResults 删除 before 之前的检测结果
+func PruneCheckResults(DB *gorm.DB, before int64) (int64, error) {
+	result := DB.Where("created_at < ?", before).Delete(&models.CheckResult{})
+	if result.Error != nil {
+		utils.Logger.Errorf("❌ 清理过期检测结果失败: %v", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// PruneSwitchEvents 删除 before 之前的切换记录
+func PruneSwitchEvents(DB *gorm.DB, before int64) (int64, error) {
+	result := DB.Where("created_at < ?", before).Delete(&models.SwitchEvent{})
+	if result.Error != nil {
+		utils.Logger.Errorf("❌ 清理过期切换记录失败: %v", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -26,3 +26,30 @@ func GetAdministrator(DB *gorm.DB, uid int64) (*models.TelegramAdmins, error) {
 	utils.Logger.Infof("[Admin] ✅ 从数据库获取管理员 UID=%d", uid)
 	return admin, nil
 }
+
+// ListMaintenanceWindows 按 ID 顺序返回全部维护窗口
+func ListMaintenanceWindows(DB *gorm.DB) ([]models.MaintenanceWindow, error) {
+	var windows []models.MaintenanceWindow
+	if err := DB.Order("id asc").Find(&windows).Error; err != nil {
+		return nil, fmt.Errorf("查询维护窗口失败: %w", err)
+	}
+	return windows, nil
+}
+
+// ListSwitchEvents 返回主域名最近的切换记录，最新的在前
+func ListSwitchEvents(DB *gorm.DB, domainID uint, limit int) ([]models.SwitchEvent, error) {
+	var events []models.SwitchEvent
+	if err := DB.Where("domain_record_id = ?", domainID).Order("id desc").Limit(limit).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("查询切换记录失败: %w", err)
+	}
+	return events, nil
+}
+
+// ListCheckResults 返回主域名自身最近的检测结果（不含转发域名），最新的在前
+func ListCheckResults(DB *gorm.DB, domainID uint, limit int) ([]models.CheckResult, error) {
+	var results []models.CheckResult
+	if err := DB.Where("domain_record_id = ? AND forward_record_id = 0", domainID).Order("id desc").Limit(limit).Find(&results).Error; err != nil {
+		return nil, fmt.Errorf("查询检测结果失败: %w", err)
+	}
+	return results, nil
+}
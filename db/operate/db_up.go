@@ -20,14 +20,15 @@ func UpdateAdministrator(DB *gorm.DB, admin models.TelegramAdmins) error {
 	return nil
 }
 
-// UpdateDomainRecord updates a domain record in the database
-func UpdateDomainRecord(DB *gorm.DB, domain models.DomainRecord) error {
-	if err := DB.Save(&domain).Error; err != nil {
+// UpdateDomainFields updates only the given columns of a domain record,
+// leaving the health columns written concurrently by auto checks untouched
+func UpdateDomainFields(DB *gorm.DB, id uint, updates map[string]interface{}) error {
+	if err := DB.Model(&models.DomainRecord{}).Where("id = ?", id).Updates(updates).Error; err != nil {
 		utils.Logger.Errorf("Failed to update domain record: %v", err)
 		return fmt.Errorf("failed to update domain record: %w", err)
 	}
 
-	utils.Logger.Infof("✅ Domain record updated ID=%d", domain.ID)
+	utils.Logger.Infof("✅ Domain record updated ID=%d", id)
 	return nil
 }
 
@@ -42,16 +43,33 @@ func UpdateForwardRecord(DB *gorm.DB, forward models.ForwardRecord) error {
 	return nil
 }
 
-// BanForward24Hours bans a forward record for 24 hours
-func BanForward24Hours(DB *gorm.DB, f *models.ForwardRecord) error {
+// UpdateForwardFields updates only the given columns of a forward record,
+// leaving the ban, latency and failback columns written concurrently by auto checks untouched
+func UpdateForwardFields(DB *gorm.DB, id uint, updates map[string]interface{}) error {
+	if err := DB.Model(&models.ForwardRecord{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		utils.Logger.Errorf("Failed to update forward record: %v", err)
+		return fmt.Errorf("failed to update forward record: %w", err)
+	}
+
+	utils.Logger.Infof("✅ Forward record updated ID=%d", id)
+	return nil
+}
+
+// BanForward bans a forward record for the given duration and bumps its consecutive ban count
+func BanForward(DB *gorm.DB, f *models.ForwardRecord, duration time.Duration) error {
+	now := time.Now()
 	f.IsBan = true
-	f.BanTime = time.Now().Add(24 * time.Hour).Unix()
+	f.BanTime = now.Add(duration).Unix()
 	f.ResolveStatus = "failed" // Mark as detection failed
+	f.BanCount++
+	f.LastBanAt = now.Unix()
 
 	if err := DB.Model(f).Updates(map[string]interface{}{
 		"is_ban":         true,
 		"ban_time":       f.BanTime,
 		"resolve_status": "failed",
+		"ban_count":      f.BanCount,
+		"last_ban_at":    f.LastBanAt,
 	}).Error; err != nil {
 		utils.Logger.Errorf("❌ Failed to ban forward domain %s: %v", f.ForwardDomain, err)
 		return err
@@ -80,6 +98,19 @@ func UpdateForwardResolveStatus(DB *gorm.DB, f *models.ForwardRecord, status, re
 	return nil
 }
 
+// UpdateForwardLatency updates the last measured latency of a forward record
+func UpdateForwardLatency(DB *gorm.DB, f *models.ForwardRecord, latency float64) error {
+	f.LastLatency = latency
+	if err := DB.Model(f).Updates(map[string]interface{}{
+		"last_latency": latency,
+	}).Error; err != nil {
+		utils.Logger.Warnf("⚠️ Failed to update forward latency: %v", err)
+		return err
+	}
+
+	return nil
+}
+
 // ClearOtherForwardStatus clears the success status of other forward records
 func ClearOtherForwardStatus(DB *gorm.DB, domainRecordID, currentForwardID uint) error {
 	// Clear success status of other forward domains
@@ -95,6 +126,21 @@ func ClearOtherForwardStatus(DB *gorm.DB, domainRecordID, currentForwardID uint)
 	return nil
 }
 
+// ClearOtherForwardStatusOfType clears the success status of other forward records with the same record type
+// (dual-stack domains switch their A and AAAA records independently)
+func ClearOtherForwardStatusOfType(DB *gorm.DB, domainRecordID, currentForwardID uint, recordType string) error {
+	if err := DB.Model(&models.ForwardRecord{}).Where(
+		"domain_record_id = ? AND id != ? AND record_type = ?", domainRecordID, currentForwardID, recordType,
+	).Updates(map[string]interface{}{
+		"resolve_status": "never",
+	}).Error; err != nil {
+		utils.Logger.Warnf("⚠️ Failed to clear other forward domain status: %v", err)
+		return err
+	}
+
+	return nil
+}
+
 // UpdateDomainRecordIfExists updates a domain record if it exists
 func UpdateDomainRecordIfExists(DB *gorm.DB, domain *models.DomainRecord) error {
 	var existingDomain models.DomainRecord
@@ -104,6 +150,7 @@ func UpdateDomainRecordIfExists(DB *gorm.DB, domain *models.DomainRecord) error
 		// Exists → Update
 		domain.ID = existingDomain.ID
 		existingDomain.RecordId = domain.RecordId
+		existingDomain.RecordIdV6 = domain.RecordIdV6
 		existingDomain.SortOrder = domain.SortOrder
 		existingDomain.IsDisableCheck = domain.IsDisableCheck
 
@@ -185,3 +232,46 @@ func AddForwardRecord(DB *gorm.DB, forward models.ForwardRecord) error {
 	utils.Logger.Infof("✅ Forward record created ID=%d for domain ID=%d", forward.ID, forward.DomainRecordID)
 	return nil
 }
+
+// UpdateDomainHealth persists the consecutive check counters of one address family
+// (v6 selects the IPv6 columns), leaving the other columns untouched
+func UpdateDomainHealth(DB *gorm.DB, d *models.DomainRecord, v6 bool) error {
+	updates := map[string]interface{}{
+		"fail_streak":    d.FailStreak,
+		"success_streak": d.SuccessStreak,
+		"is_down":        d.IsDown,
+	}
+	if v6 {
+		updates = map[string]interface{}{
+			"fail_streak_v6":    d.FailStreakV6,
+			"success_streak_v6": d.SuccessStreakV6,
+			"is_down_v6":        d.IsDownV6,
+		}
+	}
+
+	if err := DB.Model(&models.DomainRecord{}).Where("id = ?", d.ID).Updates(updates).Error; err != nil {
+		utils.Logger.Warnf("⚠️ Failed to update domain health ID=%d: %v", d.ID, err)
+		return err
+	}
+	return nil
+}
+
+// UpdateForwardHealthySince records since when a forward has been continuously healthy during failback probing
+func UpdateForwardHealthySince(DB *gorm.DB, f *models.ForwardRecord, since int64) error {
+	f.HealthySince = since
+	if err := DB.Model(f).Update("healthy_since", since).Error; err != nil {
+		utils.Logger.Warnf("⚠️ Failed to update healthy_since of forward %s: %v", f.ForwardDomain, err)
+		return err
+	}
+	return nil
+}
+
+// ResetForwardBanCount clears the consecutive ban count once a forward passes a check again
+func ResetForwardBanCount(DB *gorm.DB, f *models.ForwardRecord) error {
+	f.BanCount = 0
+	if err := DB.Model(f).Update("ban_count", 0).Error; err != nil {
+		utils.Logger.Warnf("⚠️ Failed to reset ban count of forward %s: %v", f.ForwardDomain, err)
+		return err
+	}
+	return nil
+}
@@ -46,12 +46,21 @@ func CanManageAdmins(userID int64) bool {
 	return IsSuperAdmin(userID)
 }
 
-// ValidateBackendKey 验证后端通信密钥
+// ValidateBackendKey 验证后端通信密钥，验证通过后按密钥限流
+// 已通过 BackendSignature 签名校验的请求直接放行；未签名请求仅在开启 allow_legacy_key 时按请求体中的 key 验证
 func ValidateBackendKey(key string, c *gin.Context) bool {
+	if c.GetBool(signedContextKey) {
+		return allowKey(config.Global.BackendListen.Key, c)
+	}
+	if !config.Global.BackendListen.AllowLegacyKey {
+		utils.Logger.Error("后端请求未签名，已拒绝")
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "请求未签名"})
+		return false
+	}
 	if key != config.Global.BackendListen.Key {
 		utils.Logger.Error("后端通信密钥验证失败")
 		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "密钥验证失败"})
 		return false
 	}
-	return true
+	return allowKey(key, c)
 }
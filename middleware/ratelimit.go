@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// bucketIdleTTL 令牌桶空闲超过该时间后被清理
+const bucketIdleTTL = 10 * time.Minute
+
+// tokenBucket 单个限流对象的令牌桶
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter 按键（来源 IP / 通信密钥）划分的令牌桶限流器
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPurge time.Time
+}
+
+var (
+	ipLimiter  = &rateLimiter{buckets: make(map[string]*tokenBucket)}
+	keyLimiter = &rateLimiter{buckets: make(map[string]*tokenBucket)}
+)
+
+// allow 消耗一个令牌；令牌不足时返回 false 与下一个令牌可用前需要等待的时间
+func (l *rateLimiter) allow(key string, rate float64, burst int, now time.Time) (bool, time.Duration) {
+	if rate <= 0 {
+		return true, 0
+	}
+	capacity := float64(burst)
+	if capacity < rate {
+		capacity = math.Max(rate, 1)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPurge) > bucketIdleTTL {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > bucketIdleTTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastPurge = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// probes 正在进行的单次探测数：每次拨号占用一个名额，批量检测与 WebSocket 任务通道中的探测同样计数
+var (
+	probeMu   sync.Mutex
+	probeCond = sync.NewCond(&probeMu)
+	probes    int
+)
+
+// AcquireProbe 占用一个探测名额，达到 max_inflight 上限时等待其他探测结束
+func AcquireProbe() {
+	limit := config.Global.BackendListen.RateLimit.MaxInflight
+	probeMu.Lock()
+	for limit > 0 && probes >= limit {
+		probeCond.Wait()
+	}
+	probes++
+	probeMu.Unlock()
+}
+
+// ReleaseProbe 释放探测名额，唤醒一个等待中的探测
+func ReleaseProbe() {
+	probeMu.Lock()
+	probes--
+	probeMu.Unlock()
+	probeCond.Signal()
+}
+
+// probesSaturated 探测名额是否已全部占用
+func probesSaturated(limit int) bool {
+	probeMu.Lock()
+	defer probeMu.Unlock()
+	return limit > 0 && probes >= limit
+}
+
+// InflightCount 返回正在进行的单次探测数
+func InflightCount() int {
+	probeMu.Lock()
+	defer probeMu.Unlock()
+	return probes
+}
+
+// abortTooManyRequests 返回结构化的 429 响应
+// limit 为触发的限制类型：ip / key / inflight
+func abortTooManyRequests(c *gin.Context, limit string, message string, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	utils.Logger.Warnf("⚠️ 后端请求被限流 (%s, %s): %s", c.ClientIP(), limit, message)
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"code":    429,
+		"message": message,
+		"data": gin.H{
+			"limit":       limit,
+			"retry_after": seconds,
+		},
+	})
+}
+
+// BackendRateLimit 按来源 IP 限流，探测名额已全部占用时拒绝新的检测请求
+func BackendRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rl := config.Global.BackendListen.RateLimit
+
+		if ok, wait := ipLimiter.allow(c.ClientIP(), rl.IPRate, rl.IPBurst, time.Now()); !ok {
+			abortTooManyRequests(c, "ip", "请求过于频繁，请稍后再试", wait)
+			return
+		}
+
+		// WebSocket 任务通道是长连接，不在建立时拒绝，通道内任务的探测同样占用探测名额
+		// 其余请求不占用名额，名额在每次拨号时由 AcquireProbe 占用，这里只在后端已满载时提前拒绝
+		if !c.IsWebsocket() && probesSaturated(rl.MaxInflight) {
+			abortTooManyRequests(c, "inflight", "检测后端繁忙，请稍后再试", time.Second)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// allowKey 按通信密钥限流，超出时写入 429 响应并返回 false
+func allowKey(key string, c *gin.Context) bool {
+	rl := config.Global.BackendListen.RateLimit
+	sum := sha256.Sum256([]byte(key))
+	if ok, wait := keyLimiter.allow(hex.EncodeToString(sum[:8]), rl.KeyRate, rl.KeyBurst, time.Now()); !ok {
+		abortTooManyRequests(c, "key", "该密钥请求过于频繁，请稍后再试", wait)
+		return false
+	}
+	return true
+}
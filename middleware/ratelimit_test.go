@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"telegram-auto-switch-dns-bot/config"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+
+	type step struct {
+		at       time.Duration // 相对 start 的请求时间
+		key      string
+		want     bool
+		wantWait time.Duration
+	}
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		steps []step
+	}{
+		{name: "速率为 0 不限流", rate: 0, burst: 0, steps: []step{{want: true}, {want: true}, {want: true}}},
+		{name: "突发用尽后限流", rate: 1, burst: 2, steps: []step{
+			{key: "a", want: true}, {key: "a", want: true}, {key: "a", want: false, wantWait: time.Second},
+		}},
+		{name: "令牌按速率恢复", rate: 2, burst: 2, steps: []step{
+			{key: "a", want: true}, {key: "a", want: true},
+			{key: "a", at: 250 * time.Millisecond, want: false, wantWait: 250 * time.Millisecond},
+			{key: "a", at: 500 * time.Millisecond, want: true},
+		}},
+		{name: "突发小于速率时按速率计算容量", rate: 3, burst: 1, steps: []step{
+			{key: "a", want: true}, {key: "a", want: true}, {key: "a", want: true}, {key: "a", want: false, wantWait: time.Second / 3},
+		}},
+		{name: "不同键分别计数", rate: 1, burst: 1, steps: []step{
+			{key: "a", want: true}, {key: "b", want: true}, {key: "a", want: false, wantWait: time.Second},
+		}},
+		{name: "空闲恢复不超过容量", rate: 1, burst: 1, steps: []step{
+			{key: "a", want: true}, {key: "a", at: time.Hour, want: true}, {key: "a", at: time.Hour, want: false, wantWait: time.Second},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &rateLimiter{buckets: make(map[string]*tokenBucket)}
+			for i, s := range tt.steps {
+				ok, wait := l.allow(s.key, tt.rate, tt.burst, start.Add(s.at))
+				if ok != s.want {
+					t.Fatalf("step %d: allow() = %v, want %v", i, ok, s.want)
+				}
+				if diff := wait - s.wantWait; diff > time.Millisecond || diff < -time.Millisecond {
+					t.Errorf("step %d: wait = %s, want %s", i, wait, s.wantWait)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterPurge(t *testing.T) {
+	l := &rateLimiter{buckets: make(map[string]*tokenBucket)}
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	l.allow("idle", 1, 1, now)
+	l.allow("active", 1, 1, now.Add(bucketIdleTTL))
+	l.allow("active", 1, 1, now.Add(bucketIdleTTL+time.Minute))
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("idle bucket was not purged")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("active bucket was purged")
+	}
+}
+
+func TestAcquireProbe(t *testing.T) {
+	old := config.Global.BackendListen.RateLimit
+	config.Global.BackendListen.RateLimit = config.RateLimitConfig{MaxInflight: 2}
+	t.Cleanup(func() { config.Global.BackendListen.RateLimit = old })
+
+	AcquireProbe()
+	AcquireProbe()
+	if !probesSaturated(2) || InflightCount() != 2 {
+		t.Fatalf("InflightCount() = %d, want 2 and saturated", InflightCount())
+	}
+
+	// 名额用尽时等待，直到有探测释放名额
+	acquired := make(chan struct{})
+	go func() {
+		AcquireProbe()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("AcquireProbe() over limit did not wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+	ReleaseProbe()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("AcquireProbe() still waiting after release")
+	}
+
+	for i := 0; i < 2; i++ {
+		ReleaseProbe()
+	}
+	if InflightCount() != 0 {
+		t.Errorf("InflightCount() after release = %d, want 0", InflightCount())
+	}
+	if probesSaturated(0) {
+		t.Error("probesSaturated(0) = true, want unlimited")
+	}
+}
+
+func TestBackendRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	old := config.Global.BackendListen.RateLimit
+	t.Cleanup(func() {
+		config.Global.BackendListen.RateLimit = old
+		ipLimiter = &rateLimiter{buckets: make(map[string]*tokenBucket)}
+	})
+
+	tests := []struct {
+		name      string
+		rl        config.RateLimitConfig
+		inflight  int // 请求前已占用的探测名额
+		requests  int
+		wantCodes []int
+		wantLimit string // 最后一个请求被限流时触发的限制类型
+	}{
+		{name: "未配置不限流", requests: 3, wantCodes: []int{200, 200, 200}},
+		{name: "来源 IP 限流", rl: config.RateLimitConfig{IPRate: 1, IPBurst: 2}, requests: 3, wantCodes: []int{200, 200, 429}, wantLimit: "ip"},
+		{name: "并发上限", rl: config.RateLimitConfig{MaxInflight: 1}, inflight: 1, requests: 1, wantCodes: []int{429}, wantLimit: "inflight"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Global.BackendListen.RateLimit = tt.rl
+			ipLimiter = &rateLimiter{buckets: make(map[string]*tokenBucket)}
+			for i := 0; i < tt.inflight; i++ {
+				AcquireProbe()
+			}
+			defer func() {
+				for i := 0; i < tt.inflight; i++ {
+					ReleaseProbe()
+				}
+			}()
+
+			r := gin.New()
+			r.Use(BackendRateLimit())
+			r.POST("/check", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			var w *httptest.ResponseRecorder
+			for i := 0; i < tt.requests; i++ {
+				w = httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodPost, "/check", nil)
+				req.RemoteAddr = "203.0.113.9:40000"
+				r.ServeHTTP(w, req)
+				if w.Code != tt.wantCodes[i] {
+					t.Fatalf("request %d: status = %d, want %d", i+1, w.Code, tt.wantCodes[i])
+				}
+			}
+			if tt.wantLimit == "" {
+				return
+			}
+			if w.Header().Get("Retry-After") == "" {
+				t.Error("missing Retry-After header")
+			}
+			if body := w.Body.String(); !strings.Contains(body, `"limit":"`+tt.wantLimit+`"`) {
+				t.Errorf("body = %s, want limit %s", body, tt.wantLimit)
+			}
+		})
+	}
+	if InflightCount() != 0 {
+		t.Errorf("InflightCount() = %d after requests, want 0", InflightCount())
+	}
+}
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// 请求签名头
+const (
+	HeaderTimestamp = "X-Signature-Timestamp" // Unix 时间戳（秒）
+	HeaderNonce     = "X-Signature-Nonce"     // 随机数，时间窗口内不可重复
+	HeaderSignature = "X-Signature"           // HMAC-SHA256(key, method\npath?query\ntimestamp\nnonce\nsha256(body))
+)
+
+// HeaderBackendKey 兼容模式下无请求体的请求（GET、WebSocket 升级）携带明文 key 的请求头，避免 key 出现在 URL 与访问日志中
+const HeaderBackendKey = "X-Backend-Key"
+
+// signedContextKey 请求签名校验通过后写入 gin.Context 的标记
+const signedContextKey = "backend_signed"
+
+// defaultClockSkew 默认允许的时间偏差（秒）
+const defaultClockSkew = 300
+
+// maxSignedBodyBytes 参与签名校验的请求体上限
+const maxSignedBodyBytes = 4 << 20
+
+// trustedChannelKey 标记请求来自已认证的 WebSocket 任务通道
+type trustedChannelKey struct{}
+
+// WithTrustedChannel 标记请求来自已通过认证的 WebSocket 任务通道，通道内的任务无需再次签名
+func WithTrustedChannel(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trustedChannelKey{}, true)
+}
+
+// SignBackendRequest 为发往检测后端的请求添加时间戳、随机数与签名头
+func SignBackendRequest(req *http.Request, body []byte, key string) {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := hex.EncodeToString(b[:])
+
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, computeSignature(key, req.Method, req.URL.RequestURI(), timestamp, nonce, body))
+}
+
+// computeSignature 计算请求签名（十六进制），uri 为带查询参数的请求路径，查询参数被篡改时签名不匹配
+func computeSignature(key string, method string, uri string, timestamp string, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(method + "\n" + uri + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(bodyHash[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// clockSkew 返回允许的时间偏差
+func clockSkew() time.Duration {
+	skew := config.Global.BackendListen.MaxClockSkew
+	if skew <= 0 {
+		skew = defaultClockSkew
+	}
+	return time.Duration(skew) * time.Second
+}
+
+// nonceCache 记录时间窗口内已使用的随机数，防止请求重放
+type nonceCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPurge time.Time
+}
+
+var usedNonces = &nonceCache{seen: make(map[string]time.Time)}
+
+// add 记录随机数，已使用过时返回 false；过期记录按窗口周期清理
+func (n *nonceCache) add(nonce string, now time.Time, window time.Duration) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if now.Sub(n.lastPurge) > window {
+		for k, t := range n.seen {
+			if now.Sub(t) > window {
+				delete(n.seen, k)
+			}
+		}
+		n.lastPurge = now
+	}
+
+	if _, ok := n.seen[nonce]; ok {
+		return false
+	}
+	n.seen[nonce] = now
+	return true
+}
+
+// BackendSignature 校验检测后端请求的签名头；未携带签名头的请求交由 ValidateBackendKey 按兼容模式处理
+func BackendSignature() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if trusted, _ := c.Request.Context().Value(trustedChannelKey{}).(bool); trusted {
+			c.Set(signedContextKey, true)
+			c.Next()
+			return
+		}
+
+		signature := c.GetHeader(HeaderSignature)
+		if signature == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSignedBodyBytes))
+		if err != nil {
+			rejectSignature(c, errors.New("读取请求体失败"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if err := VerifySignedRequest(c.Request, body, config.Global.BackendListen.Key); err != nil {
+			rejectSignature(c, err)
+			return
+		}
+
+		c.Set(signedContextKey, true)
+		c.Next()
+	}
+}
+
+// rejectSignature 记录并拒绝签名校验失败的请求
+func rejectSignature(c *gin.Context, err error) {
+	utils.Logger.Warnf("⚠️ 后端请求签名校验失败 (%s): %v", c.ClientIP(), err)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "签名验证失败：" + err.Error()})
+}
+
+// VerifySignedRequest 校验请求的时间戳、签名与随机数（防重放），body 为已读取的请求体
+func VerifySignedRequest(r *http.Request, body []byte, key string) error {
+	signature := r.Header.Get(HeaderSignature)
+	timestamp := r.Header.Get(HeaderTimestamp)
+	nonce := r.Header.Get(HeaderNonce)
+	if signature == "" {
+		return errors.New("缺少签名")
+	}
+	if timestamp == "" || nonce == "" {
+		return errors.New("缺少时间戳或随机数")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("时间戳格式错误")
+	}
+	now := time.Now()
+	window := clockSkew()
+	if diff := now.Sub(time.Unix(ts, 0)); diff > window || diff < -window {
+		return errors.New("时间戳超出允许偏差")
+	}
+
+	expected := computeSignature(key, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("签名不匹配")
+	}
+
+	// 签名通过后才记录随机数，避免伪造请求占用缓存；窗口取两倍偏差覆盖前后两个方向
+	if !usedNonces.add(nonce, now, 2*window) {
+		return errors.New("随机数已使用")
+	}
+	return nil
+}
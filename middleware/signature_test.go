@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVerifySignedRequest(t *testing.T) {
+	const key = "test-key"
+	body := []byte(`{"target":"example.com"}`)
+
+	tests := []struct {
+		name    string
+		url     string
+		tamper  func(r *http.Request)
+		wantErr string
+	}{
+		{name: "签名通过", url: "http://backend/api/v1/info"},
+		{name: "带查询参数签名通过", url: "http://backend/api/v1/checks?probe=tcp&id=1"},
+		{name: "篡改查询参数", url: "http://backend/api/v1/checks?probe=tcp", tamper: func(r *http.Request) {
+			r.URL.RawQuery = "probe=http"
+		}, wantErr: "签名不匹配"},
+		{name: "追加查询参数", url: "http://backend/api/v1/info", tamper: func(r *http.Request) {
+			r.URL.RawQuery = "debug=1"
+		}, wantErr: "签名不匹配"},
+		{name: "篡改路径", url: "http://backend/api/v1/info", tamper: func(r *http.Request) {
+			r.URL.Path = "/api/v1/trace"
+		}, wantErr: "签名不匹配"},
+		{name: "缺少签名", url: "http://backend/api/v1/info", tamper: func(r *http.Request) {
+			r.Header.Del(HeaderSignature)
+		}, wantErr: "缺少签名"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			SignBackendRequest(req, body, key)
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+
+			err = VerifySignedRequest(req, body, key)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifySignedRequest() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("VerifySignedRequest() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySignedRequestReplay(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://backend/api/v1/info?x=1", nil)
+	SignBackendRequest(req, nil, "k")
+	if err := VerifySignedRequest(req, nil, "k"); err != nil {
+		t.Fatalf("first VerifySignedRequest() error = %v", err)
+	}
+	if err := VerifySignedRequest(req, nil, "k"); err == nil || !strings.Contains(err.Error(), "随机数已使用") {
+		t.Errorf("replayed VerifySignedRequest() error = %v, want nonce reuse", err)
+	}
+}
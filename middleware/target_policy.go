@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// portRange 端口范围（闭区间）
+type portRange struct {
+	from, to int
+}
+
+// targetPolicy 解析后的检测目标访问控制规则
+type targetPolicy struct {
+	allowNets    []*net.IPNet
+	denyNets     []*net.IPNet
+	allowPorts   []portRange
+	denyPorts    []portRange
+	allowPrivate bool
+}
+
+var (
+	policyOnce sync.Once
+	policy     targetPolicy
+)
+
+// sharedAddressSpace 运营商级 NAT 地址段（100.64.0.0/10），net.IP.IsPrivate 不包含
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0).To4(), Mask: net.CIDRMask(10, 32)}
+
+// loadTargetPolicy 解析配置中的访问控制规则，无效条目记录日志后忽略
+func loadTargetPolicy() targetPolicy {
+	policyOnce.Do(func() {
+		tp := config.Global.BackendListen.TargetPolicy
+		policy = targetPolicy{
+			allowNets:    parseCIDRs(tp.AllowCIDRs),
+			denyNets:     parseCIDRs(tp.DenyCIDRs),
+			allowPorts:   parsePortRanges(tp.AllowPorts),
+			denyPorts:    parsePortRanges(tp.DenyPorts),
+			allowPrivate: tp.AllowPrivate,
+		}
+	})
+	return policy
+}
+
+// parseCIDRs 解析 IP 段，单个 IP 视为 /32 或 /128
+func parseCIDRs(items []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if !strings.Contains(item, "/") {
+			if ip := net.ParseIP(item); ip != nil {
+				bits := 128
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+		_, n, err := net.ParseCIDR(item)
+		if err != nil {
+			utils.Logger.Warnf("⚠️ 忽略无效的目标 IP 段配置: %s", item)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// parsePortRanges 解析端口或端口范围（如 "443"、"1000-2000"）
+func parsePortRanges(items []string) []portRange {
+	ranges := make([]portRange, 0, len(items))
+	for _, item := range items {
+		from, to, found := strings.Cut(strings.TrimSpace(item), "-")
+		if !found {
+			to = from
+		}
+		f, err1 := strconv.Atoi(strings.TrimSpace(from))
+		t, err2 := strconv.Atoi(strings.TrimSpace(to))
+		if err1 != nil || err2 != nil || f < 0 || t > 65535 || f > t {
+			utils.Logger.Warnf("⚠️ 忽略无效的目标端口配置: %s", item)
+			continue
+		}
+		ranges = append(ranges, portRange{from: f, to: t})
+	}
+	return ranges
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPort(ranges []portRange, port int) bool {
+	for _, r := range ranges {
+		if port >= r.from && port <= r.to {
+			return true
+		}
+	}
+	return false
+}
+
+// isInternalIP 判断是否为私有、回环、链路本地、未指定或组播等内网地址
+func isInternalIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// CheckTargetIP 判断是否允许检测该 IP：拒绝列表优先；配置了允许列表时必须命中；
+// 内网地址默认禁止，除非开启 allow_private 或被允许列表显式包含
+func CheckTargetIP(ip net.IP) error {
+	p := loadTargetPolicy()
+	if containsIP(p.denyNets, ip) {
+		return fmt.Errorf("目标 IP %s 在禁止检测的范围内", ip)
+	}
+	allowed := containsIP(p.allowNets, ip)
+	if len(p.allowNets) > 0 && !allowed {
+		return fmt.Errorf("目标 IP %s 不在允许检测的范围内", ip)
+	}
+	if !p.allowPrivate && !allowed && isInternalIP(ip) {
+		return fmt.Errorf("禁止检测内网地址 %s", ip)
+	}
+	return nil
+}
+
+// CheckTargetPort 判断是否允许检测该端口
+func CheckTargetPort(port int) error {
+	p := loadTargetPolicy()
+	if containsPort(p.denyPorts, port) {
+		return fmt.Errorf("端口 %d 禁止检测", port)
+	}
+	if len(p.allowPorts) > 0 && !containsPort(p.allowPorts, port) {
+		return fmt.Errorf("端口 %d 不在允许检测的范围内", port)
+	}
+	return nil
+}
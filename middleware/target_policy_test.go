@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"telegram-auto-switch-dns-bot/config"
+)
+
+// setTargetPolicy 设置访问控制配置并重新解析
+func setTargetPolicy(t *testing.T, tp config.TargetPolicyConfig) {
+	t.Helper()
+	old := config.Global.BackendListen.TargetPolicy
+	config.Global.BackendListen.TargetPolicy = tp
+	policyOnce = sync.Once{}
+	t.Cleanup(func() {
+		config.Global.BackendListen.TargetPolicy = old
+		policyOnce = sync.Once{}
+	})
+}
+
+func TestParsePortRanges(t *testing.T) {
+	got := parsePortRanges([]string{"443", " 1000 - 2000 ", "abc", "2000-1000", "70000", "-1"})
+	want := []portRange{{443, 443}, {1000, 2000}}
+	if len(got) != len(want) {
+		t.Fatalf("parsePortRanges() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parsePortRanges()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseCIDRs(t *testing.T) {
+	nets := parseCIDRs([]string{"203.0.113.0/24", " 198.51.100.7 ", "2001:db8::1", "bad", "10.0.0.0/33"})
+	if len(nets) != 3 {
+		t.Fatalf("parseCIDRs() returned %d nets, want 3", len(nets))
+	}
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "203.0.113.200", want: true},
+		{ip: "198.51.100.7", want: true},
+		{ip: "198.51.100.8", want: false},
+		{ip: "2001:db8::1", want: true},
+		{ip: "2001:db8::2", want: false},
+	}
+	for _, tt := range tests {
+		if got := containsIP(nets, net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("containsIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckTargetIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  config.TargetPolicyConfig
+		ip      string
+		wantErr bool
+	}{
+		{name: "默认允许公网地址", ip: "8.8.8.8"},
+		{name: "默认禁止私有地址", ip: "192.168.1.1", wantErr: true},
+		{name: "默认禁止回环地址", ip: "127.0.0.1", wantErr: true},
+		{name: "默认禁止 IPv6 回环", ip: "::1", wantErr: true},
+		{name: "默认禁止链路本地", ip: "169.254.169.254", wantErr: true},
+		{name: "默认禁止运营商级 NAT", ip: "100.64.1.1", wantErr: true},
+		{name: "默认禁止未指定地址", ip: "0.0.0.0", wantErr: true},
+		{name: "开启 allow_private", policy: config.TargetPolicyConfig{AllowPrivate: true}, ip: "10.0.0.1"},
+		{name: "允许列表显式包含内网", policy: config.TargetPolicyConfig{AllowCIDRs: []string{"10.0.0.0/8"}}, ip: "10.1.2.3"},
+		{name: "不在允许列表", policy: config.TargetPolicyConfig{AllowCIDRs: []string{"10.0.0.0/8"}}, ip: "8.8.8.8", wantErr: true},
+		{name: "拒绝列表优先", policy: config.TargetPolicyConfig{AllowCIDRs: []string{"8.8.0.0/16"}, DenyCIDRs: []string{"8.8.8.8"}}, ip: "8.8.8.8", wantErr: true},
+		{name: "拒绝列表优先于 allow_private", policy: config.TargetPolicyConfig{AllowPrivate: true, DenyCIDRs: []string{"10.0.0.0/8"}}, ip: "10.0.0.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTargetPolicy(t, tt.policy)
+			if err := CheckTargetIP(net.ParseIP(tt.ip)); (err != nil) != tt.wantErr {
+				t.Errorf("CheckTargetIP(%s) error = %v, wantErr %v", tt.ip, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckTargetPort(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  config.TargetPolicyConfig
+		port    int
+		wantErr bool
+	}{
+		{name: "未配置不限制", port: 22},
+		{name: "在允许范围内", policy: config.TargetPolicyConfig{AllowPorts: []string{"443", "8000-9000"}}, port: 8443},
+		{name: "不在允许范围内", policy: config.TargetPolicyConfig{AllowPorts: []string{"443", "8000-9000"}}, port: 22, wantErr: true},
+		{name: "禁止端口", policy: config.TargetPolicyConfig{DenyPorts: []string{"25"}}, port: 25, wantErr: true},
+		{name: "拒绝优先于允许", policy: config.TargetPolicyConfig{AllowPorts: []string{"1-1024"}, DenyPorts: []string{"25"}}, port: 25, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTargetPolicy(t, tt.policy)
+			if err := CheckTargetPort(tt.port); (err != nil) != tt.wantErr {
+				t.Errorf("CheckTargetPort(%d) error = %v, wantErr %v", tt.port, err, tt.wantErr)
+			}
+		})
+	}
+}
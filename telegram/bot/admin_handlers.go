@@ -1,11 +1,12 @@
 package bot
 
 import (
-	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -135,6 +136,11 @@ func showDomainDetail(bot *tgbotapi.BotAPI, chatID int64, messageID int, domainI
 		dnsIDText = "未设置"
 	}
 
+	dnsIDV6Text := d.RecordIdV6
+	if dnsIDV6Text == "" {
+		dnsIDV6Text = "未设置"
+	}
+
 	zoneIDText := d.ZoneId
 	if zoneIDText == "" {
 		zoneIDText = "未设置"
@@ -147,11 +153,54 @@ func showDomainDetail(bot *tgbotapi.BotAPI, chatID int64, messageID int, domainI
 			"*端口*: `%d`\n"+
 			"*排序*: `%d`\n"+
 			"*检测状态*: `%s`\n"+
+			"*探测类型*: `%s`\n"+
+			"*选择策略*: `%s`\n"+
+			"*延迟上限*: `%d ms`\n"+
+			"*A 记录发布*: `%s`\n"+
+			"*解析器*: `%s`\n"+
+			"*地址族*: `%s`\n"+
+			"*切换阈值*: `%s`\n"+
+			"*连续检测*: `%s`\n"+
+			"*自动回切*: `%s`\n"+
+			"*封禁时长*: `%s`\n"+
+			"*检测计划*: `%s`\n"+
+			"*模拟运行*: `%s`\n"+
 			"*DNS ID*: `%s`\n"+
+			"*AAAA DNS ID*: `%s`\n"+
 			"*Zone ID*: `%s`",
-		d.ID, d.Domain, d.Port, d.SortOrder, status, dnsIDText, zoneIDText,
+		d.ID, d.Domain, d.Port, d.SortOrder, status, probeTypeLabel(d.ProbeType),
+		selectStrategyLabel(d.SelectStrategy), int(latencyCeiling(d)), publishModeLabel(d.PublishMode), resolverLabel(d.Resolver),
+		ipFamilyLabel(d.IPFamily), thresholdLabel(d), healthLabel(d), failbackLabel(d), banDurationsLabel(d), scheduleLabel(d), dryRunLabel(d), dnsIDText, dnsIDV6Text, zoneIDText,
 	)
 
+	// HTTP(S) 探测时展示探测参数
+	if d.ProbeType == "http" || d.ProbeType == "https" {
+		host := d.HTTPHost
+		if host == "" {
+			host = d.Domain
+		}
+		text += fmt.Sprintf(
+			"\n\n🌐 *HTTP 探测参数*\n"+
+				"*请求*: %s\n"+
+				"*Host*: %s\n"+
+				"*状态码*: `%d-%d`\n"+
+				"*响应体关键字*: %s\n"+
+				"*响应体正则*: %s",
+			markdownCode(d.HTTPMethod+" "+d.HTTPPath), markdownCode(host), d.HTTPStatusMin, d.HTTPStatusMax,
+			markdownCode(emptyText(d.HTTPBodyMatch)), markdownCode(emptyText(d.HTTPBodyRegex)),
+		)
+	}
+
+	// UDP 探测时展示载荷与期望回包
+	if d.ProbeType == "udp" {
+		text += fmt.Sprintf(
+			"\n\n📦 *UDP 探测参数*\n"+
+				"*载荷*: %s\n"+
+				"*期望回包*: %s",
+			markdownCode(emptyText(d.UDPPayload)), markdownCode(emptyText(d.UDPExpect)),
+		)
+	}
+
 	edit := tgbotapi.NewEditMessageTextAndMarkup(
 		chatID,
 		messageID,
@@ -227,10 +276,11 @@ func handleDomainToggleCheck(domainID uint) {
 
 	// 切换检测状态
 	d.IsDisableCheck = !d.IsDisableCheck
-	if err := operate.UpdateDomainRecord(db.DB, d); err != nil {
+	if err := operate.UpdateDomainFields(db.DB, d.ID, map[string]interface{}{"is_disable_check": d.IsDisableCheck}); err != nil {
 		utils.Logger.Errorf("更新检测状态失败：%v", err)
 		return
 	}
+	scheduler.invalidate()
 
 	statusText := "启用检测"
 	if d.IsDisableCheck {
@@ -239,6 +289,297 @@ func handleDomainToggleCheck(domainID uint) {
 	utils.Logger.Infof("✅ 主域名 %s (ID=%d) 检测状态已切换为: %s", d.Domain, domainID, statusText)
 }
 
+// 探测类型切换顺序
+var probeTypes = []string{"tcp", "http", "https", "tls", "udp", "icmp"}
+
+// probeTypeLabel 探测类型展示名称
+func probeTypeLabel(probeType string) string {
+	if probeType == "" {
+		probeType = "tcp"
+	}
+	return strings.ToUpper(probeType)
+}
+
+// emptyText 空字符串显示为 "无"
+func emptyText(s string) string {
+	if s == "" {
+		return "无"
+	}
+	return s
+}
+
+// 切换主域名探测类型（按 probeTypes 顺序循环，静默更新）
+func handleDomainToggleProbe(domainID uint) {
+	if db.DB == nil {
+		if err := db.InitDB(); err != nil {
+			utils.Logger.Errorf("数据库初始化失败：%v", err)
+			return
+		}
+	}
+
+	var d models.DomainRecord
+	if err := db.DB.Where("id = ?", domainID).First(&d).Error; err != nil {
+		utils.Logger.Errorf("未找到主域名 ID=%d: %v", domainID, err)
+		return
+	}
+
+	next := probeTypes[0]
+	for i, t := range probeTypes {
+		if t == d.ProbeType {
+			next = probeTypes[(i+1)%len(probeTypes)]
+			break
+		}
+	}
+	d.ProbeType = next
+	if err := operate.UpdateDomainFields(db.DB, d.ID, map[string]interface{}{"probe_type": next}); err != nil {
+		utils.Logger.Errorf("更新探测类型失败：%v", err)
+		return
+	}
+
+	utils.Logger.Infof("✅ 主域名 %s (ID=%d) 探测类型已切换为: %s", d.Domain, domainID, next)
+}
+
+// handleDomainToggleStrategy 按 selectStrategies 顺序切换主域名的转发选择策略
+func handleDomainToggleStrategy(domainID uint) {
+	if db.DB == nil {
+		if err := db.InitDB(); err != nil {
+			utils.Logger.Errorf("数据库初始化失败：%v", err)
+			return
+		}
+	}
+
+	var d models.DomainRecord
+	if err := db.DB.Where("id = ?", domainID).First(&d).Error; err != nil {
+		utils.Logger.Errorf("未找到主域名 ID=%d: %v", domainID, err)
+		return
+	}
+
+	next := selectStrategies[0]
+	for i, s := range selectStrategies {
+		if s == d.SelectStrategy {
+			next = selectStrategies[(i+1)%len(selectStrategies)]
+			break
+		}
+	}
+	d.SelectStrategy = next
+	if err := operate.UpdateDomainFields(db.DB, d.ID, map[string]interface{}{"select_strategy": next}); err != nil {
+		utils.Logger.Errorf("更新选择策略失败：%v", err)
+		return
+	}
+
+	utils.Logger.Infof("✅ 主域名 %s (ID=%d) 选择策略已切换为: %s", d.Domain, domainID, next)
+}
+
+// handleDomainToggleFailback 切换主域名的自动回切开关
+func handleDomainToggleFailback(domainID uint) {
+	if db.DB == nil {
+		if err := db.InitDB(); err != nil {
+			utils.Logger.Errorf("数据库初始化失败：%v", err)
+			return
+		}
+	}
+
+	var d models.DomainRecord
+	if err := db.DB.Where("id = ?", domainID).First(&d).Error; err != nil {
+		utils.Logger.Errorf("未找到主域名 ID=%d: %v", domainID, err)
+		return
+	}
+
+	d.FailbackEnabled = !d.FailbackEnabled
+	if err := operate.UpdateDomainFields(db.DB, d.ID, map[string]interface{}{"failback_enabled": d.FailbackEnabled}); err != nil {
+		utils.Logger.Errorf("更新自动回切失败：%v", err)
+		return
+	}
+	utils.Logger.Infof("✅ 主域名 %s (ID=%d) 自动回切已切换为: %s", d.Domain, domainID, failbackLabel(d))
+}
+
+// handleDomainToggleDryRun 切换主域名的模拟运行开关
+func handleDomainToggleDryRun(domainID uint) {
+	if db.DB == nil {
+		if err := db.InitDB(); err != nil {
+			utils.Logger.Errorf("数据库初始化失败：%v", err)
+			return
+		}
+	}
+
+	var d models.DomainRecord
+	if err := db.DB.Where("id = ?", domainID).First(&d).Error; err != nil {
+		utils.Logger.Errorf("未找到主域名 ID=%d: %v", domainID, err)
+		return
+	}
+
+	d.DryRun = !d.DryRun
+	if err := operate.UpdateDomainFields(db.DB, d.ID, map[string]interface{}{"dry_run": d.DryRun}); err != nil {
+		utils.Logger.Errorf("更新模拟运行失败：%v", err)
+		return
+	}
+	utils.Logger.Infof("✅ 主域名 %s (ID=%d) 模拟运行已切换为: %s", d.Domain, domainID, dryRunLabel(d))
+}
+
+// handleDomainTogglePublish 切换主域名 A 记录的发布方式（最佳 IP / 全部健康 IP）
+// 切回最佳 IP 时删除之前发布的附加 A 记录，只保留 DNS ID 对应的记录
+func handleDomainTogglePublish(domainID uint) {
+	if db.DB == nil {
+		if err := db.InitDB(); err != nil {
+			utils.Logger.Errorf("数据库初始化失败：%v", err)
+			return
+		}
+	}
+
+	var d models.DomainRecord
+	if err := db.DB.Where("id = ?", domainID).First(&d).Error; err != nil {
+		utils.Logger.Errorf("未找到主域名 ID=%d: %v", domainID, err)
+		return
+	}
+
+	wasAll := d.PublishMode == "all"
+	if wasAll {
+		d.PublishMode = "best"
+	} else {
+		d.PublishMode = "all"
+	}
+	if err := operate.UpdateDomainFields(db.DB, d.ID, map[string]interface{}{"publish_mode": d.PublishMode}); err != nil {
+		utils.Logger.Errorf("更新发布方式失败：%v", err)
+		return
+	}
+	utils.Logger.Infof("✅ 主域名 %s (ID=%d) A 记录发布方式已切换为: %s", d.Domain, domainID, d.PublishMode)
+
+	if !wasAll {
+		return
+	}
+	for _, family := range domainFamilies(d) {
+		recordID := familyRecordID(d, family)
+		if recordID == "" {
+			continue
+		}
+		client, err := cloudflare.GetGlobalClient()
+		if err != nil {
+			utils.Logger.Errorf("❌ 获取 Cloudflare 客户端失败: %v", err)
+			return
+		}
+		recordType := familyRecordType(family)
+		if err := client.DeleteExtraDNSRecords(d.Domain, d.ZoneId, recordID, recordType, d.Domain); err != nil {
+			utils.Logger.Warnf("⚠️ 删除主域名 %s 的附加 %s 记录失败: %v", d.Domain, recordType, err)
+		}
+	}
+}
+
+// handleDomainToggleFamily 按 ipFamilies 顺序切换主域名的地址族
+// 切换到需要 IPv6 的地址族且还没有 AAAA 记录 ID 时，从 Cloudflare 查找
+func handleDomainToggleFamily(domainID uint) {
+	if db.DB == nil {
+		if err := db.InitDB(); err != nil {
+			utils.Logger.Errorf("数据库初始化失败：%v", err)
+			return
+		}
+	}
+
+	var d models.DomainRecord
+	if err := db.DB.Where("id = ?", domainID).First(&d).Error; err != nil {
+		utils.Logger.Errorf("未找到主域名 ID=%d: %v", domainID, err)
+		return
+	}
+
+	next := ipFamilies[0]
+	for i, f := range ipFamilies {
+		if f == d.IPFamily {
+			next = ipFamilies[(i+1)%len(ipFamilies)]
+			break
+		}
+	}
+	d.IPFamily = next
+
+	if next != "ipv4" && d.RecordIdV6 == "" {
+		cfClient, err := cloudflare.NewClientByDomain(extractRootDomain(d.Domain))
+		if err != nil {
+			utils.Logger.Warnf("⚠️ 无法连接 Cloudflare 查找 AAAA 记录: %v", err)
+		} else if _, recordIDV6, err := operate.LookupRecordIDs(cfClient, d.Domain); err == nil && recordIDV6 != "" {
+			d.RecordIdV6 = recordIDV6
+		} else {
+			utils.Logger.Warnf("⚠️ 主域名 %s 在 Cloudflare 中没有 AAAA 记录，IPv6 切换前请先创建", d.Domain)
+		}
+	}
+
+	if err := operate.UpdateDomainFields(db.DB, d.ID, map[string]interface{}{"ip_family": d.IPFamily, "record_id_v6": d.RecordIdV6}); err != nil {
+		utils.Logger.Errorf("更新地址族失败：%v", err)
+		return
+	}
+
+	utils.Logger.Infof("✅ 主域名 %s (ID=%d) 地址族已切换为: %s", d.Domain, domainID, next)
+}
+
+// parseHTTPProbeInput 解析 HTTP 探测参数输入：方法|Host|路径|状态码范围|响应体关键字|响应体正则
+func parseHTTPProbeInput(d *models.DomainRecord, text string) error {
+	parts := strings.Split(text, "|")
+	if len(parts) < 6 {
+		return fmt.Errorf("输入格式错误，请按照格式输入：方法|Host|路径|状态码范围|响应体关键字|响应体正则")
+	}
+
+	method := strings.ToUpper(strings.TrimSpace(parts[0]))
+	if method == "" {
+		method = "GET"
+	}
+	if method != "GET" && method != "HEAD" {
+		return fmt.Errorf("请求方法仅支持 GET 或 HEAD")
+	}
+
+	path := strings.TrimSpace(parts[2])
+	if path == "" {
+		path = "/"
+	}
+
+	statusMin, statusMax := 200, 399
+	if statusRange := strings.TrimSpace(parts[3]); statusRange != "" {
+		bounds := strings.SplitN(statusRange, "-", 2)
+		if len(bounds) != 2 {
+			return fmt.Errorf("状态码范围格式错误，示例：200-399")
+		}
+		var err1, err2 error
+		statusMin, err1 = strconv.Atoi(strings.TrimSpace(bounds[0]))
+		statusMax, err2 = strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if err1 != nil || err2 != nil || statusMin > statusMax {
+			return fmt.Errorf("状态码范围格式错误，示例：200-399")
+		}
+	}
+
+	bodyRegex := strings.TrimSpace(parts[5])
+	if bodyRegex != "" {
+		if _, err := regexp.Compile(bodyRegex); err != nil {
+			return fmt.Errorf("响应体正则错误：%v", err)
+		}
+	}
+
+	d.HTTPMethod = method
+	d.HTTPHost = strings.TrimSpace(parts[1])
+	d.HTTPPath = path
+	d.HTTPStatusMin = statusMin
+	d.HTTPStatusMax = statusMax
+	d.HTTPBodyMatch = strings.TrimSpace(parts[4])
+	d.HTTPBodyRegex = bodyRegex
+	return nil
+}
+
+// parseUDPProbeInput 解析 UDP 探测参数输入：载荷|期望回包（均为十六进制）
+func parseUDPProbeInput(d *models.DomainRecord, text string) error {
+	parts := strings.SplitN(text, "|", 2)
+	if len(parts) < 2 {
+		return fmt.Errorf("输入格式错误，请按照格式输入：载荷|期望回包")
+	}
+
+	payload := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(parts[0]), " ", ""))
+	expect := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(parts[1]), " ", ""))
+	if _, err := hex.DecodeString(payload); err != nil {
+		return fmt.Errorf("载荷不是有效的十六进制：%v", err)
+	}
+	if _, err := hex.DecodeString(expect); err != nil {
+		return fmt.Errorf("期望回包不是有效的十六进制：%v", err)
+	}
+
+	d.UDPPayload = payload
+	d.UDPExpect = expect
+	return nil
+}
+
 // 显示封禁确认界面（编辑当前消息）
 func showAdminBanConfirm(bot *tgbotapi.BotAPI, chatID int64, messageID int, uid int64) {
 	var a models.TelegramAdmins
@@ -475,7 +816,7 @@ func handleForwardToggleBan(bot *tgbotapi.BotAPI, chatID int64, forwardID uint)
 		f.BanTime = 0 // 初始值
 	}
 
-	if err := operate.UpdateForwardRecord(db.DB, f); err != nil {
+	if err := operate.UpdateForwardFields(db.DB, f.ID, map[string]interface{}{"is_ban": f.IsBan, "ban_time": f.BanTime}); err != nil {
 		msg := tgbotapi.NewMessage(chatID, "更新封禁状态失败："+err.Error())
 		_, _ = bot.Send(msg)
 		return
@@ -603,7 +944,7 @@ func handleForwardGetIP(bot *tgbotapi.BotAPI, chatID int64, messageID int, forwa
 
 	// 更新数据库
 	f.IP = targetIP
-	if err := operate.UpdateForwardRecord(db.DB, f); err != nil {
+	if err := operate.UpdateForwardFields(db.DB, f.ID, map[string]interface{}{"ip": f.IP}); err != nil {
 		edit := tgbotapi.NewEditMessageText(chatID, messageID,
 			fmt.Sprintf("❌ 更新数据库失败：%v", err))
 		_, _ = bot.Send(edit)
@@ -641,26 +982,16 @@ func handleForwardGetIP(bot *tgbotapi.BotAPI, chatID int64, messageID int, forwa
 
 // 调用后端 resolve_ip 接口获取 IP（POST /api/v1/resolve_ip）
 func callBackendResolveIP(target string, port int) (string, error) {
-	// 构建请求 URL
-	url := fmt.Sprintf("%s/api/v1/resolve_ip", config.Global.BackendURL.Api)
-
 	// 构建请求体
 	payload := CheckBackend.TCPCheckRequest{
 		Target: target,
 		Port:   port,
-		Key:    config.Global.BackendListen.Key,
+		Key:    legacyKey(),
 	}
 	buf, _ := json.Marshal(payload)
 
-	// 发送 POST 请求
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(buf))
-	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
+	// 发送 POST 请求（解析 IP 只需一个后端，多后端时使用第一个）
+	resp, err := doBackend(probeBackendList()[0], http.MethodPost, "/api/v1/resolve_ip", buf, 10*time.Second)
 	if err != nil {
 		return "", fmt.Errorf("调用后端接口失败: %w", err)
 	}
@@ -769,6 +1100,7 @@ func handleDomainDelete(bot *tgbotapi.BotAPI, chatID int64, messageID int, domai
 		return
 	}
 	utils.Logger.Infof("✅ 已删除主域名: %s (ID=%d)", domainName, domainID)
+	scheduler.invalidate()
 
 	text := fmt.Sprintf("✅ *删除成功*\n\n已删除主域名: `%s` 及其 `%d` 个转发记录", domainName, forwardCount)
 	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
@@ -810,9 +1142,12 @@ func handleDomainEditInput(ctx UpdateContext) bool {
 	oldDomainName := d.Domain
 	// 这里不再保存旧值/新值（已简化提示逻辑）
 
+	// 只更新编辑的字段，避免覆盖自动检测同时写入的连续检测状态
+	var updates map[string]interface{}
 	switch session.Field {
 	case "name":
 		d.Domain = text
+		updates = map[string]interface{}{"domain": d.Domain}
 	case "port":
 		port, err := strconv.Atoi(text)
 		if err != nil {
@@ -820,6 +1155,7 @@ func handleDomainEditInput(ctx UpdateContext) bool {
 			return true
 		}
 		d.Port = port
+		updates = map[string]interface{}{"port": d.Port}
 	case "sort":
 		sortVal, err := strconv.Atoi(text)
 		if err != nil {
@@ -827,6 +1163,68 @@ func handleDomainEditInput(ctx UpdateContext) bool {
 			return true
 		}
 		d.SortOrder = sortVal
+		updates = map[string]interface{}{"sort_order": d.SortOrder}
+	case "ceiling":
+		ceiling, err := strconv.Atoi(text)
+		if err != nil || ceiling <= 0 {
+			SendMessage(ctx, 0, false, "❌ 延迟上限必须是正整数（毫秒），请重新输入。")
+			return true
+		}
+		d.LatencyCeiling = ceiling
+		updates = map[string]interface{}{"latency_ceiling": d.LatencyCeiling}
+	case "failback":
+		stable, err := strconv.Atoi(text)
+		if err != nil || stable <= 0 {
+			SendMessage(ctx, 0, false, "❌ 稳定期必须是正整数（秒），请重新输入。")
+			return true
+		}
+		d.FailbackStable = stable
+		updates = map[string]interface{}{"failback_stable": d.FailbackStable}
+	case "threshold":
+		if err := parseThresholdInput(&d, text); err != nil {
+			SendMessage(ctx, 0, false, "❌ %v", err)
+			return true
+		}
+		updates = map[string]interface{}{"down_threshold": d.DownThreshold, "up_threshold": d.UpThreshold}
+	case "ban":
+		if err := parseBanDurationsInput(&d, text); err != nil {
+			SendMessage(ctx, 0, false, "❌ %v", err)
+			return true
+		}
+		updates = map[string]interface{}{"ban_durations": d.BanDurations}
+	case "schedule":
+		if err := parseScheduleInput(&d, text); err != nil {
+			SendMessage(ctx, 0, false, "❌ %v", err)
+			return true
+		}
+		updates = map[string]interface{}{"check_interval": d.CheckInterval, "check_cron": d.CheckCron, "check_jitter": d.CheckJitter}
+	case "resolver":
+		// 输入 - 表示使用检测后端的默认解析器
+		if text == "-" {
+			text = ""
+		}
+		d.Resolver = text
+		updates = map[string]interface{}{"resolver": d.Resolver}
+	case "http":
+		if err := parseHTTPProbeInput(&d, text); err != nil {
+			SendMessage(ctx, 0, false, "❌ %v", err)
+			return true
+		}
+		updates = map[string]interface{}{
+			"http_method":     d.HTTPMethod,
+			"http_host":       d.HTTPHost,
+			"http_path":       d.HTTPPath,
+			"http_status_min": d.HTTPStatusMin,
+			"http_status_max": d.HTTPStatusMax,
+			"http_body_match": d.HTTPBodyMatch,
+			"http_body_regex": d.HTTPBodyRegex,
+		}
+	case "udp":
+		if err := parseUDPProbeInput(&d, text); err != nil {
+			SendMessage(ctx, 0, false, "❌ %v", err)
+			return true
+		}
+		updates = map[string]interface{}{"udp_payload": d.UDPPayload, "udp_expect": d.UDPExpect}
 	default:
 		SendMessage(ctx, 0, false, "❌ 未知字段，编辑失败。")
 		delete(domainEditSessions, ctx.UserID)
@@ -859,9 +1257,8 @@ func handleDomainEditInput(ctx UpdateContext) bool {
 			return true
 		}
 
-		// 使用完整域名查找 DNS 记录并获取 ID
-		ctxBg := context.Background()
-		dnsRecord, err := cfClient.GetDNSRecordByName(ctxBg, text, "")
+		// 使用完整域名查找 DNS 记录并获取 ID（A/CNAME 与 AAAA）
+		recordID, recordIDV6, err := operate.LookupRecordIDs(cfClient, text)
 		if err != nil {
 			SendMessage(ctx, 0, false, "❌ 域名 %s 在 Cloudflare 中不存在对应的 DNS 记录，请先在 Cloudflare 中创建该域名的 DNS 记录: %v", text, err)
 			// 返回详情页（主域名）
@@ -871,13 +1268,16 @@ func handleDomainEditInput(ctx UpdateContext) bool {
 		}
 
 		// 设置新的 RecordId 和 ZoneId
-		d.RecordId = dnsRecord.ID
+		d.RecordId = recordID
+		d.RecordIdV6 = recordIDV6
 		d.ZoneId = zoneID
-		utils.Logger.Infof("✅ 自动获取 DNS ID：%s -> %s (类型: %s, 内容: %s)", text, dnsRecord.ID, dnsRecord.Type, dnsRecord.Content)
+		updates["record_id"] = d.RecordId
+		updates["record_id_v6"] = d.RecordIdV6
+		updates["zone_id"] = d.ZoneId
 		utils.Logger.Infof("✅ 自动获取 Zone ID：%s -> %s", text, zoneID)
 	}
 
-	if err := operate.UpdateDomainRecord(db.DB, d); err != nil {
+	if err := operate.UpdateDomainFields(db.DB, d.ID, updates); err != nil {
 		SendMessage(ctx, 0, false, "❌ 更新主域名失败：%v", err)
 		// 返回详情页（主域名）
 		showDomainDetail(ctx.Bot, session.ChatID, session.MessageID, d.ID)
@@ -885,6 +1285,8 @@ func handleDomainEditInput(ctx UpdateContext) bool {
 		return true
 	}
 
+	scheduler.invalidate()
+
 	if session.Field == "name" && oldDomainName != d.Domain {
 		utils.Logger.Infof("✅ 主域名已更新: %s -> %s", oldDomainName, d.Domain)
 	}
@@ -931,13 +1333,18 @@ func handleForwardEditInput(ctx UpdateContext) bool {
 
 	// 这里不再保存旧值/新值（已简化提示逻辑）
 
+	// 只更新编辑的字段，避免覆盖自动检测同时写入的封禁、延迟与回切状态
+	var updates map[string]interface{}
 	switch session.Field {
 	case "domain":
 		f.ForwardDomain = text
+		updates = map[string]interface{}{"forward_domain": f.ForwardDomain}
 	case "ip":
 		f.IP = text
+		updates = map[string]interface{}{"ip": f.IP}
 	case "isp":
 		f.ISP = text
+		updates = map[string]interface{}{"isp": f.ISP}
 	case "weight":
 		w, err := strconv.Atoi(text)
 		if err != nil {
@@ -945,6 +1352,7 @@ func handleForwardEditInput(ctx UpdateContext) bool {
 			return true
 		}
 		f.Weight = w
+		updates = map[string]interface{}{"weight": f.Weight}
 	case "sort":
 		s, err := strconv.Atoi(text)
 		if err != nil {
@@ -952,15 +1360,17 @@ func handleForwardEditInput(ctx UpdateContext) bool {
 			return true
 		}
 		f.SortOrder = s
+		updates = map[string]interface{}{"sort_order": f.SortOrder}
 	case "type":
 		f.RecordType = text
+		updates = map[string]interface{}{"record_type": f.RecordType}
 	default:
 		SendMessage(ctx, 0, false, "❌ 未知字段，编辑失败。")
 		delete(forwardEditSessions, ctx.UserID)
 		return true
 	}
 
-	if err := operate.UpdateForwardRecord(db.DB, f); err != nil {
+	if err := operate.UpdateForwardFields(db.DB, f.ID, updates); err != nil {
 		SendMessage(ctx, 0, false, "❌ 更新转发记录失败：%v", err)
 		// 返回转发详情页
 		editForwardInfo(ctx.Bot, session.ChatID, session.MessageID, f.ID)
@@ -1008,7 +1418,7 @@ func handleForwardCheckAndResolve(bot *tgbotapi.BotAPI, chatID int64, messageID
 	_, _ = bot.Send(edit)
 
 	// 调用 WebSocket 检测接口（带进度回调）
-	checkResult, err := checkForwardDomainViaWSWithProgress(f.ForwardDomain, d.Port, func(progress string) {
+	checkResult, err := checkForwardDomainViaWSWithProgress(d, f.ForwardDomain, forwardFamily(d, f), func(progress string) {
 		// 动态更新检测进度
 		progressEdit := tgbotapi.NewEditMessageText(chatID, messageID,
 			fmt.Sprintf("🔍 *正在检测转发域名...*\n\n%s", progress))
@@ -1038,14 +1448,14 @@ func handleForwardCheckAndResolve(bot *tgbotapi.BotAPI, chatID int64, messageID
 		return
 	}
 
-	if f.RecordType == "A" {
-		// A 记录：使用 TargetIp
+	if f.RecordType == "A" || f.RecordType == "AAAA" {
+		// A/AAAA 记录：使用 TargetIp
 		targetIP = checkResult.TargetIp
 		resolveMsg = fmt.Sprintf("✅ 域名可访问\n解析 IP: `%s`", targetIP)
 
 		// 更新转发域名的 IP 到数据库
 		f.IP = targetIP
-		if err := db.DB.Save(&f).Error; err != nil {
+		if err := operate.UpdateForwardFields(db.DB, f.ID, map[string]interface{}{"ip": f.IP}); err != nil {
 			utils.Logger.Warnf("更新转发域名 IP 失败: %v", err)
 		} else {
 			utils.Logger.Infof("✅ 已更新转发域名 %s 的 IP 为: %s", f.ForwardDomain, targetIP)
@@ -1059,7 +1469,7 @@ func handleForwardCheckAndResolve(bot *tgbotapi.BotAPI, chatID int64, messageID
 		// 更新转发域名的 IP 到数据库（从检测结果获取）
 		if checkResult.TargetIp != "" {
 			f.IP = checkResult.TargetIp
-			if err := db.DB.Save(&f).Error; err != nil {
+			if err := operate.UpdateForwardFields(db.DB, f.ID, map[string]interface{}{"ip": f.IP}); err != nil {
 				utils.Logger.Warnf("更新转发域名 IP 失败: %v", err)
 			} else {
 				utils.Logger.Infof("✅ 已更新转发域名 %s 的 IP 为: %s", f.ForwardDomain, checkResult.TargetIp)
@@ -1100,14 +1510,19 @@ func handleForwardCheckAndResolve(bot *tgbotapi.BotAPI, chatID int64, messageID
 			_, dnsErr = cfClient.UpdateARecord(ctx, d.RecordId, d.Domain, targetIP, false)
 		} else if f.RecordType == "CNAME" {
 			// 更新 CNAME 记录
-			_, dnsErr = cfClient.UpdateCNAMERecord(ctx, d.RecordId, d.Domain, targetIP, false)
+			_, dnsErr = cfClient.UpdateCNAMERecord(ctx, familyRecordID(d, forwardFamily(d, f)), d.Domain, targetIP, false)
 		}
 
 		if dnsErr != nil {
 			// DNS 更新失败，记录失败状态
 			f.ResolveStatus = "failed"
 			f.LastResolvedAt = time.Now().Unix()
-			_ = db.DB.Save(&f)
+			if err := operate.UpdateForwardFields(db.DB, f.ID, map[string]interface{}{
+				"resolve_status":   f.ResolveStatus,
+				"last_resolved_at": f.LastResolvedAt,
+			}); err != nil {
+				utils.Logger.Warnf("更新解析状态失败: %v", err)
+			}
 
 			edit := tgbotapi.NewEditMessageText(chatID, messageID,
 				fmt.Sprintf("❌ DNS 更新失败：%v", dnsErr))
@@ -1130,7 +1545,10 @@ func handleForwardCheckAndResolve(bot *tgbotapi.BotAPI, chatID int64, messageID
 			utils.Logger.Warnf("清除其他转发域名状态失败: %v", err)
 		}
 
-		if err := db.DB.Save(&f).Error; err != nil {
+		if err := operate.UpdateForwardFields(db.DB, f.ID, map[string]interface{}{
+			"resolve_status":   f.ResolveStatus,
+			"last_resolved_at": f.LastResolvedAt,
+		}); err != nil {
 			utils.Logger.Warnf("更新解析状态失败: %v", err)
 		} else {
 			utils.Logger.Infof("✅ 已记录解析状态: %s", f.ForwardDomain)
@@ -1165,8 +1583,10 @@ func extractRootDomain(domain string) string {
 	return domain
 }
 
-// 通过 HTTP 检测转发域名（带进度回调）
-func checkForwardDomainViaWSWithProgress(target string, port int, progressCallback func(string)) (*CheckBackend.TCPCheckResponse, error) {
+// 通过 HTTP 检测转发域名（带进度回调），探测类型与参数取自主域名，只检测 family 地址族
+func checkForwardDomainViaWSWithProgress(d models.DomainRecord, target string, family string, progressCallback func(string)) (*CheckBackend.TCPCheckResponse, error) {
+	port := d.Port
+
 	// 构建 HTTP 请求 URL
 	// url := fmt.Sprintf("%s/api/v1/tcp_checks", config.Global.BackendURL.Api)
 
@@ -1175,7 +1595,7 @@ func checkForwardDomainViaWSWithProgress(target string, port int, progressCallba
 	}
 
 	// 发送请求并获取结果
-	result, err := checkConnectivityWithProgress(target, port, func(current int, total int) {
+	result, err := checkConnectivityWithProgress(d, target, family, func(current int, total int) {
 		if progressCallback != nil {
 			progressCallback(fmt.Sprintf("🔍 正在检测连通性...\n目标: `%s:%d`\n\n⚡ 第 %d/%d 次尝试连接...", target, port, current, total))
 		}
@@ -1309,12 +1729,14 @@ func showAddForwardForm(bot *tgbotapi.BotAPI, chatID int64, messageID int, domai
 			"`转发域名|IP|ISP|权重|排序|记录类型`\n\n"+
 			"*示例*:\n"+
 			"`cdn1.example.com|1.1.1.1|电信|10|1|A`\n"+
-			"`cdn2.example.com||联通|20|2|CNAME`\n\n"+
+			"`cdn2.example.com||联通|20|2|CNAME`\n"+
+			"`cdn3.example.com||移动|10|3|AAAA`\n\n"+
 			"*说明*:\n"+
 			"- IP 可为空，系统会自动解析\n"+
 			"- ISP 可为空\n"+
 			"- 权重数值越大优先级越高\n"+
-			"- 记录类型可选 A 或 CNAME，默认为 A\n\n"+
+			"- 记录类型可选 A、AAAA 或 CNAME，默认为 A\n"+
+			"- 双栈主域名的 A 与 AAAA 转发各自独立切换，CNAME 仅用于单栈主域名\n\n"+
 			"请输入：")
 	edit.ParseMode = "Markdown"
 	_, _ = bot.Send(edit)
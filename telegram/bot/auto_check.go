@@ -2,7 +2,6 @@ package bot
 
 import (
 	"fmt"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -32,19 +31,64 @@ func getApiFailureThreshold() int {
 // 自动检测任务
 var autoCheckRunning = false
 
-// CheckReport 检测报告结构
+// defaultCheckWorkers 未配置 auto_check.workers 时并发检测的主域名数
+const defaultCheckWorkers = 4
+
+// CheckReport 检测报告结构，主域名并发检测时通过 add 追加内容
 type CheckReport struct {
-	FailedDomains       []string        // 检测失败的主域名
-	DisconnectedDomains []DomainFailure // 无法连通的主域名
-	BannedForwards      []string        // 被封禁的转发域名
-	SwitchedDomains     []DomainSwitch  // DNS 切换成功的主域名
-	NoForwardDomains    []string        // 无可用转发的主域名
+	mu sync.Mutex
+
+	FailedDomains       []string           // 检测失败的主域名
+	DisconnectedDomains []DomainFailure    // 无法连通的主域名
+	BannedForwards      []BannedForward    // 被封禁的转发域名
+	SwitchedDomains     []DomainSwitch     // DNS 切换成功的主域名
+	NoForwardDomains    []string           // 无可用转发的主域名
+	CertWarnings        []CertWarning      // 证书即将到期的主域名
+	PartialFailures     []PartialFailure   // 部分后端不通但未达到法定票数的目标
+	QuorumFailures      []QuorumFailure    // 响应的后端不足法定票数、无法判定连通性的目标
+	PendingDomains      []DomainStreak     // 连续失败/成功次数尚未达到切换/恢复阈值的主域名
+	RecoveredDomains    []DomainStreak     // 判定恢复的主域名
+	FailbackDomains     []DomainFailback   // 自动回切到高优先级转发的主域名
+	MaintenanceDomains  []MaintenanceCheck // 维护窗口内只观察的主域名
+	SkippedDomains      []string           // 手动检测时正在自动检测而跳过的主域名
+	DryRun              bool               // 整份报告为模拟运行（全局 dry_run 或 /manual_check dryrun）
+}
+
+// add 在报告锁内执行追加操作，多个主域名并发检测时共用同一份报告
+func (r *CheckReport) add(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn()
+}
+
+// PartialFailure 部分检测后端判定不通的目标
+type PartialFailure struct {
+	Target         string
+	FailedBackends []string
+	TotalBackends  int
+}
+
+// CertWarning 证书到期预警
+type CertWarning struct {
+	Domain   string
+	Port     int
+	DaysLeft int
+	NotAfter time.Time
 }
 
+// 证书预警去重：同一主域名 24 小时内自动报告只提醒一次
+var (
+	certWarnedAt    = make(map[uint]time.Time)
+	certWarnedMutex sync.Mutex
+)
+
 type DomainFailure struct {
-	Domain string
-	Port   int
-	Reason string
+	Domain   string
+	Port     int
+	Family   string // 双栈主域名不通的地址族记录类型（A / AAAA），单栈时为空
+	Reason   string
+	Backends string // 多后端检测时各后端结果摘要
+	Streak   int    // 连续失败次数
 }
 
 type DomainSwitch struct {
@@ -52,286 +96,319 @@ type DomainSwitch struct {
 	Port          int
 	RecordType    string
 	NewRecord     string
+	OldRecord     string // 切换前 Cloudflare 上的记录内容，未知时为空
 	ForwardDomain string
 	ISP           string
 	Weight        int
+	Latency       float64 // 选中转发的平均连接耗时（毫秒）
+	Simulated     bool    // 模拟运行，未实际修改 DNS
 }
 
-// StartAutoCheck 启动自动检测定时任务
-func StartAutoCheck(bot *tgbotapi.BotAPI, interval time.Duration) {
+// StartAutoCheck 启动自动检测调度器，每个主域名按各自的检测计划到期检测
+func StartAutoCheck(bot *tgbotapi.BotAPI) {
 	if autoCheckRunning {
 		utils.Logger.Info("⚠️ 自动检测任务已在运行中")
 		return
 	}
 	autoCheckRunning = true
-	utils.Logger.Info("🚀 自动检测任务已启动")
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	// 立即执行一次
-	go performAutoCheck(bot)
+	utils.Logger.Infof("🚀 自动检测任务已启动（全局检测间隔 %s）", globalCheckInterval())
 
-	for range ticker.C {
-		go performAutoCheck(bot)
-	}
+	runScheduler(bot)
 }
 
-// performAutoCheck 执行一次完整的自动检测
-func performAutoCheck(bot *tgbotapi.BotAPI) {
-	utils.Logger.Info("📊 开始执行自动检测任务...")
-
-	if db.DB == nil {
-		if err := db.InitDB(); err != nil {
-			utils.Logger.Errorf("❌ 数据库初始化失败: %v", err)
-			return
-		}
+// checkWorkers 返回自动检测并发检测的主域名数
+func checkWorkers() int {
+	if config.Global.AutoCheck.Workers <= 0 {
+		return defaultCheckWorkers
 	}
+	return config.Global.AutoCheck.Workers
+}
 
-	// 创建检测报告
-	report := &CheckReport{
+// newCheckReport 创建空的检测报告
+func newCheckReport(dryRun bool) *CheckReport {
+	return &CheckReport{
 		FailedDomains:       []string{},
 		DisconnectedDomains: []DomainFailure{},
-		BannedForwards:      []string{},
+		BannedForwards:      []BannedForward{},
 		SwitchedDomains:     []DomainSwitch{},
 		NoForwardDomains:    []string{},
+		CertWarnings:        []CertWarning{},
+		PartialFailures:     []PartialFailure{},
+		QuorumFailures:      []QuorumFailure{},
+		PendingDomains:      []DomainStreak{},
+		RecoveredDomains:    []DomainStreak{},
+		FailbackDomains:     []DomainFailback{},
+		MaintenanceDomains:  []MaintenanceCheck{},
+		SkippedDomains:      []string{},
+		DryRun:              dryRun,
 	}
+}
 
-	// 直接从数据库获取所有主域名（已弃用缓存）
-	var domains []models.DomainRecord
-	if err := db.DB.Preload("Forwards", func(tx *gorm.DB) *gorm.DB {
-		return tx.Order("weight desc, sort_order asc, id asc")
-	}).Order("sort_order asc, id asc").Find(&domains).Error; err != nil {
-		utils.Logger.Errorf("❌ 获取主域名列表失败: %v", err)
-		return
-	}
-	utils.Logger.Infof("✅ 从数据库读取到 %d 条主域名记录", len(domains))
-
-	utils.Logger.Infof("📋 共获取到 %d 个主域名", len(domains))
-
-	for _, d := range domains {
-		// 跳过被禁用检测的主域名
-		if d.IsDisableCheck {
+// runCheckWorker 从调度器取出到期的主域名逐个检测，结果写入所属检测周期的报告
+// 检测中的主域名不会被调度器再次选中，同一主域名的检测不会重叠
+func runCheckWorker(jobs <-chan checkJob) {
+	for job := range jobs {
+		var d models.DomainRecord
+		err := db.DB.Preload("Forwards", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("weight desc, sort_order asc, id asc")
+		}).Where("id = ?", job.DomainID).First(&d).Error
+		switch {
+		case err != nil:
+			utils.Logger.Errorf("❌ 获取主域名 %d 失败: %v", job.DomainID, err)
+			// 按检测计划稍后重试
+			scheduler.finish(models.DomainRecord{ID: job.DomainID})
+		case d.IsDisableCheck:
 			utils.Logger.Infof("⏭️ 跳过主域名 %s:%d (检测已禁用)", d.Domain, d.Port)
-			continue
+			scheduler.finish(d)
+		default:
+			utils.Logger.Infof("🔍 检测主域名: %s:%d", d.Domain, d.Port)
+			checkDomain(d, job.Window.report, checkOptions{})
+			scheduler.finish(d)
 		}
+		job.Window.done()
+	}
+}
 
-		utils.Logger.Infof("🔍 检测主域名: %s:%d", d.Domain, d.Port)
-		checkDomain(d, report)
+// checkOptions 单次检测的选项
+type checkOptions struct {
+	Manual      bool                                               // 手动检测：不计入接口失败计数，证书预警不去重
+	Progress    func(current int, total int, forwardDomain string) // 进度回调，可为 nil
+	Maintenance *maintenanceSet                                    // 检测开始时生效的维护窗口，nil 表示没有
+	DryRun      bool                                               // 模拟运行：不修改 DNS、不封禁转发，检测状态只保存在内存
+}
+
+// progress 调用进度回调（未设置时忽略）
+func (o checkOptions) progress(current int, total int, text string) {
+	if o.Progress != nil {
+		o.Progress(current, total, text)
 	}
+}
 
-	// 发送汇总报告
-	sendReport(bot, report)
+// connectProgress 返回单个目标的连接进度回调，未设置进度回调时返回 nil
+func (o checkOptions) connectProgress(target string) func(current int, total int) {
+	if o.Progress == nil {
+		return nil
+	}
+	return func(current int, total int) {
+		o.Progress(current, total, fmt.Sprintf("正在检测第 %d/%d 次连接：%s", current, total, target))
+	}
+}
 
-	utils.Logger.Info("✅ 自动检测任务执行完毕")
+// checkDomain 检测单个主域名及其转发池，双栈主域名的 IPv4 与 IPv6 各自独立检测与切换
+func checkDomain(d models.DomainRecord, report *CheckReport, opts checkOptions) {
+	opts.Maintenance = loadMaintenance(time.Now())
+	opts.DryRun = opts.DryRun || dryRunEnabled(d)
+	if opts.DryRun {
+		loadDryRunState(&d)
+	} else {
+		clearDryRunState(d)
+	}
+	for i, family := range domainFamilies(d) {
+		checkDomainFamily(d, family, i == 0, report, opts)
+	}
 }
 
-// checkDomain 检测单个主域名及其转发池
-func checkDomain(d models.DomainRecord, report *CheckReport) {
+// checkDomainFamily 检测主域名在 family 地址族下的连通性，不通时检测该地址族的转发池
+// certCheck 为 true 时记录证书到期预警（双栈主域名只记录一次）
+func checkDomainFamily(d models.DomainRecord, family string, certCheck bool, report *CheckReport, opts checkOptions) {
+	target := domainTarget(d, family)
+
 	// 1. 检测主域名连通性（带连接进度）
-	utils.Logger.Infof("🔍 检测主域名: %s:%d", d.Domain, d.Port)
-	result, err := checkConnectivityWithProgress(d.Domain, d.Port, nil)
+	utils.Logger.Infof("🔍 检测主域名: %s", target)
+	result, err := checkConnectivityWithProgress(d, d.Domain, family, opts.connectProgress(target))
+	recordCheckResult(d, 0, d.Domain, family, result, err, checkTrigger(opts))
 	if err != nil {
-		utils.Logger.Warnf("⚠️ 主域名 %s:%d 检测失败: %v", d.Domain, d.Port, err)
+		utils.Logger.Warnf("⚠️ 主域名 %s 检测失败: %v", target, err)
 		// 更新 API 失败计数
-		incrementApiFailureCount()
-		// 记录到报告
-		report.FailedDomains = append(report.FailedDomains, fmt.Sprintf("%s:%d", d.Domain, d.Port))
+		if !opts.Manual {
+			incrementApiFailureCount()
+		}
+		// 记录到报告：后端不足法定票数单独列出，不计入主域名检测失败
+		if !recordQuorumFailure(target, err, report) {
+			report.add(func() { report.FailedDomains = append(report.FailedDomains, target) })
+		}
 		// 接口调用失败，不继续检测，直接返回
 		return
 	}
 
 	// 检测成功，重置 API 失败计数
-	resetApiFailureCount()
-
-	// 2. 主域名连通正常
-	if result.Result {
-		utils.Logger.Infof("✅ 主域名 %s:%d 连通正常", d.Domain, d.Port)
-		return
+	if !opts.Manual {
+		resetApiFailureCount()
 	}
 
-	// 3. 主域名不通，记录到报告
-	utils.Logger.Warnf("❌ 主域名 %s:%d 无法连通", d.Domain, d.Port)
-	// 简化消息内容
-	failReason := "无法连接"
-	if result.Message != "" {
-		if strings.Contains(result.Message, "timeout") || strings.Contains(result.Message, "i/o timeout") {
-			failReason = "连接超时"
-		} else if strings.Contains(result.Message, "refused") {
-			failReason = "连接被拒绝"
-		} else if strings.Contains(result.Message, "no route") {
-			failReason = "网络不可达"
-		}
+	// 证书到期预警（自动检测 24 小时内只提醒一次）
+	if certCheck {
+		recordCertWarning(d, result, report, !opts.Manual)
 	}
-	report.DisconnectedDomains = append(report.DisconnectedDomains, DomainFailure{
-		Domain: d.Domain,
-		Port:   d.Port,
-		Reason: failReason,
-	})
 
-	// 4. 检测转发池
-	checkForwardPool(d, report)
-}
-
-// checkDomainWithProgress 检测单个主域名及其转发池（带进度回调）
-func checkDomainWithProgress(d models.DomainRecord, report *CheckReport, progressCallback func(current int, total int, forwardDomain string)) {
-	// 1. 检测主域名连通性（带连接进度）
-	utils.Logger.Infof("🔍 检测主域名: %s:%d", d.Domain, d.Port)
-	result, err := checkConnectivityWithProgress(d.Domain, d.Port, func(current int, total int) {
-		// 调用进度回调显示连接进度
-		progressCallback(current, total, fmt.Sprintf("正在检测第 %d/%d 次连接：%s:%d", current, total, d.Domain, d.Port))
-	})
-	if err != nil {
-		utils.Logger.Warnf("⚠️ 主域名 %s:%d 检测失败: %v", d.Domain, d.Port, err)
-		// 记录到报告
-		report.FailedDomains = append(report.FailedDomains, fmt.Sprintf("%s:%d", d.Domain, d.Port))
-		// 接口调用失败，不继续检测，直接返回
+	// 主域名或当前解析到的转发处于维护窗口内时只观察：不计入连续检测、不切换、不回切
+	if until, forward, ok := opts.Maintenance.familyObserved(d, family); ok {
+		recordMaintenanceCheck(d, family, forward, until, result, report)
 		return
 	}
 
 	// 2. 主域名连通正常
 	if result.Result {
-		utils.Logger.Infof("✅ 主域名 %s:%d 连通正常", d.Domain, d.Port)
+		utils.Logger.Infof("✅ 主域名 %s 连通正常", target)
+		recordDomainSuccess(&d, family, report, opts)
+		recordPartialFailure(d.Domain, d.Port, result, report)
+		// 开启回切时（自动检测且已判定恢复）探测更高优先级的转发
+		if d.FailbackEnabled && !opts.Manual && !familyHealth(d, family).Down {
+			checkFailback(d, family, report, opts)
+		}
 		return
 	}
 
-	// 3. 主域名不通，记录到报告
-	utils.Logger.Warnf("❌ 主域名 %s:%d 无法连通", d.Domain, d.Port)
-	// 简化消息内容
-	failReason := "无法连接"
-	if result.Message != "" {
-		if strings.Contains(result.Message, "timeout") || strings.Contains(result.Message, "i/o timeout") {
-			failReason = "连接超时"
-		} else if strings.Contains(result.Message, "refused") {
-			failReason = "连接被拒绝"
-		} else if strings.Contains(result.Message, "no route") {
-			failReason = "网络不可达"
-		}
+	// 3. 主域名不通，连续失败次数未达到阈值时暂不切换
+	reason := probeFailReason(result.Message)
+	down, streak := recordDomainFailure(&d, family, reason, report, opts)
+	if !down {
+		return
+	}
+	utils.Logger.Warnf("❌ 主域名 %s 无法连通 (连续失败 %d 次)", target, streak)
+	failure := DomainFailure{
+		Domain:   d.Domain,
+		Port:     d.Port,
+		Reason:   reason,
+		Backends: verdictSummary(result.Backends),
+		Streak:   streak,
+	}
+	if d.IPFamily == "dual" {
+		failure.Family = familyRecordType(family)
 	}
-	report.DisconnectedDomains = append(report.DisconnectedDomains, DomainFailure{
-		Domain: d.Domain,
-		Port:   d.Port,
-		Reason: failReason,
-	})
+	report.add(func() { report.DisconnectedDomains = append(report.DisconnectedDomains, failure) })
 
 	// 4. 检测转发池
-	checkForwardPoolWithProgress(d, report, progressCallback)
+	checkForwardPool(d, family, reason, report, opts)
 }
 
-// checkForwardPool 检测转发池并更新到 Cloudflare
-func checkForwardPool(d models.DomainRecord, report *CheckReport) {
-	if len(d.Forwards) == 0 {
-		utils.Logger.Warnf("⚠️ 主域名 %s:%d 无转发记录", d.Domain, d.Port)
-		// 记录到报告
-		report.NoForwardDomains = append(report.NoForwardDomains, fmt.Sprintf("%s:%d", d.Domain, d.Port))
-		return
+// probeFailReason 将后端返回的失败消息简化为报告中的原因
+func probeFailReason(message string) string {
+	switch {
+	case strings.Contains(message, "timeout"):
+		return "连接超时"
+	case strings.Contains(message, "refused"):
+		return "连接被拒绝"
+	case strings.Contains(message, "no route"):
+		return "网络不可达"
+	case strings.Contains(message, "证书"):
+		return "证书校验失败"
+	case strings.Contains(message, "校验失败"):
+		return "应用层校验失败"
+	default:
+		return "无法连接"
 	}
+}
 
-	// 按权重从大到小排序
-	forwards := make([]models.ForwardRecord, len(d.Forwards))
-	copy(forwards, d.Forwards)
-	sort.Slice(forwards, func(i, j int) bool {
-		if forwards[i].Weight != forwards[j].Weight {
-			return forwards[i].Weight > forwards[j].Weight
-		}
-		return forwards[i].SortOrder < forwards[j].SortOrder
-	})
-
-	utils.Logger.Infof("🔄 转发池共 %d 个域名，开始按权重检测", len(forwards))
+// recordCertWarning 主域名为 TLS 探测且证书剩余天数低于阈值时记录预警，dedupe 为 true 时 24 小时内只记录一次
+func recordCertWarning(d models.DomainRecord, result tcpCheckResponseData, report *CheckReport, dedupe bool) {
+	if d.ProbeType != "tls" || result.NotAfter == 0 {
+		return
+	}
 
-	var availableForward *models.ForwardRecord
-	var resolvedIP string // 保存后端接口返回的实际 IP
-	var bannedForwards []string
+	warnDays := config.Global.AutoCheck.CertWarnDays
+	if warnDays <= 0 {
+		warnDays = 14
+	}
+	if result.DaysLeft > warnDays {
+		return
+	}
 
-	// 检测每个转发域名
-	for i, f := range forwards {
-		// 跳过已封禁的转发域名
-		if f.IsBan {
-			utils.Logger.Infof("⏭️ 跳过已封禁的转发域名: %s (权重: %d)", f.ForwardDomain, f.Weight)
-			continue
+	if dedupe {
+		certWarnedMutex.Lock()
+		last, ok := certWarnedAt[d.ID]
+		if ok && time.Since(last) < 24*time.Hour {
+			certWarnedMutex.Unlock()
+			return
 		}
+		certWarnedAt[d.ID] = time.Now()
+		certWarnedMutex.Unlock()
+	}
 
-		utils.Logger.Infof("🔍 [%d/%d] 检测转发域名: %s (权重: %d)", i+1, len(forwards), f.ForwardDomain, f.Weight)
-
-		// 检测连通性（带连接进度）
-		result, err := checkConnectivityWithProgress(f.ForwardDomain, d.Port, nil)
-		if err != nil {
-			utils.Logger.Warnf("⚠️ 转发域名 %s 检测失败: %v", f.ForwardDomain, err)
-			// 更新 API 失败计数
-			incrementApiFailureCount()
-			banForward24Hours(&f)
-			bannedForwards = append(bannedForwards, f.ForwardDomain)
-			continue
-		}
+	utils.Logger.Warnf("🔐 主域名 %s:%d 证书剩余 %d 天", d.Domain, d.Port, result.DaysLeft)
+	warning := CertWarning{
+		Domain:   d.Domain,
+		Port:     d.Port,
+		DaysLeft: result.DaysLeft,
+		NotAfter: time.Unix(result.NotAfter, 0),
+	}
+	report.add(func() { report.CertWarnings = append(report.CertWarnings, warning) })
+}
 
-		// 检测成功，重置 API 失败计数
-		resetApiFailureCount()
+// bannedForwardText 封禁转发域名在报告中的展示文本，多后端检测时附带各后端结果
+func bannedForwardText(forwardDomain string, result tcpCheckResponseData) string {
+	if summary := verdictSummary(result.Backends); summary != "" {
+		return fmt.Sprintf("%s (%s)", forwardDomain, summary)
+	}
+	return forwardDomain
+}
 
-		// 检查检测结果
-		if !result.Result {
-			// 检查是否是因为连接超时导致的失败
-			if strings.Contains(result.Message, "检测结束") && strings.Contains(result.Message, "无法连接") {
-				utils.Logger.Warnf("❌ 转发域名 %s 5次连接测试全部失败，进行24小时封禁", f.ForwardDomain)
-				banForward24Hours(&f)
-				bannedForwards = append(bannedForwards, f.ForwardDomain)
-				continue
-			} else {
-				// 其他原因导致的失败，不封禁
-				utils.Logger.Warnf("⚠️ 转发域名 %s 检测失败，但不是因为5次连接全部失败: %s", f.ForwardDomain, result.Message)
-			}
+// writeDisconnectedDomains 输出主域名连通性故障段落
+func writeDisconnectedDomains(message *strings.Builder, failures []DomainFailure) {
+	message.WriteString("🚨 *主域名连通性故障*\n")
+	for _, d := range failures {
+		if d.Family != "" {
+			message.WriteString(fmt.Sprintf("  • `%s:%d` (%s) - %s，连续失败 `%d` 次\n", d.Domain, d.Port, d.Family, d.Reason, d.Streak))
 		} else {
-			// 找到可用的转发域名
-			utils.Logger.Infof("✅ 转发域名 %s 连通正常 (IP: %s)", f.ForwardDomain, result.TargetIp)
-			availableForward = &f
-			resolvedIP = result.TargetIp // 保存后端解析的实际 IP
-			break
+			message.WriteString(fmt.Sprintf("  • `%s:%d` - %s，连续失败 `%d` 次\n", d.Domain, d.Port, d.Reason, d.Streak))
+		}
+		if d.Backends != "" {
+			message.WriteString(fmt.Sprintf("    线路: %s\n", d.Backends))
 		}
 	}
+	message.WriteString("\n")
+}
 
-	// 通知封禁情况
-	if len(bannedForwards) > 0 {
-		report.BannedForwards = append(report.BannedForwards, bannedForwards...)
+// writePartialFailures 输出部分线路异常段落
+func writePartialFailures(message *strings.Builder, failures []PartialFailure) {
+	message.WriteString("📍 *部分线路异常*\n")
+	for _, p := range failures {
+		message.WriteString(fmt.Sprintf("  • `%s` - %s 不通 (%d/%d)\n",
+			p.Target, strings.Join(p.FailedBackends, ", "), len(p.FailedBackends), p.TotalBackends))
 	}
+	message.WriteString("\n")
+}
 
-	// 如果找到可用的转发域名，更新到 Cloudflare
-	if availableForward != nil {
-		updateToCloudflare(d, *availableForward, resolvedIP, report)
-	} else {
-		utils.Logger.Errorf("❌ 主域名 %s:%d 无可用转发域名", d.Domain, d.Port)
-		// 记录到报告
-		report.NoForwardDomains = append(report.NoForwardDomains, fmt.Sprintf("%s:%d", d.Domain, d.Port))
+// writeCertWarnings 输出证书到期预警段落
+func writeCertWarnings(message *strings.Builder, warnings []CertWarning) {
+	message.WriteString("🔐 *证书即将到期*\n")
+	for _, w := range warnings {
+		if w.DaysLeft < 0 {
+			message.WriteString(fmt.Sprintf("  • `%s:%d` - 已过期 (到期 `%s`)\n", w.Domain, w.Port, w.NotAfter.Format("2006-01-02")))
+			continue
+		}
+		message.WriteString(fmt.Sprintf("  • `%s:%d` - 剩余 `%d` 天 (到期 `%s`)\n", w.Domain, w.Port, w.DaysLeft, w.NotAfter.Format("2006-01-02")))
 	}
+	message.WriteString("\n")
 }
 
-// checkForwardPoolWithProgress 检测转发池并更新到 Cloudflare（带进度回调）
-func checkForwardPoolWithProgress(d models.DomainRecord, report *CheckReport, progressCallback func(current int, total int, forwardDomain string)) {
-	if len(d.Forwards) == 0 {
-		utils.Logger.Warnf("⚠️ 主域名 %s:%d 无转发记录", d.Domain, d.Port)
+// checkForwardPool 检测 family 地址族的转发池，按主域名的选择策略选出转发并更新到 Cloudflare
+// reason 为主域名不通的原因，记录在切换事件中
+func checkForwardPool(d models.DomainRecord, family string, reason string, report *CheckReport, opts checkOptions) {
+	// 只检测能服务该地址族的转发记录，按权重从大到小排序
+	forwards := familyForwards(d, family, opts.DryRun)
+	if len(forwards) == 0 {
+		utils.Logger.Warnf("⚠️ 主域名 %s 无转发记录", domainTarget(d, family))
 		// 记录到报告
-		report.NoForwardDomains = append(report.NoForwardDomains, fmt.Sprintf("%s:%d", d.Domain, d.Port))
+		report.add(func() { report.NoForwardDomains = append(report.NoForwardDomains, domainTarget(d, family)) })
 		return
 	}
 
-	// 按权重从大到小排序
-	forwards := make([]models.ForwardRecord, len(d.Forwards))
-	copy(forwards, d.Forwards)
-	sort.Slice(forwards, func(i, j int) bool {
-		if forwards[i].Weight != forwards[j].Weight {
-			return forwards[i].Weight > forwards[j].Weight
-		}
-		return forwards[i].SortOrder < forwards[j].SortOrder
-	})
+	utils.Logger.Infof("🔄 转发池共 %d 个域名，开始按权重检测 (策略: %s)", len(forwards), selectStrategyLabel(d.SelectStrategy))
 
-	utils.Logger.Infof("🔄 转发池共 %d 个域名，开始按权重检测", len(forwards))
+	var candidates []forwardCandidate
+	var bannedForwards []BannedForward
 
-	var availableForward *models.ForwardRecord
-	var resolvedIP string // 保存后端接口返回的实际 IP
-	var bannedForwards []string
+	// 自动检测时通过批量接口一次取得整个转发池的结果，没有结果的转发再逐个检测
+	var prefetched map[int]tcpCheckResponseData
+	if opts.Progress == nil {
+		prefetched = prefetchForwardPool(d, forwards, family)
+	}
 
 	// 检测每个转发域名
 	for i, f := range forwards {
 		// 调用进度回调
-		progressCallback(i+1, len(forwards), f.ForwardDomain)
+		opts.progress(i+1, len(forwards), f.ForwardDomain)
 
 		// 跳过已封禁的转发域名
 		if f.IsBan {
@@ -340,113 +417,183 @@ func checkForwardPoolWithProgress(d models.DomainRecord, report *CheckReport, pr
 		}
 
 		utils.Logger.Infof("🔍 [%d/%d] 检测转发域名: %s (权重: %d)", i+1, len(forwards), f.ForwardDomain, f.Weight)
+		// 维护中的转发只观察，不封禁也不参与切换
+		inMaintenance := opts.Maintenance.forward(f.ID)
 
 		// 检测连通性（带连接进度）
-		result, err := checkConnectivityWithProgress(f.ForwardDomain, d.Port, func(current int, total int) {
-			// 调用进度回调显示连接进度
-			progressCallback(current, total, fmt.Sprintf("正在检测第 %d/%d 次连接：%s", current, total, f.ForwardDomain))
-		})
+		result, found := prefetched[i]
+		var err error
+		if !found {
+			result, err = checkConnectivityWithProgress(d, f.ForwardDomain, family, opts.connectProgress(f.ForwardDomain))
+		}
+		recordCheckResult(d, f.ID, f.ForwardDomain, family, result, err, checkTrigger(opts))
 		if err != nil {
 			utils.Logger.Warnf("⚠️ 转发域名 %s 检测失败: %v", f.ForwardDomain, err)
-			banForward24Hours(&f)
-			bannedForwards = append(bannedForwards, f.ForwardDomain)
+			// 更新 API 失败计数
+			if !opts.Manual {
+				incrementApiFailureCount()
+			}
+			// 后端不足法定票数不能说明转发不通，不封禁
+			if recordQuorumFailure(f.ForwardDomain, err, report) || inMaintenance {
+				continue
+			}
+			duration := banForward(d, &f, opts.DryRun)
+			bannedForwards = append(bannedForwards, BannedForward{Text: f.ForwardDomain, Duration: duration, Count: f.BanCount, Simulated: opts.DryRun})
 			continue
 		}
 
+		// 检测成功，重置 API 失败计数
+		if !opts.Manual {
+			resetApiFailureCount()
+		}
+
 		// 检查检测结果
 		if !result.Result {
 			// 检查是否是因为连接超时导致的失败
-			if strings.Contains(result.Message, "检测结束") && strings.Contains(result.Message, "无法连接") {
-				utils.Logger.Warnf("❌ 转发域名 %s 5次连接测试全部失败，进行24小时封禁", f.ForwardDomain)
-				banForward24Hours(&f)
-				bannedForwards = append(bannedForwards, f.ForwardDomain)
-				continue
+			if inMaintenance {
+				utils.Logger.Warnf("🛠 转发域名 %s 维护中，无法连通，不封禁", f.ForwardDomain)
+			} else if strings.Contains(result.Message, "检测结束") && strings.Contains(result.Message, "无法连接") {
+				utils.Logger.Warnf("❌ 转发域名 %s 5次连接测试全部失败，进行封禁", f.ForwardDomain)
+				duration := banForward(d, &f, opts.DryRun)
+				bannedForwards = append(bannedForwards, BannedForward{Text: bannedForwardText(f.ForwardDomain, result), Duration: duration, Count: f.BanCount, Simulated: opts.DryRun})
 			} else {
 				// 其他原因导致的失败，不封禁
 				utils.Logger.Warnf("⚠️ 转发域名 %s 检测失败，但不是因为5次连接全部失败: %s", f.ForwardDomain, result.Message)
 			}
-		} else {
-			// 找到可用的转发域名
-			utils.Logger.Infof("✅ 转发域名 %s 连通正常 (IP: %s)", f.ForwardDomain, result.TargetIp)
-			availableForward = &f
-			resolvedIP = result.TargetIp // 保存后端解析的实际 IP
+			continue
+		}
+
+		if inMaintenance {
+			utils.Logger.Infof("🛠 转发域名 %s 维护中，连通正常但不参与切换", f.ForwardDomain)
+			continue
+		}
+
+		// 转发域名连通，清零连续封禁次数并记录延迟（保存后端解析的实际 IP）
+		resetBanCount(&f, opts.DryRun)
+		latency := recordForwardLatency(&f, result, opts.DryRun)
+		utils.Logger.Infof("✅ 转发域名 %s 连通正常 (IP: %s, 延迟: %s)", f.ForwardDomain, result.TargetIp, latencyText(latency))
+		recordPartialFailure(f.ForwardDomain, d.Port, result, report)
+
+		candidate := forwardCandidate{Forward: f, IP: result.TargetIp, IPs: healthyAddresses(result), Latency: latency}
+		candidates = append(candidates, candidate)
+		if poolScanDone(d, candidate) {
 			break
 		}
 	}
 
 	// 通知封禁情况
 	if len(bannedForwards) > 0 {
-		report.BannedForwards = append(report.BannedForwards, bannedForwards...)
+		report.add(func() { report.BannedForwards = append(report.BannedForwards, bannedForwards...) })
 	}
 
 	// 如果找到可用的转发域名，更新到 Cloudflare
-	if availableForward != nil {
-		updateToCloudflare(d, *availableForward, resolvedIP, report)
+	if chosen := selectForward(d, candidates); chosen != nil {
+		utils.Logger.Infof("🎯 主域名 %s 选中转发域名 %s (延迟: %s)", domainTarget(d, family), chosen.Forward.ForwardDomain, latencyText(chosen.Latency))
+		updateToCloudflare(d, family, chosen.Forward, chosen.IPs, reason, report, opts)
 	} else {
-		utils.Logger.Errorf("❌ 主域名 %s:%d 无可用转发域名", d.Domain, d.Port)
+		utils.Logger.Errorf("❌ 主域名 %s 无可用转发域名", domainTarget(d, family))
 		// 记录到报告
-		report.NoForwardDomains = append(report.NoForwardDomains, fmt.Sprintf("%s:%d", d.Domain, d.Port))
+		report.add(func() { report.NoForwardDomains = append(report.NoForwardDomains, domainTarget(d, family)) })
 	}
 }
 
-// banForward24Hours 封禁转发域名24小时
-func banForward24Hours(f *models.ForwardRecord) {
-	f.IsBan = true
-	f.BanTime = time.Now().Add(24 * time.Hour).Unix()
-	f.ResolveStatus = "failed" // 标记为检测失败
+// updateToCloudflare 更新主域名 family 地址族的 DNS 记录到 Cloudflare，resolvedIPs 为检测连通的 IP（最佳 IP 在前）
+// 切换成功时保存切换事件
+func updateToCloudflare(d models.DomainRecord, family string, f models.ForwardRecord, resolvedIPs []string, reason string, report *CheckReport, opts checkOptions) {
+	if sw, ok := publishForward(d, family, f, resolvedIPs, opts.DryRun); ok {
+		recordSwitchEvent(d, family, f, sw, reason, checkTrigger(opts))
+		report.add(func() { report.SwitchedDomains = append(report.SwitchedDomains, sw) })
+	}
+}
 
-	if err := operate.BanForward24Hours(db.DB, f); err != nil {
-		utils.Logger.Errorf("❌ 封禁转发域名失败 %s: %v", f.ForwardDomain, err)
-		return
+// publishForward 将主域名 family 地址族的 DNS 记录切换到转发域名 f，成功时返回切换信息
+func publishForward(d models.DomainRecord, family string, f models.ForwardRecord, resolvedIPs []string, dryRun bool) (DomainSwitch, bool) {
+	recordID := familyRecordID(d, family)
+	if recordID == "" {
+		utils.Logger.Warnf("⚠️ 主域名 %s 没有 %s 记录的 DNS ID，无法更新 Cloudflare", d.Domain, familyRecordType(family))
+		return DomainSwitch{}, false
 	}
 
-	utils.Logger.Infof("🚫 转发域名 %s 已封禁至 %s", f.ForwardDomain, time.Unix(f.BanTime, 0).Format("2006-01-02 15:04:05"))
-}
+	resolvedIP := resolvedIPs[0]
 
-// updateToCloudflare 更新 DNS 记录到 Cloudflare
-func updateToCloudflare(d models.DomainRecord, f models.ForwardRecord, resolvedIP string, report *CheckReport) {
-	if d.RecordId == "" {
-		utils.Logger.Warnf("⚠️ 主域名 %s 没有 DNS ID，无法更新 Cloudflare", d.Domain)
-		return
+	// 根据记录类型确定更新内容（使用后端接口返回的 IP）
+	var contents []string
+	if f.RecordType == "A" || f.RecordType == "AAAA" {
+		// A/AAAA 记录使用后端解析的实际 IP，发布全部健康 IP 时使用所有连通的 IP
+		contents = []string{resolvedIP}
+		if d.PublishMode == "all" {
+			contents = resolvedIPs
+		}
+		utils.Logger.Infof("🔄 %s 记录使用后端解析 IP: %s", f.RecordType, strings.Join(contents, ", "))
+	} else if f.RecordType == "CNAME" {
+		// CNAME 记录使用转发域名作为内容，但仍需要保存解析的 IP
+		contents = []string{f.ForwardDomain}
+		utils.Logger.Infof("🔄 CNAME 记录使用转发域名: %s", f.ForwardDomain)
+	} else {
+		utils.Logger.Warnf("⚠️ 不支持的记录类型: %s", f.RecordType)
+		return DomainSwitch{}, false
+	}
+
+	sw := DomainSwitch{
+		Domain:        d.Domain,
+		Port:          d.Port,
+		RecordType:    f.RecordType,
+		NewRecord:     strings.Join(contents, ", "),
+		ForwardDomain: f.ForwardDomain,
+		ISP:           f.ISP,
+		Weight:        f.Weight,
+		Latency:       f.LastLatency,
+	}
+
+	// 模拟运行：只记录将要发布的内容，不修改 Cloudflare 与解析状态
+	if dryRun {
+		utils.Logger.Infof("🧪 [模拟] 将更新 Cloudflare: %s -> %s (%s: %s)", d.Domain, f.ForwardDomain, f.RecordType, sw.NewRecord)
+		sw.Simulated = true
+		return sw, true
 	}
 
 	// 获取全局 Cloudflare 客户端
 	client, err := cloudflare.GetGlobalClient()
 	if err != nil {
 		utils.Logger.Errorf("❌ 获取 Cloudflare 客户端失败: %v", err)
-		return
+		return DomainSwitch{}, false
 	}
 
-	// 根据记录类型确定更新内容（使用后端接口返回的 IP）
-	var content string
-	if f.RecordType == "A" {
-		// A 记录使用后端解析的实际 IP
-		content = resolvedIP
-		utils.Logger.Infof("🔄 A 记录使用后端解析 IP: %s", resolvedIP)
-	} else if f.RecordType == "CNAME" {
-		// CNAME 记录使用转发域名作为内容，但仍需要保存解析的 IP
-		content = f.ForwardDomain
-		utils.Logger.Infof("🔄 CNAME 记录使用转发域名: %s", f.ForwardDomain)
+	// 更新前读取 Cloudflare 上当前的记录内容，保存到切换事件
+	if old, err := client.RecordContents(d.Domain, d.ZoneId, recordID, d.Domain, familyRecordType(family), "CNAME"); err != nil {
+		utils.Logger.Warnf("⚠️ 读取 %s 当前记录失败: %v", d.Domain, err)
 	} else {
-		utils.Logger.Warnf("⚠️ 不支持的记录类型: %s", f.RecordType)
-		return
+		sw.OldRecord = strings.Join(old, ", ")
 	}
 
-	// 直接使用 DNS ID 更新
-	updateErr := client.UpdateDNSRecordByID(
-		d.Domain,                     // 域名（用于获取 Zone ID）
-		d.ZoneId,                     // Zone ID
-		d.RecordId,                   // DNS 记录 ID
-		f.RecordType,                 // 记录类型
-		d.Domain,                     // 记录名称
-		content,                      // 记录内容
-		config.Global.Cloudflare.TTL, // 使用配置文件中的 TTL
-		false,                        // Proxied
-	)
+	var updateErr error
+	if d.PublishMode == "all" && f.RecordType != "CNAME" {
+		// 同步同名 A/AAAA 记录集合，DNS ID 对应的主记录使用最佳 IP
+		updateErr = client.SyncDNSRecordSet(d.Domain, d.ZoneId, recordID, f.RecordType, d.Domain, contents, config.Global.Cloudflare.TTL, false)
+	} else {
+		if d.PublishMode == "all" {
+			// CNAME 不能与同名地址记录共存，先删除之前发布的附加地址记录
+			updateErr = client.DeleteExtraDNSRecords(d.Domain, d.ZoneId, recordID, familyRecordType(family), d.Domain)
+		}
+
+		// 直接使用 DNS ID 更新
+		if updateErr == nil {
+			updateErr = client.UpdateDNSRecordByID(
+				d.Domain,                     // 域名（用于获取 Zone ID）
+				d.ZoneId,                     // Zone ID
+				recordID,                     // DNS 记录 ID
+				f.RecordType,                 // 记录类型
+				d.Domain,                     // 记录名称
+				contents[0],                  // 记录内容
+				config.Global.Cloudflare.TTL, // 使用配置文件中的 TTL
+				false,                        // Proxied
+			)
+		}
+	}
 
 	if updateErr != nil {
 		utils.Logger.Errorf("❌ 更新 Cloudflare 失败: %v", updateErr)
-		return
+		return DomainSwitch{}, false
 	}
 
 	// 更新成功，记录解析状态和 IP
@@ -455,8 +602,14 @@ func updateToCloudflare(d models.DomainRecord, f models.ForwardRecord, resolvedI
 	// 更新数据库中的 IP（无论是 A 记录还是 CNAME 记录）
 	f.IP = resolvedIP
 
-	// 清除同一主域名下其他转发域名的 success 状态
-	if err := operate.ClearOtherForwardStatus(db.DB, f.DomainRecordID, f.ID); err != nil {
+	// 清除同一主域名下其他转发域名的 success 状态（双栈主域名只清除同一地址族的）
+	var clearErr error
+	if d.IPFamily == "dual" {
+		clearErr = operate.ClearOtherForwardStatusOfType(db.DB, f.DomainRecordID, f.ID, f.RecordType)
+	} else {
+		clearErr = operate.ClearOtherForwardStatus(db.DB, f.DomainRecordID, f.ID)
+	}
+	if err := clearErr; err != nil {
 		utils.Logger.Warnf("⚠️ 清除其他转发域名状态失败: %v", err)
 	}
 
@@ -464,17 +617,8 @@ func updateToCloudflare(d models.DomainRecord, f models.ForwardRecord, resolvedI
 		utils.Logger.Warnf("⚠️ 更新解析状态失败: %v", err)
 	}
 
-	// 记录到报告
 	utils.Logger.Infof("✅ 已更新 Cloudflare: %s -> %s (%s)", d.Domain, f.ForwardDomain, f.RecordType)
-	report.SwitchedDomains = append(report.SwitchedDomains, DomainSwitch{
-		Domain:        d.Domain,
-		Port:          d.Port,
-		RecordType:    f.RecordType,
-		NewRecord:     content,
-		ForwardDomain: f.ForwardDomain,
-		ISP:           f.ISP,
-		Weight:        f.Weight,
-	})
+	return sw, true
 }
 
 // incrementApiFailureCount 增加 API 失败计数
@@ -518,12 +662,16 @@ func sendReport(bot *tgbotapi.BotAPI, report *CheckReport) {
 	if len(report.DisconnectedDomains) > 0 ||
 		len(report.BannedForwards) > 0 ||
 		len(report.SwitchedDomains) > 0 ||
-		len(report.NoForwardDomains) > 0 {
+		len(report.NoForwardDomains) > 0 ||
+		len(report.CertWarnings) > 0 ||
+		len(report.RecoveredDomains) > 0 ||
+		len(report.FailbackDomains) > 0 {
 		shouldSend = true
 	}
+	// 部分线路异常与状态待确认不单独触发报告，避免每轮检测重复推送
 
 	// 检查 API 失败次数是否超过阈值
-	if len(report.FailedDomains) > 0 && shouldSendApiFailureNotification() {
+	if (len(report.FailedDomains) > 0 || len(report.QuorumFailures) > 0) && shouldSendApiFailureNotification() {
 		shouldSend = true
 	}
 
@@ -536,21 +684,28 @@ func sendReport(bot *tgbotapi.BotAPI, report *CheckReport) {
 	var message strings.Builder
 	message.WriteString("📊 *自动检测报告*\n")
 	message.WriteString(fmt.Sprintf("🕒 时间: `%s`\n\n", time.Now().Format("2006-01-02 15:04:05")))
+	writeDryRunNotice(&message, report)
 
 	// 1. DNS 切换成功
 	if len(report.SwitchedDomains) > 0 {
 		message.WriteString("✅ *DNS 自动切换成功*\n")
 		for _, sw := range report.SwitchedDomains {
 			message.WriteString(fmt.Sprintf(
-				"  • `%s:%d`\n"+
+				"  • `%s:%d`%s\n"+
 					"    类型: `%s` | 运营商: `%s`\n"+
-					"    转发: `%s` | 权重: `%d`\n",
-				sw.Domain, sw.Port, sw.RecordType, sw.ISP, sw.ForwardDomain, sw.Weight,
+					"    转发: `%s` | 权重: `%d`\n"+
+					"    延迟: `%s`\n",
+				sw.Domain, sw.Port, simulatedTag(sw.Simulated), sw.RecordType, sw.ISP, sw.ForwardDomain, sw.Weight, latencyText(sw.Latency),
 			))
 		}
 		message.WriteString("\n")
 	}
 
+	// 自动回切到高优先级转发
+	if len(report.FailbackDomains) > 0 {
+		writeFailbackDomains(&message, report.FailbackDomains)
+	}
+
 	// 2. 检测失败的主域名（只有当失败次数超过阈值时才发送）
 	if len(report.FailedDomains) > 0 && shouldSendApiFailureNotification() {
 		message.WriteString("⚠️ *接口调用失败*\n")
@@ -562,20 +717,12 @@ func sendReport(bot *tgbotapi.BotAPI, report *CheckReport) {
 
 	// 3. 无法连通的主域名
 	if len(report.DisconnectedDomains) > 0 {
-		message.WriteString("🚨 *主域名连通性故障*\n")
-		for _, d := range report.DisconnectedDomains {
-			message.WriteString(fmt.Sprintf("  • `%s:%d` - %s\n", d.Domain, d.Port, d.Reason))
-		}
-		message.WriteString("\n")
+		writeDisconnectedDomains(&message, report.DisconnectedDomains)
 	}
 
 	// 4. 封禁的转发域名
 	if len(report.BannedForwards) > 0 {
-		message.WriteString("🚫 *转发域名已封禁 24小时*\n")
-		for _, f := range report.BannedForwards {
-			message.WriteString(fmt.Sprintf("  • `%s`\n", f))
-		}
-		message.WriteString("\n")
+		writeBannedForwards(&message, report.BannedForwards)
 	}
 
 	// 5. 无可用转发的主域名
@@ -587,6 +734,36 @@ func sendReport(bot *tgbotapi.BotAPI, report *CheckReport) {
 		message.WriteString("\n")
 	}
 
+	// 6. 证书即将到期
+	if len(report.CertWarnings) > 0 {
+		writeCertWarnings(&message, report.CertWarnings)
+	}
+
+	// 7. 部分线路异常
+	if len(report.PartialFailures) > 0 {
+		writePartialFailures(&message, report.PartialFailures)
+	}
+
+	// 未达到法定票数（检测后端不可用）
+	if len(report.QuorumFailures) > 0 {
+		writeQuorumFailures(&message, report.QuorumFailures)
+	}
+
+	// 8. 主域名已恢复
+	if len(report.RecoveredDomains) > 0 {
+		writeRecoveredDomains(&message, report.RecoveredDomains)
+	}
+
+	// 9. 状态待确认（未达到切换/恢复阈值）
+	if len(report.PendingDomains) > 0 {
+		writePendingDomains(&message, report.PendingDomains)
+	}
+
+	// 10. 维护中（仅观察，不单独触发报告）
+	if len(report.MaintenanceDomains) > 0 {
+		writeMaintenanceDomains(&message, report.MaintenanceDomains)
+	}
+
 	message.WriteString("──────────\n")
 	message.WriteString("🔍 检测完成")
 
@@ -632,12 +809,17 @@ func notifyAdmins(bot *tgbotapi.BotAPI, message string) {
 	}
 }
 
-// manualCheckHandler 手动检测命令处理器
+// manualCheckHandler 手动检测命令处理器，/manual_check dryrun 为模拟检测
 func manualCheckHandler(ctx UpdateContext) {
 	chatID := ctx.Update.Message.Chat.ID
+	dryRun := commandArgs(ctx) == "dryrun"
 
 	// 发送初始消息
-	initMsg := tgbotapi.NewMessage(chatID, "🔍 *开始手动检测*\n\n正在初始化检测任务…")
+	title := "🔍 *开始手动检测*"
+	if dryRun {
+		title = "🧪 *开始模拟检测*（不修改 DNS、不封禁转发）"
+	}
+	initMsg := tgbotapi.NewMessage(chatID, title+"\n\n正在初始化检测任务…")
 	initMsg.ParseMode = "Markdown"
 	sentMsg, err := ctx.Bot.Send(initMsg)
 	if err != nil {
@@ -647,11 +829,11 @@ func manualCheckHandler(ctx UpdateContext) {
 	messageID := sentMsg.MessageID
 
 	// 异步执行手动检测，避免阻塞主线程
-	go performManualCheck(ctx.Bot, chatID, messageID)
+	go performManualCheck(ctx.Bot, chatID, messageID, dryRun)
 }
 
-// performManualCheck 执行手动检测（带进度显示）
-func performManualCheck(bot *tgbotapi.BotAPI, chatID int64, messageID int) {
+// performManualCheck 执行手动检测（带进度显示），dryRun 为 true 时生成模拟报告
+func performManualCheck(bot *tgbotapi.BotAPI, chatID int64, messageID int, dryRun bool) {
 	utils.Logger.Info("📊 开始执行手动检测任务...")
 
 	if db.DB == nil {
@@ -664,13 +846,7 @@ func performManualCheck(bot *tgbotapi.BotAPI, chatID int64, messageID int) {
 	}
 
 	// 创建检测报告
-	report := &CheckReport{
-		FailedDomains:       []string{},
-		DisconnectedDomains: []DomainFailure{},
-		BannedForwards:      []string{},
-		SwitchedDomains:     []DomainSwitch{},
-		NoForwardDomains:    []string{},
-	}
+	report := newCheckReport(dryRun)
 
 	// 直接从数据库获取所有主域名
 	var domains []models.DomainRecord
@@ -719,8 +895,15 @@ func performManualCheck(bot *tgbotapi.BotAPI, chatID int64, messageID int) {
 				"⏳ 请稍候...",
 			len(activeDomains), i+1, len(activeDomains), d.Domain, d.Port))
 
+		// 正在自动检测的主域名跳过，避免同一主域名的检测重叠（重复切换、重复封禁）
+		if !scheduler.claim(d.ID) {
+			utils.Logger.Infof("⏭️ 主域名 %s:%d 正在自动检测，跳过", d.Domain, d.Port)
+			report.SkippedDomains = append(report.SkippedDomains, fmt.Sprintf("%s:%d", d.Domain, d.Port))
+			continue
+		}
+
 		utils.Logger.Infof("🔍 检测主域名: %s:%d", d.Domain, d.Port)
-		checkDomainWithProgress(d, report, func(current int, total int, forwardDomain string) {
+		checkDomain(d, report, checkOptions{Manual: true, DryRun: dryRun, Progress: func(current int, total int, forwardDomain string) {
 			// 实时更新转发域名检测进度
 			updateProgress(bot, chatID, messageID, fmt.Sprintf(
 				"🔍 *手动检测进行中*\n\n"+
@@ -730,7 +913,8 @@ func performManualCheck(bot *tgbotapi.BotAPI, chatID int64, messageID int) {
 					"🔄 转发检测: `%s`\n"+
 					"⏳ 请稍候...",
 				len(activeDomains), i+1, len(activeDomains), d.Domain, d.Port, forwardDomain))
-		})
+		}})
+		scheduler.release(d.ID)
 	}
 
 	// 检测完成，显示最终报告
@@ -768,7 +952,14 @@ func sendManualCheckReport(bot *tgbotapi.BotAPI, chatID int64, messageID int, re
 		len(report.DisconnectedDomains) == 0 &&
 		len(report.BannedForwards) == 0 &&
 		len(report.SwitchedDomains) == 0 &&
-		len(report.NoForwardDomains) == 0 {
+		len(report.NoForwardDomains) == 0 &&
+		len(report.CertWarnings) == 0 &&
+		len(report.PartialFailures) == 0 &&
+		len(report.QuorumFailures) == 0 &&
+		len(report.PendingDomains) == 0 &&
+		len(report.RecoveredDomains) == 0 &&
+		len(report.MaintenanceDomains) == 0 &&
+		len(report.SkippedDomains) == 0 {
 		edit := tgbotapi.NewEditMessageText(chatID, messageID,
 			"✅ *检测完成*\n\n"+
 				"🎉 所有主域名连通正常，未发现异常！")
@@ -780,16 +971,18 @@ func sendManualCheckReport(bot *tgbotapi.BotAPI, chatID int64, messageID int, re
 	var message strings.Builder
 	message.WriteString("📊 *手动检测报告*\n")
 	message.WriteString(fmt.Sprintf("🕒 时间: `%s`\n\n", time.Now().Format("2006-01-02 15:04:05")))
+	writeDryRunNotice(&message, report)
 
 	// 1. DNS 切换成功
 	if len(report.SwitchedDomains) > 0 {
 		message.WriteString("✅ *DNS 自动切换成功*\n")
 		for _, sw := range report.SwitchedDomains {
 			message.WriteString(fmt.Sprintf(
-				"  • `%s:%d`\n"+
+				"  • `%s:%d`%s\n"+
 					"    类型: `%s` | 运营商: `%s`\n"+
-					"    转发: `%s` | 权重: `%d`\n",
-				sw.Domain, sw.Port, sw.RecordType, sw.ISP, sw.ForwardDomain, sw.Weight,
+					"    转发: `%s` | 权重: `%d`\n"+
+					"    延迟: `%s`\n",
+				sw.Domain, sw.Port, simulatedTag(sw.Simulated), sw.RecordType, sw.ISP, sw.ForwardDomain, sw.Weight, latencyText(sw.Latency),
 			))
 		}
 		message.WriteString("\n")
@@ -806,20 +999,12 @@ func sendManualCheckReport(bot *tgbotapi.BotAPI, chatID int64, messageID int, re
 
 	// 3. 无法连通的主域名
 	if len(report.DisconnectedDomains) > 0 {
-		message.WriteString("🚨 *主域名连通性故障*\n")
-		for _, d := range report.DisconnectedDomains {
-			message.WriteString(fmt.Sprintf("  • `%s:%d` - %s\n", d.Domain, d.Port, d.Reason))
-		}
-		message.WriteString("\n")
+		writeDisconnectedDomains(&message, report.DisconnectedDomains)
 	}
 
 	// 4. 封禁的转发域名
 	if len(report.BannedForwards) > 0 {
-		message.WriteString("🚫 *转发域名已封禁 24小时*\n")
-		for _, f := range report.BannedForwards {
-			message.WriteString(fmt.Sprintf("  • `%s`\n", f))
-		}
-		message.WriteString("\n")
+		writeBannedForwards(&message, report.BannedForwards)
 	}
 
 	// 5. 无可用转发的主域名
@@ -831,6 +1016,45 @@ func sendManualCheckReport(bot *tgbotapi.BotAPI, chatID int64, messageID int, re
 		message.WriteString("\n")
 	}
 
+	// 6. 证书即将到期
+	if len(report.CertWarnings) > 0 {
+		writeCertWarnings(&message, report.CertWarnings)
+	}
+
+	// 7. 部分线路异常
+	if len(report.PartialFailures) > 0 {
+		writePartialFailures(&message, report.PartialFailures)
+	}
+
+	// 未达到法定票数（检测后端不可用）
+	if len(report.QuorumFailures) > 0 {
+		writeQuorumFailures(&message, report.QuorumFailures)
+	}
+
+	// 8. 主域名已恢复
+	if len(report.RecoveredDomains) > 0 {
+		writeRecoveredDomains(&message, report.RecoveredDomains)
+	}
+
+	// 9. 状态待确认（未达到切换/恢复阈值）
+	if len(report.PendingDomains) > 0 {
+		writePendingDomains(&message, report.PendingDomains)
+	}
+
+	// 10. 维护中（仅观察，不单独触发报告）
+	if len(report.MaintenanceDomains) > 0 {
+		writeMaintenanceDomains(&message, report.MaintenanceDomains)
+	}
+
+	// 11. 正在自动检测而跳过
+	if len(report.SkippedDomains) > 0 {
+		message.WriteString("⏭️ *正在自动检测，已跳过*\n")
+		for _, d := range report.SkippedDomains {
+			message.WriteString(fmt.Sprintf("  • `%s`\n", d))
+		}
+		message.WriteString("\n")
+	}
+
 	message.WriteString("──────────\n")
 	message.WriteString("🔍 检测完成")
 
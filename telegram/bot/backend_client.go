@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+var (
+	backendTransportOnce sync.Once
+	backendTransport     *http.Transport
+	backendTransportErr  error
+)
+
+// backendTLSClientConfig 按 backend_url 的证书配置构造访问检测后端的 TLS 配置，未配置证书时返回 nil：
+// ca_file 固定信任的 CA，cert_file/key_file 为后端开启 mTLS 时出示的客户端证书
+func backendTLSClientConfig() (*tls.Config, error) {
+	bc := config.Global.BackendURL
+	if bc.CAFile == "" && bc.CertFile == "" && bc.KeyFile == "" && bc.ServerName == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: bc.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if bc.CAFile != "" {
+		pem, err := os.ReadFile(bc.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取后端 CA 失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("后端 CA 文件中没有有效证书: %s", bc.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if bc.CertFile != "" || bc.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(bc.CertFile, bc.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// buildBackendTransport 构造访问检测后端的传输层
+func buildBackendTransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig, err := backendTLSClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
+// backendHTTPClient 返回访问检测后端的 HTTP 客户端，传输层在首次调用时构造并复用
+func backendHTTPClient(timeout time.Duration) (*http.Client, error) {
+	backendTransportOnce.Do(func() {
+		backendTransport, backendTransportErr = buildBackendTransport()
+	})
+	if backendTransportErr != nil {
+		return nil, backendTransportErr
+	}
+	return &http.Client{Timeout: timeout, Transport: backendTransport}, nil
+}
+
+// backendResponse 检测后端的响应，HTTP 与 WebSocket 通道共用
+type backendResponse struct {
+	StatusCode int
+	Body       io.ReadCloser
+}
+
+// doBackend 调用检测后端接口（path 如 /api/v1/tcp_checks）：反向连接后端经其注册的连接提交；
+// 开启 websocket 且通道已连接时经通道提交，否则使用签名 HTTP 请求
+func doBackend(endpoint config.BackendEndpoint, method string, path string, body []byte, timeout time.Duration) (*backendResponse, error) {
+	// 反向连接后端只能通过其注册的连接访问
+	if endpoint.Reverse {
+		resp, err := backendSession(endpoint).Do(method, path, body, timeout)
+		if errors.Is(err, errWSUnavailable) {
+			return nil, fmt.Errorf("反向连接后端 %s 未注册", endpoint.Name)
+		}
+		return resp, err
+	}
+	if config.Global.BackendURL.WebSocket {
+		resp, err := backendSession(endpoint).Do(method, path, body, timeout)
+		if err == nil {
+			return resp, nil
+		}
+		if !errors.Is(err, errWSUnavailable) {
+			utils.Logger.Warnf("⚠️ 检测后端 %s 的 WebSocket 任务失败，改用 HTTP: %v", endpoint.Name, err)
+		}
+	}
+
+	client, err := backendHTTPClient(timeout)
+	if err != nil {
+		return nil, err
+	}
+	req, err := newBackendRequest(method, strings.TrimRight(endpoint.Api, "/")+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return &backendResponse{StatusCode: resp.StatusCode, Body: resp.Body}, nil
+}
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"telegram-auto-switch-dns-bot/CheckBackend"
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// defaultInfoPoll 默认轮询后端状态的间隔
+const defaultInfoPoll = 60 * time.Second
+
+// backendStatus 最近一次轮询到的检测后端状态
+type backendStatus struct {
+	Endpoint  config.BackendEndpoint
+	Info      *CheckBackend.BackendInfoResponse // 轮询失败时为 nil
+	Err       error
+	Latency   time.Duration // 接口响应耗时
+	CheckedAt time.Time
+}
+
+var (
+	backendStatusMu sync.RWMutex
+	backendStatuses = make(map[string]backendStatus) // key 为后端地址
+)
+
+// fetchBackendInfo 调用单个检测后端的 /api/v1/info 接口
+func fetchBackendInfo(endpoint config.BackendEndpoint) (CheckBackend.BackendInfoResponse, time.Duration, error) {
+	start := time.Now()
+	resp, err := doBackend(endpoint, http.MethodGet, "/api/v1/info", nil, 10*time.Second)
+	if err != nil {
+		return CheckBackend.BackendInfoResponse{}, 0, fmt.Errorf("调用后端接口失败: %w", err)
+	}
+	defer resp.Body.Close()
+	latency := time.Since(start)
+
+	var apiResp CheckBackend.APIResponse[CheckBackend.BackendInfoResponse]
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return CheckBackend.BackendInfoResponse{}, latency, fmt.Errorf("解析后端响应失败 (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if apiResp.Code != 0 {
+		return CheckBackend.BackendInfoResponse{}, latency, fmt.Errorf("后端返回错误: %s", apiResp.Message)
+	}
+	return apiResp.Data, latency, nil
+}
+
+// refreshBackendStatuses 并发轮询所有检测后端并更新状态，后端上线/离线时记录日志
+func refreshBackendStatuses() []backendStatus {
+	endpoints := probeBackendList()
+	statuses := make([]backendStatus, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint config.BackendEndpoint) {
+			defer wg.Done()
+			info, latency, err := fetchBackendInfo(endpoint)
+			status := backendStatus{Endpoint: endpoint, Err: err, Latency: latency, CheckedAt: time.Now()}
+			if err == nil {
+				status.Info = &info
+			}
+			statuses[i] = status
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	backendStatusMu.Lock()
+	defer backendStatusMu.Unlock()
+	for _, status := range statuses {
+		prev, seen := backendStatuses[endpointID(status.Endpoint)]
+		switch {
+		case status.Err != nil && (!seen || prev.Err == nil):
+			utils.Logger.Warnf("⚠️ 检测后端 %s 不可用: %v", status.Endpoint.Name, status.Err)
+		case status.Err == nil && (!seen || prev.Err != nil):
+			utils.Logger.Infof("✅ 检测后端 %s 在线 (版本 %s, 出口 IP %s)", status.Endpoint.Name, status.Info.Version, status.Info.PublicIP)
+		}
+		backendStatuses[endpointID(status.Endpoint)] = status
+	}
+	return statuses
+}
+
+// StartBackendInfoPoll 定时轮询检测后端状态
+func StartBackendInfoPoll() {
+	interval := time.Duration(config.Global.BackendURL.InfoPoll) * time.Second
+	if interval <= 0 {
+		interval = defaultInfoPoll
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	refreshBackendStatuses()
+	for range ticker.C {
+		refreshBackendStatuses()
+	}
+}
+
+// formatUptime 将运行秒数格式化为 天/小时/分钟
+func formatUptime(seconds int64) string {
+	d := seconds / 86400
+	h := seconds % 86400 / 3600
+	m := seconds % 3600 / 60
+	if d > 0 {
+		return fmt.Sprintf("%d天%d小时%d分钟", d, h, m)
+	}
+	if h > 0 {
+		return fmt.Sprintf("%d小时%d分钟", h, m)
+	}
+	return fmt.Sprintf("%d分钟", m)
+}
+
+// formatBackendStatuses 生成 /backends 命令展示的后端状态文本
+func formatBackendStatuses(statuses []backendStatus) string {
+	var sb strings.Builder
+	sb.WriteString("🛰 *检测后端状态*\n")
+	for _, s := range statuses {
+		address := s.Endpoint.Api
+		if s.Endpoint.Reverse {
+			address = "反向连接"
+		}
+		sb.WriteString(fmt.Sprintf("\n*%s* `%s`\n", escapeMarkdown(s.Endpoint.Name), address))
+		if s.Err != nil {
+			sb.WriteString(fmt.Sprintf("🔴 不可用: %s\n", escapeMarkdown(s.Err.Error())))
+			continue
+		}
+		info := s.Info
+		transport := "HTTP"
+		if info.MutualTLS {
+			transport = "HTTPS + mTLS"
+		} else if info.TLS {
+			transport = "HTTPS"
+		}
+		inflight := fmt.Sprintf("%d", info.Inflight)
+		if info.MaxInflight > 0 {
+			inflight = fmt.Sprintf("%d/%d", info.Inflight, info.MaxInflight)
+		}
+		sb.WriteString(fmt.Sprintf("🟢 在线 | 响应 %dms | %s\n", s.Latency.Milliseconds(), transport))
+		sb.WriteString(fmt.Sprintf("版本: %s | 已运行: %s\n", escapeMarkdown(info.Version), formatUptime(info.Uptime)))
+		sb.WriteString(fmt.Sprintf("出口 IP: `%s`\n", info.PublicIP))
+		sb.WriteString(fmt.Sprintf("探测类型: %s\n", strings.Join(info.Probes, ", ")))
+		if len(info.Resolvers) > 0 {
+			sb.WriteString(fmt.Sprintf("解析器: %s\n", escapeMarkdown(strings.Join(info.Resolvers, ", "))))
+		}
+		sb.WriteString(fmt.Sprintf("探测并发: %s | 批量上限: %d 个目标 / %d 并发\n", inflight, info.MaxBatchTargets, info.MaxBatchWorkers))
+	}
+	return sb.String()
+}
+
+// backendsHandler /backends 命令：立即轮询所有检测后端并展示状态
+func backendsHandler(ctx UpdateContext) {
+	statuses := refreshBackendStatuses()
+	SendMessage(ctx, ParseModeMarkdown, true, "%s", formatBackendStatuses(statuses))
+}
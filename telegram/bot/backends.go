@@ -0,0 +1,223 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/db/models"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// BackendVerdict 单个检测后端对目标的检测结果
+type BackendVerdict struct {
+	Name     string // 后端名称
+	Result   bool   // 是否连通
+	TargetIp string // 后端解析到的 IP
+	Message  string // 后端返回的消息
+	Err      error  // 接口调用失败时的错误（不参与投票）
+}
+
+// probeBackendList 返回配置的检测后端列表；未配置多后端时使用 backend_url.api
+func probeBackendList() []config.BackendEndpoint {
+	if len(config.Global.BackendURL.Backends) > 0 {
+		return config.Global.BackendURL.Backends
+	}
+	return []config.BackendEndpoint{{Name: "默认", Api: config.Global.BackendURL.Api}}
+}
+
+// endpointID 返回检测后端的唯一标识：普通后端为地址，反向连接后端为名称
+func endpointID(endpoint config.BackendEndpoint) string {
+	if endpoint.Reverse {
+		return "reverse:" + endpoint.Name
+	}
+	return endpoint.Api
+}
+
+// probeQuorum 返回判定目标不通所需的票数，不超过配置的后端数量
+func probeQuorum(total int) int {
+	quorum := config.Global.BackendURL.Quorum
+	if quorum <= 0 {
+		quorum = total/2 + 1
+	}
+	if quorum > total {
+		quorum = total
+	}
+	return quorum
+}
+
+// probeAllBackends 并发向所有检测后端发起检测，不通票数达到法定票数才判定目标不通
+// 进度回调只转发第一个后端的进度，避免多个后端交替刷新进度
+func probeAllBackends(d models.DomainRecord, target string, family string, progressCallback func(current int, total int)) (tcpCheckResponseData, error) {
+	backends := probeBackendList()
+	results := make([]tcpCheckResponseData, len(backends))
+	verdicts := make([]BackendVerdict, len(backends))
+
+	var wg sync.WaitGroup
+	for i, endpoint := range backends {
+		wg.Add(1)
+		go func(i int, endpoint config.BackendEndpoint) {
+			defer wg.Done()
+			var callback func(current int, total int)
+			if i == 0 {
+				callback = progressCallback
+			}
+			result, err := probeBackend(endpoint, d, target, family, callback)
+			results[i] = result
+			verdicts[i] = BackendVerdict{
+				Name:     endpoint.Name,
+				Result:   result.Result,
+				TargetIp: result.TargetIp,
+				Message:  result.Message,
+				Err:      err,
+			}
+			if err != nil {
+				utils.Logger.Warnf("⚠️ 检测后端 %s 调用失败: %v", endpoint.Name, err)
+			}
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	return mergeBackendVerdicts(target, results, verdicts)
+}
+
+// quorumError 多后端检测时响应的后端不足法定票数：检测后端不可用，不能据此判定目标不通
+type quorumError struct {
+	Responded int   // 响应的后端数
+	Quorum    int   // 法定票数
+	Total     int   // 后端总数
+	Err       error // 最后一个后端的调用错误
+}
+
+func (e *quorumError) Error() string {
+	return fmt.Sprintf("未达到法定票数（检测后端不可用）: %d/%d 个后端响应，需要 %d 个: %v", e.Responded, e.Total, e.Quorum, e.Err)
+}
+
+func (e *quorumError) Unwrap() error {
+	return e.Err
+}
+
+// mergeBackendVerdicts 汇总各后端对同一目标的结果：接口调用失败的后端不参与投票，
+// 响应的后端不足法定票数时返回 quorumError，避免少数后端的结果触发封禁与切换；
+// 不通票数达到法定票数时返回第一个判定不通的结果，否则返回第一个判定连通的结果
+func mergeBackendVerdicts(target string, results []tcpCheckResponseData, verdicts []BackendVerdict) (tcpCheckResponseData, error) {
+	responded, downVotes := 0, 0
+	firstUp, firstDown := -1, -1
+	var lastErr error
+	for i, v := range verdicts {
+		if v.Err != nil {
+			lastErr = v.Err
+			continue
+		}
+		responded++
+		if v.Result {
+			if firstUp < 0 {
+				firstUp = i
+			}
+			continue
+		}
+		downVotes++
+		if firstDown < 0 {
+			firstDown = i
+		}
+	}
+
+	quorum := probeQuorum(len(verdicts))
+	if responded == 0 || responded < quorum {
+		if len(verdicts) == 1 {
+			return tcpCheckResponseData{}, lastErr
+		}
+		utils.Logger.Warnf("⚠️ 目标 %s 仅 %d 个后端响应，不足法定票数 %d，无法判定连通性", target, responded, quorum)
+		return tcpCheckResponseData{}, &quorumError{Responded: responded, Quorum: quorum, Total: len(verdicts), Err: lastErr}
+	}
+
+	var merged tcpCheckResponseData
+	if downVotes >= quorum {
+		merged = results[firstDown]
+	} else {
+		merged = results[firstUp]
+	}
+	merged.Backends = verdicts
+
+	if len(verdicts) > 1 {
+		utils.Logger.Infof("🗳 目标 %s 多后端检测: %d/%d 后端判定不通（法定票数 %d），结果: %v",
+			target, downVotes, responded, quorum, merged.Result)
+	}
+	return merged, nil
+}
+
+// failedBackendNames 返回判定不通的后端名称
+func failedBackendNames(verdicts []BackendVerdict) []string {
+	var names []string
+	for _, v := range verdicts {
+		if v.Err == nil && !v.Result {
+			names = append(names, v.Name)
+		}
+	}
+	return names
+}
+
+// verdictSummary 生成各后端结果摘要，如 "电信❌ 联通✅ 移动⚠️"；单后端时返回空字符串
+func verdictSummary(verdicts []BackendVerdict) string {
+	if len(verdicts) <= 1 {
+		return ""
+	}
+	parts := make([]string, 0, len(verdicts))
+	for _, v := range verdicts {
+		mark := "✅"
+		if v.Err != nil {
+			mark = "⚠️"
+		} else if !v.Result {
+			mark = "❌"
+		}
+		parts = append(parts, v.Name+mark)
+	}
+	return strings.Join(parts, " ")
+}
+
+// QuorumFailure 响应的检测后端不足法定票数、无法判定连通性的目标
+type QuorumFailure struct {
+	Target    string
+	Responded int
+	Quorum    int
+	Total     int
+}
+
+// recordQuorumFailure err 为未达到法定票数时记录到报告并返回 true，该目标不按不通处理
+func recordQuorumFailure(target string, err error, report *CheckReport) bool {
+	var qe *quorumError
+	if !errors.As(err, &qe) {
+		return false
+	}
+	failure := QuorumFailure{Target: target, Responded: qe.Responded, Quorum: qe.Quorum, Total: qe.Total}
+	report.add(func() { report.QuorumFailures = append(report.QuorumFailures, failure) })
+	return true
+}
+
+// writeQuorumFailures 输出未达到法定票数段落
+func writeQuorumFailures(message *strings.Builder, failures []QuorumFailure) {
+	message.WriteString("🗳 *未达到法定票数（检测后端不可用）*\n")
+	for _, q := range failures {
+		message.WriteString(fmt.Sprintf("  • `%s` - 仅 %d/%d 个后端响应，需要 %d 个\n", q.Target, q.Responded, q.Total, q.Quorum))
+	}
+	message.WriteString("\n")
+}
+
+// recordPartialFailure 目标整体判定连通，但部分后端不通时记录到报告（如仅某运营商线路被阻断）
+func recordPartialFailure(target string, port int, result tcpCheckResponseData, report *CheckReport) {
+	if !result.Result {
+		return
+	}
+	failed := failedBackendNames(result.Backends)
+	if len(failed) == 0 {
+		return
+	}
+	partial := PartialFailure{
+		Target:         fmt.Sprintf("%s:%d", target, port),
+		FailedBackends: failed,
+		TotalBackends:  len(result.Backends),
+	}
+	report.add(func() { report.PartialFailures = append(report.PartialFailures, partial) })
+}
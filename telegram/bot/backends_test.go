@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"telegram-auto-switch-dns-bot/config"
+)
+
+func TestProbeQuorum(t *testing.T) {
+	old := config.Global.BackendURL.Quorum
+	t.Cleanup(func() { config.Global.BackendURL.Quorum = old })
+
+	tests := []struct {
+		configured int
+		total      int
+		want       int
+	}{
+		{configured: 0, total: 1, want: 1},
+		{configured: 0, total: 2, want: 2},
+		{configured: 0, total: 3, want: 2},
+		{configured: 0, total: 4, want: 3},
+		{configured: 1, total: 3, want: 1},
+		{configured: 3, total: 3, want: 3},
+		{configured: 5, total: 3, want: 3},
+	}
+	for _, tt := range tests {
+		config.Global.BackendURL.Quorum = tt.configured
+		if got := probeQuorum(tt.total); got != tt.want {
+			t.Errorf("probeQuorum(%d) with quorum %d = %d, want %d", tt.total, tt.configured, got, tt.want)
+		}
+	}
+}
+
+func TestMergeBackendVerdicts(t *testing.T) {
+	old := config.Global.BackendURL.Quorum
+	t.Cleanup(func() { config.Global.BackendURL.Quorum = old })
+
+	errAPI := errors.New("调用后端接口失败")
+	up := func(name string) BackendVerdict {
+		return BackendVerdict{Name: name, Result: true, TargetIp: name + "-ip"}
+	}
+	down := func(name string) BackendVerdict { return BackendVerdict{Name: name, TargetIp: name + "-ip"} }
+	failed := func(name string) BackendVerdict { return BackendVerdict{Name: name, Err: errAPI} }
+
+	tests := []struct {
+		name      string
+		quorum    int
+		verdicts  []BackendVerdict
+		wantErr   bool
+		quorumErr bool // 错误为未达到法定票数（多后端时）
+		wantUp    bool
+		wantIP    string // 返回结果取自的后端
+	}{
+		{name: "单后端连通", verdicts: []BackendVerdict{up("a")}, wantUp: true, wantIP: "a-ip"},
+		{name: "单后端不通", verdicts: []BackendVerdict{down("a")}, wantUp: false, wantIP: "a-ip"},
+		{name: "单后端调用失败", verdicts: []BackendVerdict{failed("a")}, wantErr: true},
+		{name: "少数不通判定连通", verdicts: []BackendVerdict{down("a"), up("b"), up("c")}, wantUp: true, wantIP: "b-ip"},
+		{name: "多数不通判定不通", verdicts: []BackendVerdict{up("a"), down("b"), down("c")}, wantUp: false, wantIP: "b-ip"},
+		{name: "调用失败的后端不参与投票", verdicts: []BackendVerdict{failed("a"), down("b"), down("c")}, wantUp: false, wantIP: "b-ip"},
+		{name: "两个后端不可用未达到法定票数", verdicts: []BackendVerdict{failed("a"), failed("b"), down("c")}, wantErr: true, quorumErr: true},
+		{name: "两个后端不可用时少数连通也不判定", verdicts: []BackendVerdict{up("a"), failed("b"), failed("c")}, wantErr: true, quorumErr: true},
+		{name: "全部后端不可用", verdicts: []BackendVerdict{failed("a"), failed("b")}, wantErr: true, quorumErr: true},
+		{name: "配置票数高于响应数", quorum: 3, verdicts: []BackendVerdict{failed("a"), down("b"), down("c")}, wantErr: true, quorumErr: true},
+		{name: "配置票数未达到判定连通", quorum: 3, verdicts: []BackendVerdict{down("a"), down("b"), up("c")}, wantUp: true, wantIP: "c-ip"},
+		{name: "配置 1 票即判定不通", quorum: 1, verdicts: []BackendVerdict{up("a"), down("b"), up("c")}, wantUp: false, wantIP: "b-ip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Global.BackendURL.Quorum = tt.quorum
+			results := make([]tcpCheckResponseData, len(tt.verdicts))
+			for i, v := range tt.verdicts {
+				results[i] = tcpCheckResponseData{Result: v.Result, TargetIp: v.TargetIp}
+			}
+
+			got, err := mergeBackendVerdicts("example.com", results, tt.verdicts)
+			if tt.wantErr {
+				if !errors.Is(err, errAPI) {
+					t.Fatalf("mergeBackendVerdicts() error = %v, want %v", err, errAPI)
+				}
+				var qe *quorumError
+				if errors.As(err, &qe) != tt.quorumErr {
+					t.Fatalf("mergeBackendVerdicts() error = %v, quorum error %v, want %v", err, !tt.quorumErr, tt.quorumErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mergeBackendVerdicts() error = %v", err)
+			}
+			if got.Result != tt.wantUp || got.TargetIp != tt.wantIP {
+				t.Errorf("mergeBackendVerdicts() = (%v, %s), want (%v, %s)", got.Result, got.TargetIp, tt.wantUp, tt.wantIP)
+			}
+			if len(got.Backends) != len(tt.verdicts) {
+				t.Errorf("Backends = %d verdicts, want %d", len(got.Backends), len(tt.verdicts))
+			}
+		})
+	}
+}
+
+func TestVerdictSummary(t *testing.T) {
+	errAPI := errors.New("调用后端接口失败")
+	verdicts := []BackendVerdict{
+		{Name: "电信", Result: false},
+		{Name: "联通", Result: true},
+		{Name: "移动", Err: errAPI},
+	}
+
+	if got, want := verdictSummary(verdicts), "电信❌ 联通✅ 移动⚠️"; got != want {
+		t.Errorf("verdictSummary() = %q, want %q", got, want)
+	}
+	if got := verdictSummary(verdicts[:1]); got != "" {
+		t.Errorf("verdictSummary() with one backend = %q, want empty", got)
+	}
+	if got, want := failedBackendNames(verdicts), []string{"电信"}; !slices.Equal(got, want) {
+		t.Errorf("failedBackendNames() = %v, want %v", got, want)
+	}
+}
+
+func TestRecordQuorumFailure(t *testing.T) {
+	old := config.Global.BackendURL.Quorum
+	config.Global.BackendURL.Quorum = 0
+	t.Cleanup(func() { config.Global.BackendURL.Quorum = old })
+
+	// 三个后端中两个不可用：目标单独列为未达到法定票数，不计入检测失败
+	errAPI := errors.New("调用后端接口失败")
+	verdicts := []BackendVerdict{{Name: "电信", Err: errAPI}, {Name: "联通", Err: errAPI}, {Name: "移动"}}
+	_, err := mergeBackendVerdicts("a.example.com", make([]tcpCheckResponseData, len(verdicts)), verdicts)
+
+	report := newCheckReport(false)
+	if !recordQuorumFailure("a.example.com", err, report) {
+		t.Fatalf("recordQuorumFailure(%v) = false, want true", err)
+	}
+	want := QuorumFailure{Target: "a.example.com", Responded: 1, Quorum: 2, Total: 3}
+	if len(report.QuorumFailures) != 1 || report.QuorumFailures[0] != want {
+		t.Errorf("QuorumFailures = %+v, want [%+v]", report.QuorumFailures, want)
+	}
+
+	if recordQuorumFailure("b.example.com", errAPI, report) {
+		t.Error("recordQuorumFailure() = true for a plain API error")
+	}
+	if len(report.QuorumFailures) != 1 {
+		t.Errorf("QuorumFailures = %+v after a plain API error", report.QuorumFailures)
+	}
+}
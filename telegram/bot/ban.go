@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/db"
+	"telegram-auto-switch-dns-bot/db/models"
+	"telegram-auto-switch-dns-bot/db/operate"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// defaultBanDuration 未配置 ban_durations 时的封禁时长
+const defaultBanDuration = 24 * time.Hour
+
+// BannedForward 报告中被自动封禁的转发域名
+type BannedForward struct {
+	Text      string        // 转发域名（多后端检测时附带各后端结果）
+	Duration  time.Duration // 本次封禁时长
+	Count     int           // 连续封禁次数
+	Simulated bool          // 模拟运行，未实际封禁
+}
+
+// parseBanDurations 解析封禁时长列表，如 ["10m", "1h"]
+func parseBanDurations(items []string) ([]time.Duration, error) {
+	var durations []time.Duration
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		dur, err := time.ParseDuration(item)
+		if err != nil || dur <= 0 {
+			return nil, fmt.Errorf("无效的封禁时长: %s", item)
+		}
+		durations = append(durations, dur)
+	}
+	return durations, nil
+}
+
+// banDurations 返回主域名的封禁时长阶梯：主域名设置优先，其次全局 ban_durations，默认 24 小时
+func banDurations(d models.DomainRecord) []time.Duration {
+	if d.BanDurations != "" {
+		if durations, err := parseBanDurations(strings.Split(d.BanDurations, ",")); err == nil && len(durations) > 0 {
+			return durations
+		}
+		utils.Logger.Warnf("⚠️ 主域名 %s 的封禁时长配置无效: %s，使用全局配置", d.Domain, d.BanDurations)
+	}
+	durations, err := parseBanDurations(config.Global.AutoCheck.BanDurations)
+	if err != nil {
+		utils.Logger.Warnf("⚠️ 全局 ban_durations 配置无效: %v，使用默认 24 小时", err)
+	}
+	if err != nil || len(durations) == 0 {
+		return []time.Duration{defaultBanDuration}
+	}
+	return durations
+}
+
+// nextBanDuration 按转发域名的连续封禁次数取下一次封禁时长，超出阶梯时使用最后一项
+func nextBanDuration(d models.DomainRecord, f models.ForwardRecord) time.Duration {
+	durations := banDurations(d)
+	i := f.BanCount
+	if i >= len(durations) {
+		i = len(durations) - 1
+	}
+	return durations[i]
+}
+
+// banForward 按封禁时长阶梯封禁转发域名，返回本次封禁时长
+// 模拟运行时封禁次数与封禁到期时间保存在进程内，不写入数据库
+func banForward(d models.DomainRecord, f *models.ForwardRecord, dryRun bool) time.Duration {
+	duration := nextBanDuration(d, *f)
+	if dryRun {
+		f.BanCount++
+		f.IsBan, f.BanTime = true, time.Now().Add(duration).Unix()
+		saveDryRunForward(*f, true)
+		utils.Logger.Infof("🧪 [模拟] 转发域名 %s 第 %d 次封禁 %s", f.ForwardDomain, f.BanCount, durationText(duration))
+		return duration
+	}
+	if err := operate.BanForward(db.DB, f, duration); err != nil {
+		utils.Logger.Errorf("❌ 封禁转发域名失败 %s: %v", f.ForwardDomain, err)
+		return duration
+	}
+
+	utils.Logger.Infof("🚫 转发域名 %s 第 %d 次封禁 %s，至 %s", f.ForwardDomain, f.BanCount, durationText(duration), time.Unix(f.BanTime, 0).Format("2006-01-02 15:04:05"))
+	return duration
+}
+
+// unbanExpired 封禁已到期时自动解除，返回转发域名当前是否仍被封禁；模拟运行时不写入数据库
+func unbanExpired(f *models.ForwardRecord, dryRun bool) bool {
+	if !f.IsBan || f.BanTime == 0 || time.Now().Unix() <= f.BanTime {
+		return f.IsBan
+	}
+	if dryRun {
+		f.IsBan = false
+		saveDryRunForward(*f, false)
+		return false
+	}
+	if err := operate.AutoUnbanForward(db.DB, f); err != nil {
+		utils.Logger.Errorf("自动解除封禁失败: %v", err)
+	}
+	return f.IsBan
+}
+
+// resetBanCount 转发域名检测连通后清零连续封禁次数，下次封禁重新从第一档开始；模拟运行时不写入数据库
+func resetBanCount(f *models.ForwardRecord, dryRun bool) {
+	if f.BanCount == 0 {
+		return
+	}
+	if dryRun {
+		f.BanCount = 0
+		saveDryRunForward(*f, false)
+		return
+	}
+	_ = operate.ResetForwardBanCount(db.DB, f)
+}
+
+// durationText 将时长格式化为 天/小时/分钟，不足一分钟时显示秒
+func durationText(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%d秒", int(d.Seconds()))
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	var sb strings.Builder
+	if days > 0 {
+		sb.WriteString(fmt.Sprintf("%d天", days))
+	}
+	if hours > 0 {
+		sb.WriteString(fmt.Sprintf("%d小时", hours))
+	}
+	if minutes > 0 {
+		sb.WriteString(fmt.Sprintf("%d分钟", minutes))
+	}
+	return sb.String()
+}
+
+// banDurationsLabel 主域名详情中展示的封禁时长阶梯
+func banDurationsLabel(d models.DomainRecord) string {
+	var parts []string
+	for _, dur := range banDurations(d) {
+		parts = append(parts, durationText(dur))
+	}
+	label := strings.Join(parts, " → ")
+	if d.BanDurations == "" {
+		label += "（全局）"
+	}
+	return label
+}
+
+// parseBanDurationsInput 解析主域名封禁时长输入：逗号分隔的时长，- 表示使用全局配置
+func parseBanDurationsInput(d *models.DomainRecord, text string) error {
+	if text == "-" {
+		d.BanDurations = ""
+		return nil
+	}
+	items := strings.Split(strings.ReplaceAll(text, "，", ","), ",")
+	durations, err := parseBanDurations(items)
+	if err != nil {
+		return err
+	}
+	if len(durations) == 0 {
+		return fmt.Errorf("请至少输入一个封禁时长")
+	}
+	var parts []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			parts = append(parts, item)
+		}
+	}
+	d.BanDurations = strings.Join(parts, ",")
+	return nil
+}
+
+// writeBannedForwards 输出转发域名封禁段落
+func writeBannedForwards(message *strings.Builder, banned []BannedForward) {
+	message.WriteString("🚫 *转发域名已封禁*\n")
+	for _, b := range banned {
+		message.WriteString(fmt.Sprintf("  • `%s` - 封禁 `%s` (连续第 %d 次)%s\n", b.Text, durationText(b.Duration), b.Count, simulatedTag(b.Simulated)))
+	}
+	message.WriteString("\n")
+}
@@ -0,0 +1,143 @@
+package bot
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/db/models"
+)
+
+func TestParseBanDurations(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   []string
+		want    []time.Duration
+		wantErr bool
+	}{
+		{name: "空列表", items: nil, want: nil},
+		{name: "多档时长", items: []string{"10m", " 1h ", "24h"}, want: []time.Duration{10 * time.Minute, time.Hour, 24 * time.Hour}},
+		{name: "忽略空项", items: []string{"", "30m", " "}, want: []time.Duration{30 * time.Minute}},
+		{name: "无效时长", items: []string{"10m", "abc"}, wantErr: true},
+		{name: "时长为 0", items: []string{"0s"}, wantErr: true},
+		{name: "负数时长", items: []string{"-1h"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBanDurations(tt.items)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBanDurations(%q) error = %v, wantErr %v", tt.items, err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("parseBanDurations(%q) = %v, want %v", tt.items, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBanDurationsInput(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "-", want: ""},
+		{input: "10m,1h", want: "10m,1h"},
+		{input: " 10m ， 1h ,, 6h ", want: "10m,1h,6h"},
+		{input: ",", wantErr: true},
+		{input: "10m,abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d := models.DomainRecord{BanDurations: "5m"}
+			err := parseBanDurationsInput(&d, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBanDurationsInput(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && d.BanDurations != tt.want {
+				t.Errorf("parseBanDurationsInput(%q) = %q, want %q", tt.input, d.BanDurations, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextBanDuration(t *testing.T) {
+	old := config.Global.AutoCheck.BanDurations
+	t.Cleanup(func() { config.Global.AutoCheck.BanDurations = old })
+
+	tests := []struct {
+		name     string
+		global   []string
+		domain   string
+		banCount int
+		want     time.Duration
+	}{
+		{name: "未配置时默认 24 小时", banCount: 0, want: defaultBanDuration},
+		{name: "未配置时重复封禁仍为 24 小时", banCount: 3, want: defaultBanDuration},
+		{name: "全局第一档", global: []string{"10m", "1h"}, banCount: 0, want: 10 * time.Minute},
+		{name: "全局第二档", global: []string{"10m", "1h"}, banCount: 1, want: time.Hour},
+		{name: "超出阶梯使用最后一档", global: []string{"10m", "1h"}, banCount: 5, want: time.Hour},
+		{name: "主域名设置优先", global: []string{"10m", "1h"}, domain: "5m,30m", banCount: 1, want: 30 * time.Minute},
+		{name: "主域名设置无效时使用全局", global: []string{"10m", "1h"}, domain: "abc", banCount: 0, want: 10 * time.Minute},
+		{name: "全局配置无效时默认 24 小时", global: []string{"abc"}, banCount: 0, want: defaultBanDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Global.AutoCheck.BanDurations = tt.global
+			d := models.DomainRecord{Domain: "example.com", BanDurations: tt.domain}
+			f := models.ForwardRecord{BanCount: tt.banCount}
+			if got := nextBanDuration(d, f); got != tt.want {
+				t.Errorf("nextBanDuration() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationText(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 45 * time.Second, want: "45秒"},
+		{d: 10 * time.Minute, want: "10分钟"},
+		{d: 90 * time.Minute, want: "1小时30分钟"},
+		{d: 24 * time.Hour, want: "1天"},
+		{d: 49*time.Hour + 5*time.Minute, want: "2天1小时5分钟"},
+	}
+	for _, tt := range tests {
+		if got := durationText(tt.d); got != tt.want {
+			t.Errorf("durationText(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestBanDryRun(t *testing.T) {
+	resetDryRunState(t)
+	old := config.Global.AutoCheck.BanDurations
+	config.Global.AutoCheck.BanDurations = []string{"10m", "1h"}
+	t.Cleanup(func() { config.Global.AutoCheck.BanDurations = old })
+
+	// 模拟运行只修改内存中的记录，不访问数据库
+	d := models.DomainRecord{Domain: "example.com"}
+	f := models.ForwardRecord{ForwardDomain: "a.example.com"}
+	if got := banForward(d, &f, true); got != 10*time.Minute || f.BanCount != 1 {
+		t.Fatalf("banForward() = %s, BanCount %d, want 10m and 1", got, f.BanCount)
+	}
+	if got := banForward(d, &f, true); got != time.Hour || f.BanCount != 2 {
+		t.Fatalf("banForward() = %s, BanCount %d, want 1h and 2", got, f.BanCount)
+	}
+	resetBanCount(&f, true)
+	if f.BanCount != 0 {
+		t.Errorf("resetBanCount() BanCount = %d, want 0", f.BanCount)
+	}
+
+	f.IsBan, f.BanTime = true, time.Now().Add(time.Hour).Unix()
+	if !unbanExpired(&f, true) {
+		t.Error("unbanExpired() = false before the ban expires")
+	}
+	f.BanTime = time.Now().Add(-time.Minute).Unix()
+	if unbanExpired(&f, true) || f.IsBan {
+		t.Error("unbanExpired() kept an expired ban")
+	}
+}
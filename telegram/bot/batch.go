@@ -0,0 +1,203 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/db/models"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// batchCheckTarget 批量检测的单个目标
+type batchCheckTarget struct {
+	Target string `json:"target"`
+	Port   int    `json:"port"`
+}
+
+// batchCheckRequest 批量检测请求（POST /api/v1/batch_checks）
+type batchCheckRequest struct {
+	Key     string             `json:"key,omitempty"`
+	Probe   string             `json:"probe"`
+	Params  interface{}        `json:"params"`
+	Targets []batchCheckTarget `json:"targets"`
+}
+
+// batchCheckResult 批量检测中单个目标的结果
+type batchCheckResult struct {
+	Index  int                  `json:"index"`
+	Target string               `json:"target"`
+	Port   int                  `json:"port"`
+	Data   tcpCheckResponseData `json:"data"`
+}
+
+// batchStreamLine 批量检测流式响应的一行
+type batchStreamLine struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// checkConnectivityBatch 通过批量接口向所有检测后端检测同一主域名下的多个目标，并按法定票数汇总
+// 返回与 targets 下标对应的结果，ok[i] 为 false 表示该目标没有任何后端返回结果
+func checkConnectivityBatch(d models.DomainRecord, targets []string, family string) ([]tcpCheckResponseData, []bool, error) {
+	backends := probeBackendList()
+	backendResults := make([][]tcpCheckResponseData, len(backends))
+	backendOK := make([][]bool, len(backends))
+	backendErrs := make([]error, len(backends))
+
+	var wg sync.WaitGroup
+	for i, endpoint := range backends {
+		wg.Add(1)
+		go func(i int, endpoint config.BackendEndpoint) {
+			defer wg.Done()
+			backendResults[i], backendOK[i], backendErrs[i] = probeBackendBatch(endpoint, d, targets, family)
+			if backendErrs[i] != nil {
+				utils.Logger.Warnf("⚠️ 检测后端 %s 批量检测失败: %v", endpoint.Name, backendErrs[i])
+			}
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range backendErrs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed == len(backends) {
+		return nil, nil, backendErrs[0]
+	}
+
+	merged := make([]tcpCheckResponseData, len(targets))
+	ok := make([]bool, len(targets))
+	for t, target := range targets {
+		results := make([]tcpCheckResponseData, len(backends))
+		verdicts := make([]BackendVerdict, len(backends))
+		for b, endpoint := range backends {
+			verdicts[b] = BackendVerdict{Name: endpoint.Name, Err: backendErrs[b]}
+			if backendErrs[b] != nil {
+				continue
+			}
+			if !backendOK[b][t] {
+				verdicts[b].Err = fmt.Errorf("后端未返回 %s 的结果", target)
+				continue
+			}
+			results[b] = backendResults[b][t]
+			verdicts[b].Result = results[b].Result
+			verdicts[b].TargetIp = results[b].TargetIp
+			verdicts[b].Message = results[b].Message
+		}
+
+		result, err := mergeBackendVerdicts(target, results, verdicts)
+		if err != nil {
+			continue
+		}
+		merged[t] = result
+		ok[t] = true
+	}
+	return merged, ok, nil
+}
+
+// probeBackendBatch 调用单个检测后端的批量检测接口，逐行读取各目标结果
+func probeBackendBatch(endpoint config.BackendEndpoint, d models.DomainRecord, targets []string, family string) ([]tcpCheckResponseData, []bool, error) {
+	probe, params := buildProbeRequest(d, "", family)
+	reqBody := batchCheckRequest{
+		Key:     legacyKey(),
+		Probe:   probe,
+		Params:  params,
+		Targets: make([]batchCheckTarget, len(targets)),
+	}
+	for i, target := range targets {
+		reqBody.Targets[i] = batchCheckTarget{Target: target, Port: d.Port}
+	}
+
+	buf, _ := json.Marshal(reqBody)
+	resp, err := doBackend(endpoint, http.MethodPost, "/api/v1/batch_checks", buf, config.Global.BackendURL.Timeout*time.Second)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("backend error: HTTP %d", resp.StatusCode)
+	}
+
+	results := make([]tcpCheckResponseData, len(targets))
+	ok := make([]bool, len(targets))
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var streamLine batchStreamLine
+		if err := json.Unmarshal(line, &streamLine); err != nil {
+			utils.Logger.Warnf("Failed to parse batch response line: %s, error: %v", line, err)
+			continue
+		}
+
+		// 最终汇总 (Code=0)
+		if streamLine.Code == 0 {
+			return results, ok, nil
+		}
+
+		// 单个目标结果 (Code=1)
+		if streamLine.Code == 1 && streamLine.Message == "result" {
+			var result batchCheckResult
+			if err := json.Unmarshal(streamLine.Data, &result); err != nil {
+				utils.Logger.Warnf("Failed to parse batch result: %v", err)
+				continue
+			}
+			if result.Index >= 0 && result.Index < len(targets) {
+				results[result.Index] = result.Data
+				ok[result.Index] = true
+			}
+			continue
+		}
+
+		return nil, nil, fmt.Errorf("backend error: %s", streamLine.Message)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read batch response: %w", err)
+	}
+	return nil, nil, fmt.Errorf("unexpected response format")
+}
+
+// prefetchForwardPool 通过批量接口一次检测转发池中所有未封禁的转发域名（只检测 family 地址族）
+// 返回以 forwards 下标为键的结果；批量接口不可用时返回 nil，由调用方逐个检测
+func prefetchForwardPool(d models.DomainRecord, forwards []models.ForwardRecord, family string) map[int]tcpCheckResponseData {
+	var indexes []int
+	var targets []string
+	for i, f := range forwards {
+		if f.IsBan {
+			continue
+		}
+		indexes = append(indexes, i)
+		targets = append(targets, f.ForwardDomain)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	utils.Logger.Infof("📦 批量检测主域名 %s:%d 的 %d 个转发域名", d.Domain, d.Port, len(targets))
+	results, ok, err := checkConnectivityBatch(d, targets, family)
+	if err != nil {
+		utils.Logger.Warnf("⚠️ 批量检测失败，改为逐个检测: %v", err)
+		return nil
+	}
+
+	prefetched := make(map[int]tcpCheckResponseData, len(targets))
+	for i, idx := range indexes {
+		if ok[i] {
+			prefetched[idx] = results[i]
+		}
+	}
+	return prefetched
+}
@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -16,22 +15,83 @@ import (
 
 	"telegram-auto-switch-dns-bot/db/models"
 	"telegram-auto-switch-dns-bot/db/operate"
+	"telegram-auto-switch-dns-bot/middleware"
 	"telegram-auto-switch-dns-bot/utils"
 )
 
 // 统一后端检测请求/响应结构
 type tcpCheckRequest struct {
-	Target string `json:"target"`
-	Port   int    `json:"port"`
-	Key    string `json:"key"`
+	Target   string `json:"target"`
+	Port     int    `json:"port"`
+	Key      string `json:"key,omitempty"`
+	Protocol string `json:"protocol,omitempty"` // tcp / udp / icmp
+	Payload  string `json:"payload,omitempty"`  // UDP 载荷（十六进制）
+	Expect   string `json:"expect,omitempty"`   // UDP 期望回包（十六进制）
+	Resolver string `json:"resolver,omitempty"` // 后端解析器名称
+	Family   string `json:"family,omitempty"`   // 探测的地址族: ipv4 / ipv6
+}
+
+// httpCheckRequest HTTP(S) 应用层探测请求
+type httpCheckRequest struct {
+	Target       string `json:"target"`
+	Port         int    `json:"port"`
+	Key          string `json:"key,omitempty"`
+	Scheme       string `json:"scheme"`
+	Method       string `json:"method"`
+	Host         string `json:"host"`
+	Path         string `json:"path"`
+	StatusMin    int    `json:"status_min"`
+	StatusMax    int    `json:"status_max"`
+	BodyContains string `json:"body_contains"`
+	BodyRegex    string `json:"body_regex"`
+	Resolver     string `json:"resolver,omitempty"`
+	Family       string `json:"family,omitempty"`
+}
+
+// tlsCheckRequest TLS 握手与证书校验请求
+type tlsCheckRequest struct {
+	Target     string `json:"target"`
+	Port       int    `json:"port"`
+	Key        string `json:"key,omitempty"`
+	ServerName string `json:"server_name"`
+	Resolver   string `json:"resolver,omitempty"`
+	Family     string `json:"family,omitempty"`
 }
 
 type tcpCheckResponseData struct {
-	Result          bool   `json:"result"`
-	Target          string `json:"target"`
-	TargetIp        string `json:"target_ip"`
-	Message         string `json:"message"`
-	BackendPublicIP string `json:"backend_public_ip"`
+	Result          bool             `json:"result"`
+	Target          string           `json:"target"`
+	TargetIp        string           `json:"target_ip"`
+	Message         string           `json:"message"`
+	BackendPublicIP string           `json:"backend_public_ip"`
+	Backends        []BackendVerdict `json:"-"`                     // 多后端检测时各后端的结果
+	StatusCode      int              `json:"status_code,omitempty"` // HTTP 探测的状态码
+	CertValid       bool             `json:"cert_valid,omitempty"`  // TLS 探测：证书是否有效
+	SANMatch        bool             `json:"san_match,omitempty"`   // TLS 探测：SAN 是否匹配主域名
+	DaysLeft        int              `json:"days_left,omitempty"`   // TLS 探测：证书剩余天数
+	NotAfter        int64            `json:"not_after,omitempty"`   // TLS 探测：证书到期时间戳
+	Protocol        string           `json:"protocol,omitempty"`    // TLS 探测：协商的协议版本
+	Latency         *latencyStats    `json:"latency,omitempty"`     // 连接耗时统计
+	Addresses       []addressResult  `json:"addresses,omitempty"`   // 目标解析到的每个 IP 的检测结果
+	Resolver        string           `json:"resolver,omitempty"`    // 应答的解析器名称
+	TTL             uint32           `json:"ttl,omitempty"`         // 解析应答的 TTL（秒）
+}
+
+// addressResult 后端返回的单个解析 IP 的检测结果
+type addressResult struct {
+	IP      string        `json:"ip"`
+	Result  bool          `json:"result"`
+	Message string        `json:"message,omitempty"`
+	Latency *latencyStats `json:"latency,omitempty"`
+}
+
+// latencyStats 后端返回的耗时统计（毫秒）
+type latencyStats struct {
+	Min     float64 `json:"min"`
+	Avg     float64 `json:"avg"`
+	Max     float64 `json:"max"`
+	Jitter  float64 `json:"jitter"`
+	Samples int     `json:"samples"`
 }
 
 type apiResponse struct {
@@ -40,28 +100,101 @@ type apiResponse struct {
 	Data    interface{} `json:"data"`
 }
 
-// checkConnectivityWithProgress 调用后端 /api/v1/tcp_checks（带进度回调）
-func checkConnectivityWithProgress(target string, port int, progressCallback func(current int, total int)) (tcpCheckResponseData, error) {
-	backend := strings.TrimRight(config.Global.BackendURL.Api, "/")
-	url := backend + "/api/v1/tcp_checks"
+// buildProbeRequest 根据主域名的探测类型构造后端探测类型（tcp/http/tls，对应 /api/v1/<probe>_checks）与请求体
+// family 为只检测的地址族（ipv4 / ipv6）
+func buildProbeRequest(d models.DomainRecord, target string, family string) (string, interface{}) {
+	switch d.ProbeType {
+	case "http", "https":
+		// 转发域名承载的是主域名的流量，Host 头默认使用主域名
+		host := d.HTTPHost
+		if host == "" {
+			host = d.Domain
+		}
+		return "http", httpCheckRequest{
+			Target:       target,
+			Port:         d.Port,
+			Key:          legacyKey(),
+			Scheme:       d.ProbeType,
+			Method:       d.HTTPMethod,
+			Host:         host,
+			Path:         d.HTTPPath,
+			StatusMin:    d.HTTPStatusMin,
+			StatusMax:    d.HTTPStatusMax,
+			BodyContains: d.HTTPBodyMatch,
+			BodyRegex:    d.HTTPBodyRegex,
+			Resolver:     d.Resolver,
+			Family:       family,
+		}
+	case "tls":
+		// 以主域名作为 SNI，校验转发 IP 上的证书能否服务主域名
+		return "tls", tlsCheckRequest{
+			Target:     target,
+			Port:       d.Port,
+			Key:        legacyKey(),
+			ServerName: d.Domain,
+			Resolver:   d.Resolver,
+			Family:     family,
+		}
+	case "udp", "icmp":
+		return "tcp", tcpCheckRequest{
+			Target:   target,
+			Port:     d.Port,
+			Key:      legacyKey(),
+			Protocol: d.ProbeType,
+			Payload:  d.UDPPayload,
+			Expect:   d.UDPExpect,
+			Resolver: d.Resolver,
+			Family:   family,
+		}
+	default:
+		return "tcp", tcpCheckRequest{
+			Target:   target,
+			Port:     d.Port,
+			Key:      legacyKey(),
+			Resolver: d.Resolver,
+			Family:   family,
+		}
+	}
+}
 
-	// 构建请求体
-	payload := tcpCheckRequest{
-		Target: target,
-		Port:   port,
-		Key:    config.Global.BackendListen.Key,
+// legacyKey 兼容旧版后端时在请求体中携带明文 key，否则留空仅依赖请求签名
+func legacyKey() string {
+	if config.Global.BackendURL.LegacyKey {
+		return config.Global.BackendListen.Key
 	}
-	buf, _ := json.Marshal(payload)
+	return ""
+}
 
-	// 发送 POST 请求（流式）
-	client := &http.Client{Timeout: config.Global.BackendURL.Timeout * time.Second}
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(buf))
+// newBackendRequest 构造发往检测后端的请求，并附加 HMAC 签名头（body 为空时不设置 Content-Type，兼容模式下改用请求头携带 key）
+func newBackendRequest(method string, url string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
 	if err != nil {
-		return tcpCheckResponseData{}, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	} else if key := legacyKey(); key != "" {
+		req.Header.Set(middleware.HeaderBackendKey, key)
+	}
+	middleware.SignBackendRequest(req, body, config.Global.BackendListen.Key)
+	return req, nil
+}
+
+// checkConnectivityWithProgress 按主域名的探测类型向所有检测后端发起检测（带进度回调），并按法定票数汇总结果
+// target 为实际检测的目标（主域名或转发域名），端口与探测参数取自主域名，family 为只检测的地址族
+func checkConnectivityWithProgress(d models.DomainRecord, target string, family string, progressCallback func(current int, total int)) (tcpCheckResponseData, error) {
+	return probeAllBackends(d, target, family, progressCallback)
+}
+
+// probeBackend 调用单个检测后端的检测接口（流式读取进度与结果）
+func probeBackend(endpoint config.BackendEndpoint, d models.DomainRecord, target string, family string, progressCallback func(current int, total int)) (tcpCheckResponseData, error) {
+	probe, payload := buildProbeRequest(d, target, family)
 
-	resp, err := client.Do(req)
+	// 构建请求体
+	buf, _ := json.Marshal(payload)
+
+	// 发送 POST 请求（流式）
+	resp, err := doBackend(endpoint, http.MethodPost, "/api/v1/"+probe+"_checks", buf, config.Global.BackendURL.Timeout*time.Second)
 	if err != nil {
 		return tcpCheckResponseData{}, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -107,6 +240,9 @@ func checkConnectivityWithProgress(target string, port int, progressCallback fun
 			if err := json.Unmarshal(dataBytes, &result); err != nil {
 				return tcpCheckResponseData{}, fmt.Errorf("failed to unmarshal data: %v", err)
 			}
+			if result.Resolver != "" {
+				utils.Logger.Infof("🧭 后端 %s 使用解析器 %s 解析 %s (TTL: %d)", endpoint.Name, result.Resolver, target, result.TTL)
+			}
 
 			return result, nil
 		}
@@ -320,10 +456,13 @@ func editForwardInfo(bot *tgbotapi.BotAPI, chatID int64, messageID int, forwardI
 			"*ISP*: `%s`\n"+
 			"*封禁状态*: `%s`\n"+
 			"*封禁时间*: `%s`\n"+
+			"*连续封禁*: `%d 次`\n"+
 			"*权重*: `%d`\n"+
 			"*排序*: `%d`\n"+
-			"*记录类型*: `%s`",
-		f.ID, d.Domain, d.Port, f.ForwardDomain, f.IP, f.ISP, status, banTimeText, f.Weight, f.SortOrder, f.RecordType,
+			"*记录类型*: `%s`\n"+
+			"*最近延迟*: `%s`",
+		f.ID, d.Domain, d.Port, f.ForwardDomain, f.IP, f.ISP, status, banTimeText, f.BanCount, f.Weight, f.SortOrder, f.RecordType,
+		latencyText(f.LastLatency),
 	)
 	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, ForwardActionsKeyboard(f))
 	edit.ParseMode = "Markdown"
@@ -80,9 +80,33 @@ func InitCommands() {
 		},
 		{
 			Command:      "manual_check",
-			Description:  "手动执行一次完整的域名检测和自动切换",
+			Description:  "手动执行一次完整的域名检测和自动切换，加 dryrun 参数为模拟检测",
 			Handler:      manualCheckHandler,
 			RequireAdmin: true,
 		},
+		{
+			Command:      "backends",
+			Description:  "查看检测后端状态（版本、探测能力、出口 IP）",
+			Handler:      backendsHandler,
+			RequireAdmin: true,
+		},
+		{
+			Command:      "pause",
+			Description:  "暂停自动检测一段时间，如 /pause 2h",
+			Handler:      pauseHandler,
+			RequireAdmin: true,
+		},
+		{
+			Command:      "resume",
+			Description:  "立即恢复自动检测",
+			Handler:      resumeHandler,
+			RequireAdmin: true,
+		},
+		{
+			Command:      "maintenance",
+			Description:  "查看与管理维护窗口（窗口内只观察，不切换、不封禁）",
+			Handler:      maintenanceHandler,
+			RequireAdmin: true,
+		},
 	}
 }
@@ -64,6 +64,75 @@ func HandleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 			_, _ = bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
 			return
 		}
+		if strings.HasPrefix(data, "dom_probe:") {
+			idStr := strings.TrimPrefix(data, "dom_probe:")
+			did, _ := strconv.ParseUint(idStr, 10, 64)
+			chatID := update.CallbackQuery.Message.Chat.ID
+			msgID := update.CallbackQuery.Message.MessageID
+			handleDomainToggleProbe(uint(did))
+			showDomainDetail(bot, chatID, msgID, uint(did))
+			_, _ = bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
+			return
+		}
+		if strings.HasPrefix(data, "dom_strategy:") {
+			idStr := strings.TrimPrefix(data, "dom_strategy:")
+			did, _ := strconv.ParseUint(idStr, 10, 64)
+			chatID := update.CallbackQuery.Message.Chat.ID
+			msgID := update.CallbackQuery.Message.MessageID
+			handleDomainToggleStrategy(uint(did))
+			showDomainDetail(bot, chatID, msgID, uint(did))
+			_, _ = bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
+			return
+		}
+		if strings.HasPrefix(data, "dom_publish:") {
+			idStr := strings.TrimPrefix(data, "dom_publish:")
+			did, _ := strconv.ParseUint(idStr, 10, 64)
+			chatID := update.CallbackQuery.Message.Chat.ID
+			msgID := update.CallbackQuery.Message.MessageID
+			handleDomainTogglePublish(uint(did))
+			showDomainDetail(bot, chatID, msgID, uint(did))
+			_, _ = bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
+			return
+		}
+		if strings.HasPrefix(data, "dom_failback:") {
+			idStr := strings.TrimPrefix(data, "dom_failback:")
+			did, _ := strconv.ParseUint(idStr, 10, 64)
+			chatID := update.CallbackQuery.Message.Chat.ID
+			msgID := update.CallbackQuery.Message.MessageID
+			handleDomainToggleFailback(uint(did))
+			showDomainDetail(bot, chatID, msgID, uint(did))
+			_, _ = bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
+			return
+		}
+		if strings.HasPrefix(data, "dom_dryrun:") {
+			idStr := strings.TrimPrefix(data, "dom_dryrun:")
+			did, _ := strconv.ParseUint(idStr, 10, 64)
+			chatID := update.CallbackQuery.Message.Chat.ID
+			msgID := update.CallbackQuery.Message.MessageID
+			handleDomainToggleDryRun(uint(did))
+			showDomainDetail(bot, chatID, msgID, uint(did))
+			_, _ = bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
+			return
+		}
+		if strings.HasPrefix(data, "dom_history:") {
+			idStr := strings.TrimPrefix(data, "dom_history:")
+			did, _ := strconv.ParseUint(idStr, 10, 64)
+			chatID := update.CallbackQuery.Message.Chat.ID
+			msgID := update.CallbackQuery.Message.MessageID
+			showDomainHistory(bot, chatID, msgID, uint(did))
+			_, _ = bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
+			return
+		}
+		if strings.HasPrefix(data, "dom_family:") {
+			idStr := strings.TrimPrefix(data, "dom_family:")
+			did, _ := strconv.ParseUint(idStr, 10, 64)
+			chatID := update.CallbackQuery.Message.Chat.ID
+			msgID := update.CallbackQuery.Message.MessageID
+			handleDomainToggleFamily(uint(did))
+			showDomainDetail(bot, chatID, msgID, uint(did))
+			_, _ = bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
+			return
+		}
 		if strings.HasPrefix(data, "dom_delete:") {
 			idStr := strings.TrimPrefix(data, "dom_delete:")
 			did, _ := strconv.ParseUint(idStr, 10, 64)
@@ -112,6 +181,65 @@ func HandleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 					text = "🔌 *修改端口*\n\n请输入新的端口（数字）："
 				case "sort":
 					text = "🔢 *修改排序*\n\n请输入新的排序值（数字）："
+				case "http":
+					text = "🌐 *修改 HTTP 探测参数*\n\n" +
+						"请按照以下格式输入：\n" +
+						"`方法|Host|路径|状态码范围|响应体关键字|响应体正则`\n\n" +
+						"*示例*:\n" +
+						"`GET||/health|200-299|ok|`\n\n" +
+						"*说明*:\n" +
+						"- 方法可选 GET 或 HEAD\n" +
+						"- Host 为空时使用主域名\n" +
+						"- 关键字与正则可为空"
+				case "resolver":
+					text = "🧭 *修改解析器*\n\n请输入检测后端 `backend_listen.resolvers` 中配置的解析器名称：\n\n" +
+						"- `system` 表示后端系统解析器\n" +
+						"- 输入 `-` 使用后端的 `default_resolver`"
+				case "ceiling":
+					text = "📶 *修改延迟上限*\n\n请输入新的延迟上限（毫秒，数字）：\n\n" +
+						"仅在选择策略为「权重+延迟上限」时生效"
+				case "failback":
+					text = "⏱ *修改回切稳定期*\n\n请输入高优先级转发需要持续连通的时长（秒，数字）：\n\n" +
+						"开启自动回切后，每轮自动检测都会探测更高优先级的转发，持续连通达到该时长后切回"
+				case "threshold":
+					text = "📉 *修改切换阈值*\n\n" +
+						"请按照以下格式输入：\n" +
+						"`连续失败次数|连续成功次数`\n\n" +
+						"*示例*:\n" +
+						"`3|2`\n\n" +
+						"*说明*:\n" +
+						"- 主域名连续失败达到次数才判定不通并切换\n" +
+						"- 判定不通后连续成功达到次数才判定恢复\n" +
+						"- 手动检测不等待阈值"
+				case "ban":
+					text = "🚫 *修改封禁时长*\n\n" +
+						"请输入逗号分隔的封禁时长阶梯：\n\n" +
+						"*示例*:\n" +
+						"`10m,1h,6h,24h`\n\n" +
+						"*说明*:\n" +
+						"- 转发域名每次连续被封禁使用下一档时长，超出后保持最后一档\n" +
+						"- 转发域名检测连通后重新从第一档开始\n" +
+						"- 输入 `-` 使用全局 `ban_durations` 配置"
+				case "schedule":
+					text = "⏰ *修改检测计划*\n\n" +
+						"请输入检测间隔或 cron 表达式，可用 `|` 附加随机推迟上限：\n\n" +
+						"*示例*:\n" +
+						"`30s`\n" +
+						"`1h|5m`\n" +
+						"`*/5 9-23 * * *|30s`\n\n" +
+						"*说明*:\n" +
+						"- 间隔最短 10s，支持 s/m/h 单位\n" +
+						"- cron 为 5 段（分 时 日 月 周），按服务器时区\n" +
+						"- 随机推迟用于错开同时到期的主域名\n" +
+						"- 输入 `-` 使用全局 `check_time` 间隔"
+				case "udp":
+					text = "📦 *修改 UDP 探测参数*\n\n" +
+						"请按照以下格式输入（十六进制）：\n" +
+						"`载荷|期望回包`\n\n" +
+						"*示例*:\n" +
+						"`ffffffff54536f7572636520456e67696e6520517565727900|ffffffff49`\n\n" +
+						"*说明*:\n" +
+						"- 期望回包为空时，收到任意回包即视为连通"
 				}
 				edit := tgbotapi.NewEditMessageText(chatID, msgID, text)
 				edit.ParseMode = "Markdown"
@@ -327,6 +455,16 @@ func HandleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 			_, _ = bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
 			return
 		}
+		if strings.HasPrefix(data, "fwd_trace:") {
+			idStr := strings.TrimPrefix(data, "fwd_trace:")
+			fid, _ := strconv.ParseUint(idStr, 10, 64)
+			chatID := update.CallbackQuery.Message.Chat.ID
+			msgID := update.CallbackQuery.Message.MessageID
+			// 追踪最长需要数十秒，异步执行避免阻塞消息分发
+			go handleForwardTrace(bot, chatID, msgID, uint(fid))
+			_, _ = bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
+			return
+		}
 		if strings.HasPrefix(data, "fwd_get_ip:") {
 			idStr := strings.TrimPrefix(data, "fwd_get_ip:")
 			fid, _ := strconv.ParseUint(idStr, 10, 64)
@@ -446,7 +584,7 @@ func HandleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
 					f.RecordType = value
 				}
 
-				if err := operate.UpdateForwardRecord(db.DB, f); err != nil {
+				if err := operate.UpdateForwardFields(db.DB, f.ID, map[string]interface{}{"record_type": f.RecordType}); err != nil {
 					edit := tgbotapi.NewEditMessageText(chatID, msgID, "❌ 更新失败："+err.Error())
 					_, _ = bot.Send(edit)
 					return
@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/db/models"
+)
+
+// dryRunEnabled 主域名是否模拟运行：全局 dry_run 或主域名单独开启
+func dryRunEnabled(d models.DomainRecord) bool {
+	return config.Global.AutoCheck.DryRun || d.DryRun
+}
+
+// dryRunLabel 主域名详情中展示的模拟运行状态
+func dryRunLabel(d models.DomainRecord) string {
+	switch {
+	case config.Global.AutoCheck.DryRun:
+		return "开启（全局）"
+	case d.DryRun:
+		return "开启"
+	}
+	return "关闭"
+}
+
+// dryRunForward 模拟运行中转发域名的封禁与稳定期状态
+type dryRunForward struct {
+	BanCount     int
+	BanUntil     int64 // 模拟封禁的到期时间，0 表示未模拟封禁
+	HealthySince int64
+}
+
+// 模拟运行不写数据库，而检测协程每次都从数据库重新加载记录，
+// 因此连续检测计数、稳定期起点和封禁次数保存在进程内，下次模拟检测时叠加到记录上，重启后清空
+var (
+	dryRunMu       sync.Mutex
+	dryRunHealth   = make(map[string]domainHealth) // 主域名ID/地址族 -> 连续检测状态
+	dryRunForwards = make(map[uint]dryRunForward)  // 转发记录ID -> 封禁与稳定期状态
+)
+
+// dryRunHealthKey 模拟运行连续检测状态的键
+func dryRunHealthKey(domainID uint, family string) string {
+	return fmt.Sprintf("%d/%s", domainID, family)
+}
+
+// loadDryRunState 将之前模拟检测累计的状态叠加到刚从数据库加载的主域名与转发记录上
+func loadDryRunState(d *models.DomainRecord) {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+
+	for _, family := range domainFamilies(*d) {
+		if h, ok := dryRunHealth[dryRunHealthKey(d.ID, family)]; ok {
+			setFamilyHealth(d, family, h)
+		}
+	}
+	forwards := make([]models.ForwardRecord, len(d.Forwards))
+	for i, f := range d.Forwards {
+		if s, ok := dryRunForwards[f.ID]; ok {
+			f.BanCount, f.HealthySince = s.BanCount, s.HealthySince
+			if s.BanUntil > 0 && !f.IsBan {
+				f.IsBan, f.BanTime = true, s.BanUntil
+			}
+		}
+		forwards[i] = f
+	}
+	d.Forwards = forwards
+}
+
+// clearDryRunState 主域名真实检测时丢弃模拟运行累计的状态，再次开启模拟运行时从数据库中的状态重新开始
+func clearDryRunState(d models.DomainRecord) {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+
+	for _, family := range []string{"ipv4", "ipv6"} {
+		delete(dryRunHealth, dryRunHealthKey(d.ID, family))
+	}
+	for _, f := range d.Forwards {
+		delete(dryRunForwards, f.ID)
+	}
+}
+
+// saveDryRunHealth 保存模拟运行中主域名 family 地址族的连续检测状态
+func saveDryRunHealth(d models.DomainRecord, family string, h domainHealth) {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+	dryRunHealth[dryRunHealthKey(d.ID, family)] = h
+}
+
+// saveDryRunForward 保存模拟运行中转发域名的封禁次数、模拟封禁与稳定期起点
+func saveDryRunForward(f models.ForwardRecord, simulatedBan bool) {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+
+	s := dryRunForwards[f.ID]
+	s.BanCount, s.HealthySince = f.BanCount, f.HealthySince
+	switch {
+	case simulatedBan:
+		s.BanUntil = f.BanTime
+	case !f.IsBan || s.BanUntil <= time.Now().Unix():
+		s.BanUntil = 0
+	}
+	dryRunForwards[f.ID] = s
+}
+
+// simulatedTag 模拟运行的切换与封禁在报告中附加的标记
+func simulatedTag(simulated bool) string {
+	if simulated {
+		return " 🧪模拟"
+	}
+	return ""
+}
+
+// hasSimulated 报告中是否包含模拟运行的切换、回切或封禁
+func hasSimulated(report *CheckReport) bool {
+	for _, sw := range report.SwitchedDomains {
+		if sw.Simulated {
+			return true
+		}
+	}
+	for _, fb := range report.FailbackDomains {
+		if fb.Simulated {
+			return true
+		}
+	}
+	for _, b := range report.BannedForwards {
+		if b.Simulated {
+			return true
+		}
+	}
+	return false
+}
+
+// writeDryRunNotice 报告为模拟运行或包含模拟结果时在开头输出提示
+func writeDryRunNotice(message *strings.Builder, report *CheckReport) {
+	switch {
+	case report.DryRun:
+		message.WriteString("🧪 *模拟运行*：以下切换与封禁均未实际执行\n\n")
+	case hasSimulated(report):
+		message.WriteString("🧪 标记 *模拟* 的切换与封禁来自模拟运行的主域名，未实际执行\n\n")
+	}
+}
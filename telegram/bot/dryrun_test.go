@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/db/models"
+)
+
+// resetDryRunState 清空进程内的模拟运行状态，测试结束后同样清空
+func resetDryRunState(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		dryRunMu.Lock()
+		defer dryRunMu.Unlock()
+		dryRunHealth = make(map[string]domainHealth)
+		dryRunForwards = make(map[uint]dryRunForward)
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// reloadDomain 模拟检测协程每次从数据库重新加载记录：数据库中的记录不被模拟运行修改
+func reloadDomain(stored models.DomainRecord) models.DomainRecord {
+	d := stored
+	d.Forwards = append([]models.ForwardRecord(nil), stored.Forwards...)
+	loadDryRunState(&d)
+	return d
+}
+
+func TestDryRunHealthAcrossChecks(t *testing.T) {
+	resetDryRunState(t)
+
+	stored := models.DomainRecord{ID: 1, Domain: "example.com", Port: 443, DownThreshold: 2, UpThreshold: 2}
+	opts := checkOptions{DryRun: true}
+
+	// 连续两次模拟检测失败：第一次未达到阈值，第二次判定不通
+	for i, want := range []bool{false, true} {
+		d := reloadDomain(stored)
+		down, streak := recordDomainFailure(&d, "ipv4", "timeout", &CheckReport{}, opts)
+		if down != want || streak != i+1 {
+			t.Fatalf("第 %d 次模拟检测 recordDomainFailure() = %v, %d, want %v, %d", i+1, down, streak, want, i+1)
+		}
+	}
+
+	// 恢复同样需要跨检测累计连续成功次数
+	for i, want := range []bool{true, false} {
+		d := reloadDomain(stored)
+		recordDomainSuccess(&d, "ipv4", &CheckReport{}, opts)
+		if h := familyHealth(d, "ipv4"); h.Down != want || h.SuccessStreak != i+1 {
+			t.Fatalf("第 %d 次模拟检测成功后状态 = %+v, want Down %v, SuccessStreak %d", i+1, h, want, i+1)
+		}
+	}
+
+	if stored.FailStreak != 0 || stored.IsDown {
+		t.Errorf("模拟运行修改了数据库中的记录: %+v", stored)
+	}
+
+	// 真实检测丢弃模拟状态
+	clearDryRunState(stored)
+	if h := familyHealth(reloadDomain(stored), "ipv4"); h != (domainHealth{}) {
+		t.Errorf("clearDryRunState() 后状态 = %+v, want 初始状态", h)
+	}
+}
+
+func TestDryRunForwardAcrossChecks(t *testing.T) {
+	resetDryRunState(t)
+	old := config.Global.AutoCheck.BanDurations
+	config.Global.AutoCheck.BanDurations = []string{"10m", "1h"}
+	t.Cleanup(func() { config.Global.AutoCheck.BanDurations = old })
+
+	stored := models.DomainRecord{
+		ID:       1,
+		Domain:   "example.com",
+		Forwards: []models.ForwardRecord{{ID: 7, ForwardDomain: "a.example.com", RecordType: "CNAME"}},
+	}
+
+	// 第一次模拟检测封禁，下次检测时仍处于模拟封禁中
+	d := reloadDomain(stored)
+	if got := banForward(d, &d.Forwards[0], true); got != 10*time.Minute {
+		t.Fatalf("第一次封禁时长 = %s, want 10m", got)
+	}
+	d = reloadDomain(stored)
+	if f := d.Forwards[0]; !f.IsBan || f.BanCount != 1 {
+		t.Fatalf("下次检测时转发状态 IsBan %v, BanCount %d, want true, 1", f.IsBan, f.BanCount)
+	}
+
+	// 模拟封禁到期后自动解除，再次封禁按阶梯升级
+	dryRunMu.Lock()
+	s := dryRunForwards[7]
+	s.BanUntil = time.Now().Add(-time.Minute).Unix()
+	dryRunForwards[7] = s
+	dryRunMu.Unlock()
+
+	d = reloadDomain(stored)
+	forwards := familyForwards(d, "ipv4", true)
+	if forwards[0].IsBan {
+		t.Fatal("familyForwards() 未解除已到期的模拟封禁")
+	}
+	if got := banForward(d, &forwards[0], true); got != time.Hour {
+		t.Fatalf("第二次封禁时长 = %s, want 1h", got)
+	}
+
+	// 稳定期起点跨检测保留
+	since := time.Now().Unix()
+	d = reloadDomain(stored)
+	setHealthySince(&d.Forwards[0], since, true)
+	if f := reloadDomain(stored).Forwards[0]; f.HealthySince != since || f.BanCount != 2 {
+		t.Errorf("下次检测时 HealthySince %d, BanCount %d, want %d, 2", f.HealthySince, f.BanCount, since)
+	}
+	if f := stored.Forwards[0]; f.IsBan || f.BanCount != 0 || f.HealthySince != 0 {
+		t.Errorf("模拟运行修改了数据库中的记录: %+v", f)
+	}
+}
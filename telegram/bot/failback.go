@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"telegram-auto-switch-dns-bot/db"
+	"telegram-auto-switch-dns-bot/db/models"
+	"telegram-auto-switch-dns-bot/db/operate"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// DomainFailback 自动回切到高优先级转发的主域名
+type DomainFailback struct {
+	DomainSwitch
+	Family      string        // 双栈主域名回切的地址族记录类型（A / AAAA），单栈时为空
+	FromForward string        // 回切前的转发域名
+	StableFor   time.Duration // 高优先级转发已持续连通的时长
+}
+
+// familyForwards 返回能服务 family 地址族的转发记录，按优先级排列（权重从大到小，同权重按排序值）
+// 封禁已到期的转发在此自动解除
+func familyForwards(d models.DomainRecord, family string, dryRun bool) []models.ForwardRecord {
+	var forwards []models.ForwardRecord
+	for _, f := range d.Forwards {
+		if forwardServesFamily(d, f, family) {
+			unbanExpired(&f, dryRun)
+			forwards = append(forwards, f)
+		}
+	}
+	sort.Slice(forwards, func(i, j int) bool {
+		if forwards[i].Weight != forwards[j].Weight {
+			return forwards[i].Weight > forwards[j].Weight
+		}
+		return forwards[i].SortOrder < forwards[j].SortOrder
+	})
+	return forwards
+}
+
+// failbackStable 返回回切前高优先级转发需要持续连通的时长，未设置时为 10 分钟
+func failbackStable(d models.DomainRecord) time.Duration {
+	if d.FailbackStable <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(d.FailbackStable) * time.Second
+}
+
+// setHealthySince 更新高优先级转发的稳定期起点，模拟运行时保存在进程内
+func setHealthySince(f *models.ForwardRecord, since int64, dryRun bool) {
+	if dryRun {
+		f.HealthySince = since
+		saveDryRunForward(*f, false)
+		return
+	}
+	_ = operate.UpdateForwardHealthySince(db.DB, f, since)
+}
+
+// checkFailback 当前转发正常时探测优先级更高的转发，持续连通达到稳定期后回切到其中优先级最高的一个
+// 稳定期起点保存在转发记录上，跨检测周期与重启累计
+func checkFailback(d models.DomainRecord, family string, report *CheckReport, opts checkOptions) {
+	forwards := familyForwards(d, family, opts.DryRun)
+	current := -1
+	for i, f := range forwards {
+		if f.ResolveStatus == "success" {
+			current = i
+			break
+		}
+	}
+	// 当前转发未知或已是最高优先级时无需回切
+	if current <= 0 {
+		return
+	}
+
+	higher := forwards[:current]
+	utils.Logger.Infof("🔙 主域名 %s 当前转发 %s，探测 %d 个更高优先级的转发", domainTarget(d, family), forwards[current].ForwardDomain, len(higher))
+	prefetched := prefetchForwardPool(d, higher, family)
+	now := time.Now()
+	stable := failbackStable(d)
+
+	for i := range higher {
+		f := &higher[i]
+		if f.IsBan || opts.Maintenance.forward(f.ID) {
+			continue
+		}
+
+		result, found := prefetched[i]
+		var err error
+		if !found {
+			result, err = checkConnectivityWithProgress(d, f.ForwardDomain, family, nil)
+		}
+		recordCheckResult(d, f.ID, f.ForwardDomain, family, result, err, "failback")
+		if err != nil {
+			// 接口调用失败不能说明转发不通，保留已累计的稳定期
+			utils.Logger.Warnf("⚠️ 回切探测 %s 失败: %v", f.ForwardDomain, err)
+			continue
+		}
+		if !result.Result {
+			if f.HealthySince != 0 {
+				setHealthySince(f, 0, opts.DryRun)
+			}
+			utils.Logger.Infof("🔙 高优先级转发 %s 仍不可用", f.ForwardDomain)
+			continue
+		}
+
+		resetBanCount(f, opts.DryRun)
+		recordForwardLatency(f, result, opts.DryRun)
+		if f.HealthySince == 0 {
+			setHealthySince(f, now.Unix(), opts.DryRun)
+		}
+		stableFor := now.Sub(time.Unix(f.HealthySince, 0))
+		if stableFor < stable {
+			utils.Logger.Infof("🔙 高优先级转发 %s 已连通 %s，达到 %s 后回切", f.ForwardDomain, stableFor.Round(time.Second), stable)
+			continue
+		}
+
+		sw, ok := publishForward(d, family, *f, healthyAddresses(result), opts.DryRun)
+		if !ok {
+			return
+		}
+		setHealthySince(f, 0, opts.DryRun)
+		utils.Logger.Infof("🔙 主域名 %s 已回切到 %s", domainTarget(d, family), f.ForwardDomain)
+		recordSwitchEvent(d, family, *f, sw, fmt.Sprintf("高优先级转发已稳定 %s", formatUptime(int64(stableFor.Seconds()))), "failback")
+
+		failback := DomainFailback{DomainSwitch: sw, FromForward: forwards[current].ForwardDomain, StableFor: stableFor}
+		if d.IPFamily == "dual" {
+			failback.Family = familyRecordType(family)
+		}
+		report.add(func() { report.FailbackDomains = append(report.FailbackDomains, failback) })
+		return
+	}
+}
+
+// writeFailbackDomains 输出自动回切段落
+func writeFailbackDomains(message *strings.Builder, failbacks []DomainFailback) {
+	message.WriteString("🔙 *自动回切*\n")
+	for _, fb := range failbacks {
+		if fb.Family != "" {
+			message.WriteString(fmt.Sprintf("  • `%s:%d` (%s)\n", fb.Domain, fb.Port, fb.Family))
+		} else {
+			message.WriteString(fmt.Sprintf("  • `%s:%d`\n", fb.Domain, fb.Port))
+		}
+		message.WriteString(fmt.Sprintf(
+			"    `%s` → `%s` | 权重: `%d`%s\n"+
+				"    已稳定: `%s` | 延迟: `%s`\n",
+			fb.FromForward, fb.ForwardDomain, fb.Weight, simulatedTag(fb.Simulated), formatUptime(int64(fb.StableFor.Seconds())), latencyText(fb.Latency),
+		))
+	}
+	message.WriteString("\n")
+}
+
+// failbackLabel 主域名详情中展示的回切策略
+func failbackLabel(d models.DomainRecord) string {
+	if !d.FailbackEnabled {
+		return "关闭"
+	}
+	return fmt.Sprintf("开启（稳定 %s 后回切）", formatUptime(int64(failbackStable(d).Seconds())))
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"fmt"
+
+	"telegram-auto-switch-dns-bot/db/models"
+)
+
+// 主域名地址族
+var ipFamilies = []string{"ipv4", "ipv6", "dual"}
+
+// ipFamilyLabel 地址族展示名称
+func ipFamilyLabel(family string) string {
+	switch family {
+	case "ipv6":
+		return "仅IPv6"
+	case "dual":
+		return "双栈"
+	default:
+		return "仅IPv4"
+	}
+}
+
+// domainFamilies 返回主域名需要检测的地址族（ipv4 / ipv6），双栈时两个地址族各自独立检测与切换
+func domainFamilies(d models.DomainRecord) []string {
+	switch d.IPFamily {
+	case "ipv6":
+		return []string{"ipv6"}
+	case "dual":
+		return []string{"ipv4", "ipv6"}
+	default:
+		return []string{"ipv4"}
+	}
+}
+
+// familyRecordType 地址族对应的地址记录类型
+func familyRecordType(family string) string {
+	if family == "ipv6" {
+		return "AAAA"
+	}
+	return "A"
+}
+
+// familyRecordID 返回主域名在该地址族下的 Cloudflare 记录 ID
+func familyRecordID(d models.DomainRecord, family string) string {
+	if family == "ipv6" {
+		return d.RecordIdV6
+	}
+	return d.RecordId
+}
+
+// forwardServesFamily 判断转发记录能否用于主域名该地址族的切换：
+// A 记录服务 IPv4，AAAA 记录服务 IPv6；CNAME 不能与同名 A/AAAA 记录共存，只用于单栈主域名
+func forwardServesFamily(d models.DomainRecord, f models.ForwardRecord, family string) bool {
+	switch f.RecordType {
+	case "A", "AAAA":
+		return f.RecordType == familyRecordType(family)
+	case "CNAME":
+		return d.IPFamily != "dual"
+	default:
+		return false
+	}
+}
+
+// forwardFamily 返回检测转发记录时使用的地址族
+func forwardFamily(d models.DomainRecord, f models.ForwardRecord) string {
+	switch f.RecordType {
+	case "A":
+		return "ipv4"
+	case "AAAA":
+		return "ipv6"
+	default:
+		return domainFamilies(d)[0]
+	}
+}
+
+// domainTarget 报告中展示的主域名，双栈主域名附带地址族
+func domainTarget(d models.DomainRecord, family string) string {
+	if d.IPFamily == "dual" {
+		return fmt.Sprintf("%s:%d (%s)", d.Domain, d.Port, familyRecordType(family))
+	}
+	return fmt.Sprintf("%s:%d", d.Domain, d.Port)
+}
+
+// inferIPFamily 根据转发记录类型推断导入主域名的地址族
+func inferIPFamily(forwards []models.ForwardRecord) string {
+	hasV4, hasV6 := false, false
+	for _, f := range forwards {
+		switch f.RecordType {
+		case "AAAA":
+			hasV6 = true
+		default:
+			hasV4 = true
+		}
+	}
+	switch {
+	case hasV4 && hasV6:
+		return "dual"
+	case hasV6:
+		return "ipv6"
+	default:
+		return "ipv4"
+	}
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"telegram-auto-switch-dns-bot/db"
+	"telegram-auto-switch-dns-bot/db/models"
+	"telegram-auto-switch-dns-bot/db/operate"
+)
+
+// 转发选择策略
+var selectStrategies = []string{"weight", "latency", "weight_ceiling"}
+
+// forwardCandidate 检测连通的转发域名
+type forwardCandidate struct {
+	Forward models.ForwardRecord
+	IP      string   // 后端选出的最佳 IP
+	IPs     []string // 检测连通的全部 IP（最佳 IP 在前）
+	Latency float64  // 平均连接耗时（毫秒），0 表示未知
+}
+
+// selectStrategyLabel 选择策略展示名称
+func selectStrategyLabel(strategy string) string {
+	switch strategy {
+	case "latency":
+		return "延迟最低"
+	case "weight_ceiling":
+		return "权重+延迟上限"
+	default:
+		return "权重优先"
+	}
+}
+
+// publishModeLabel A 记录发布方式展示名称
+func publishModeLabel(mode string) string {
+	if mode == "all" {
+		return "全部健康IP"
+	}
+	return "最佳IP"
+}
+
+// resolverLabel 解析器展示名称
+func resolverLabel(resolver string) string {
+	if resolver == "" {
+		return "后端默认"
+	}
+	return resolver
+}
+
+// healthyAddresses 返回检测连通的全部 IP，最佳 IP 排在第一位；后端未返回逐 IP 结果时只有最佳 IP
+func healthyAddresses(result tcpCheckResponseData) []string {
+	ips := []string{result.TargetIp}
+	for _, a := range result.Addresses {
+		if a.Result && a.IP != result.TargetIp {
+			ips = append(ips, a.IP)
+		}
+	}
+	return ips
+}
+
+// latencyCeiling 返回主域名的延迟上限（毫秒）
+func latencyCeiling(d models.DomainRecord) float64 {
+	if d.LatencyCeiling <= 0 {
+		return 300
+	}
+	return float64(d.LatencyCeiling)
+}
+
+// latencyText 延迟展示文本
+func latencyText(latency float64) string {
+	if latency <= 0 {
+		return "未知"
+	}
+	return fmt.Sprintf("%.1f ms", latency)
+}
+
+// recordForwardLatency 保存转发域名的平均连接耗时，返回该耗时（未返回统计时为 0）；模拟运行时只更新内存
+func recordForwardLatency(f *models.ForwardRecord, result tcpCheckResponseData, dryRun bool) float64 {
+	if result.Latency == nil {
+		return 0
+	}
+	if dryRun {
+		f.LastLatency = result.Latency.Avg
+		return f.LastLatency
+	}
+	_ = operate.UpdateForwardLatency(db.DB, f, result.Latency.Avg)
+	return result.Latency.Avg
+}
+
+// poolScanDone 按选择策略判断找到该连通转发后是否可以停止检测剩余转发域名
+func poolScanDone(d models.DomainRecord, c forwardCandidate) bool {
+	switch d.SelectStrategy {
+	case "latency":
+		// 需要比较全部转发的延迟
+		return false
+	case "weight_ceiling":
+		// 延迟未知时无法判断，视为满足上限
+		return c.Latency <= latencyCeiling(d)
+	default:
+		return true
+	}
+}
+
+// selectForward 按主域名的选择策略从连通的转发中选出一个，candidates 需按权重顺序排列
+func selectForward(d models.DomainRecord, candidates []forwardCandidate) *forwardCandidate {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	switch d.SelectStrategy {
+	case "latency":
+		return lowestLatency(candidates)
+	case "weight_ceiling":
+		for i := range candidates {
+			if candidates[i].Latency <= latencyCeiling(d) {
+				return &candidates[i]
+			}
+		}
+		// 全部超过上限时退化为延迟最低
+		return lowestLatency(candidates)
+	default:
+		return &candidates[0]
+	}
+}
+
+// lowestLatency 返回延迟最低的转发，延迟相同时保持权重顺序，延迟未知的排在最后
+func lowestLatency(candidates []forwardCandidate) *forwardCandidate {
+	sorted := make([]forwardCandidate, len(candidates))
+	copy(sorted, candidates)
+	effective := func(c forwardCandidate) float64 {
+		if c.Latency <= 0 {
+			return math.MaxFloat64
+		}
+		return c.Latency
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return effective(sorted[i]) < effective(sorted[j])
+	})
+	return &sorted[0]
+}
@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"testing"
+
+	"telegram-auto-switch-dns-bot/db/models"
+)
+
+func TestSelectForward(t *testing.T) {
+	candidate := func(domain string, latency float64) forwardCandidate {
+		return forwardCandidate{Forward: models.ForwardRecord{ForwardDomain: domain}, Latency: latency}
+	}
+	pool := []forwardCandidate{candidate("a", 900), candidate("b", 30), candidate("c", 0), candidate("d", 30)}
+
+	tests := []struct {
+		name       string
+		strategy   string
+		ceiling    int
+		candidates []forwardCandidate
+		want       string
+	}{
+		{name: "无候选", strategy: "weight", candidates: nil, want: ""},
+		{name: "权重优先取第一个", strategy: "weight", candidates: pool, want: "a"},
+		{name: "未设置策略按权重优先", strategy: "", candidates: pool, want: "a"},
+		{name: "延迟最低且相同延迟保持权重顺序", strategy: "latency", candidates: pool, want: "b"},
+		{name: "延迟未知排在最后", strategy: "latency", candidates: []forwardCandidate{candidate("x", 0), candidate("y", 500)}, want: "y"},
+		{name: "全部延迟未知取第一个", strategy: "latency", candidates: []forwardCandidate{candidate("x", 0), candidate("y", 0)}, want: "x"},
+		{name: "延迟上限内按权重取第一个", strategy: "weight_ceiling", ceiling: 100, candidates: pool, want: "b"},
+		{name: "默认延迟上限 300ms", strategy: "weight_ceiling", candidates: []forwardCandidate{candidate("x", 310), candidate("y", 290)}, want: "y"},
+		{name: "延迟未知视为满足上限", strategy: "weight_ceiling", ceiling: 100, candidates: []forwardCandidate{candidate("x", 900), candidate("y", 0)}, want: "y"},
+		{name: "全部超过上限时取延迟最低", strategy: "weight_ceiling", ceiling: 10, candidates: []forwardCandidate{candidate("x", 900), candidate("y", 200)}, want: "y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := models.DomainRecord{SelectStrategy: tt.strategy, LatencyCeiling: tt.ceiling}
+			got := selectForward(d, tt.candidates)
+			if tt.want == "" {
+				if got != nil {
+					t.Fatalf("selectForward() = %s, want nil", got.Forward.ForwardDomain)
+				}
+				return
+			}
+			if got == nil || got.Forward.ForwardDomain != tt.want {
+				t.Fatalf("selectForward() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -137,7 +137,7 @@ func UploadDomainsHandler(ctx UpdateContext) {
 			escapeMarkdownV2("/upload_domains <数据>"),
 			"`domain\\|port\\|is\\_disable\\|sort\\_order\\|forward\\_domain\\|ip\\|isp\\|is\\_ban\\|weight\\|forward\\_sort\\|record\\_type`",
 			"`/upload\\_domains main\\.example\\.com\\|80\\|false\\|1\\|forward\\.example\\.com\\|0\\.0\\.0\\.0\\|电信\\|false\\|10\\|1\\|A\nmain\\.example\\.com\\|80\\|false\\|1\\|forward\\.example\\.com\\|0\\.0\\.0\\.0\\|联通\\|false\\|20\\|2\\|A`",
-			escapeMarkdownV2("- DNS ID 会自动从 Cloudflare 获取，请确保域名在 Cloudflare 中已存在\n- 相同的 domain 会自动合并为一个主域名\n- is_disable 和 is_ban 使用 true/false\n- isp 可留空\n- record_type 默认为 A，也可以是 CNAME"))
+			escapeMarkdownV2("- DNS ID 会自动从 Cloudflare 获取，请确保域名在 Cloudflare 中已存在\n- 相同的 domain 会自动合并为一个主域名\n- is_disable 和 is_ban 使用 true/false\n- isp 可留空\n- record_type 默认为 A，也可以是 AAAA 或 CNAME\n- 同一主域名同时包含 A 与 AAAA 转发时自动设为双栈"))
 		return
 	}
 
@@ -167,6 +167,7 @@ func UploadDomainsHandler(ctx UpdateContext) {
 		SendMessage(ctx, 0, false, fmt.Sprintf("❌ 保存失败：\n%v", err))
 	} else {
 		utils.Logger.Infof("批量导入保存成功")
+		scheduler.invalidate()
 		SendMessage(ctx, 0, false,
 			fmt.Sprintf("🎉 批量导入成功！\n\n"+
 				"✅ 已成功导入 %d 条主域名记录\n"+
@@ -0,0 +1,258 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/db"
+	"telegram-auto-switch-dns-bot/db/models"
+	"telegram-auto-switch-dns-bot/db/operate"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// 详情页展示的切换事件与检测结果条数
+const (
+	historySwitchLimit = 10
+	historyCheckLimit  = 10
+)
+
+// checkTrigger 返回检测的触发方式
+func checkTrigger(opts checkOptions) string {
+	if opts.Manual {
+		return "manual"
+	}
+	return "auto"
+}
+
+// triggerLabel 返回触发方式的展示文本
+func triggerLabel(trigger string) string {
+	switch trigger {
+	case "manual":
+		return "手动"
+	case "failback":
+		return "回切"
+	default:
+		return "自动"
+	}
+}
+
+// recordCheckResult 保存一次检测结果，forwardID 为 0 表示主域名检测；err 非 nil 表示检测接口调用失败
+func recordCheckResult(d models.DomainRecord, forwardID uint, target string, family string, result tcpCheckResponseData, err error, trigger string) {
+	r := models.CheckResult{
+		DomainRecordID:  d.ID,
+		ForwardRecordID: forwardID,
+		Target:          target,
+		Family:          family,
+		Trigger:         trigger,
+	}
+	switch {
+	case err != nil:
+		r.Verdict = "error"
+		r.Message = err.Error()
+	case result.Result:
+		r.Verdict = "up"
+	default:
+		r.Verdict = "down"
+	}
+	if err == nil {
+		r.Message = result.Message
+		r.TargetIP = result.TargetIp
+		if result.Latency != nil {
+			r.Latency = result.Latency.Avg
+		}
+	}
+
+	// 多后端时保存各后端结果摘要，单后端时保存后端名称
+	backends := probeBackendList()
+	if summary := verdictSummary(result.Backends); summary != "" {
+		r.Backend = summary
+	} else if len(result.Backends) == 1 {
+		r.Backend = result.Backends[0].Name
+	} else if len(backends) == 1 {
+		r.Backend = backends[0].Name
+	}
+	if runes := []rune(r.Message); len(runes) > 255 {
+		r.Message = string(runes[:255])
+	}
+
+	_ = operate.AddCheckResult(db.DB, &r)
+}
+
+// currentForward 返回主域名 family 地址族当前解析到的转发，未知时返回 nil
+func currentForward(d models.DomainRecord, family string) *models.ForwardRecord {
+	for i := range d.Forwards {
+		f := &d.Forwards[i]
+		if f.ResolveStatus == "success" && forwardServesFamily(d, *f, family) {
+			return f
+		}
+	}
+	return nil
+}
+
+// recordSwitchEvent 保存一次 DNS 切换事件，切换前的转发取自检测开始时的解析状态，记录内容取自更新前的 Cloudflare
+func recordSwitchEvent(d models.DomainRecord, family string, f models.ForwardRecord, sw DomainSwitch, reason string, trigger string) {
+	e := models.SwitchEvent{
+		DomainRecordID:  d.ID,
+		ForwardRecordID: f.ID,
+		Domain:          d.Domain,
+		Port:            d.Port,
+		RecordType:      sw.RecordType,
+		NewForward:      sw.ForwardDomain,
+		NewContent:      sw.NewRecord,
+		OldContent:      sw.OldRecord,
+		Latency:         sw.Latency,
+		Reason:          reason,
+		Trigger:         trigger,
+		Simulated:       sw.Simulated,
+	}
+	if old := currentForward(d, family); old != nil {
+		e.OldForward = old.ForwardDomain
+	}
+
+	_ = operate.AddSwitchEvent(db.DB, &e)
+}
+
+// historyRetention 返回检测结果与切换事件的保留时长
+func historyRetention() (time.Duration, time.Duration) {
+	checkDays := config.Global.AutoCheck.HistoryDays
+	if checkDays <= 0 {
+		checkDays = 7
+	}
+	switchDays := config.Global.AutoCheck.SwitchDays
+	if switchDays <= 0 {
+		switchDays = 90
+	}
+	return time.Duration(checkDays) * 24 * time.Hour, time.Duration(switchDays) * 24 * time.Hour
+}
+
+// StartHistoryPrune 每小时清理超过保留期的检测结果与切换事件
+func StartHistoryPrune() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	pruneHistory()
+	for range ticker.C {
+		pruneHistory()
+	}
+}
+
+// pruneHistory 删除超过保留期的检测结果与切换事件
+func pruneHistory() {
+	if db.DB == nil {
+		return
+	}
+	checkKeep, switchKeep := historyRetention()
+	now := time.Now()
+
+	checks, err := operate.PruneCheckResults(db.DB, now.Add(-checkKeep).Unix())
+	if err != nil {
+		return
+	}
+	switches, err := operate.PruneSwitchEvents(db.DB, now.Add(-switchKeep).Unix())
+	if err != nil {
+		return
+	}
+	if checks > 0 || switches > 0 {
+		utils.Logger.Infof("🧹 已清理 %d 条检测结果、%d 条切换事件", checks, switches)
+	}
+}
+
+// verdictLabel 返回检测结论的展示文本
+func verdictLabel(verdict string) string {
+	switch verdict {
+	case "up":
+		return "✅"
+	case "down":
+		return "❌"
+	default:
+		return "⚠️"
+	}
+}
+
+// showDomainHistory 展示主域名最近的 DNS 切换事件与检测结果
+func showDomainHistory(bot *tgbotapi.BotAPI, chatID int64, messageID int, domainID uint) {
+	idStr := fmt.Sprintf("%d", domainID)
+	kb := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回主域名", "back:domain:"+idStr),
+	))
+
+	var d models.DomainRecord
+	if err := db.DB.Where("id = ?", domainID).First(&d).Error; err != nil {
+		edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, "获取主域名失败："+err.Error(), kb)
+		_, _ = bot.Send(edit)
+		return
+	}
+	events, err := operate.ListSwitchEvents(db.DB, domainID, historySwitchLimit)
+	if err != nil {
+		edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, "获取切换记录失败："+err.Error(), kb)
+		_, _ = bot.Send(edit)
+		return
+	}
+	checks, err := operate.ListCheckResults(db.DB, domainID, historyCheckLimit)
+	if err != nil {
+		edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, "获取检测记录失败："+err.Error(), kb)
+		_, _ = bot.Send(edit)
+		return
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("📜 *切换记录*\n\n主域名: `%s:%d`\n\n", d.Domain, d.Port))
+	writeSwitchEvents(&message, events)
+	writeCheckResults(&message, checks)
+
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, message.String(), kb)
+	edit.ParseMode = "Markdown"
+	_, _ = bot.Send(edit)
+}
+
+// writeSwitchEvents 输出最近的 DNS 切换事件
+func writeSwitchEvents(message *strings.Builder, events []models.SwitchEvent) {
+	message.WriteString("🔀 *最近切换*\n")
+	if len(events) == 0 {
+		message.WriteString("  暂无记录\n\n")
+		return
+	}
+	for _, e := range events {
+		old := e.OldForward
+		if old == "" {
+			old = "未知"
+		}
+		message.WriteString(fmt.Sprintf("  • `%s` %s切换 %s%s\n",
+			time.Unix(e.CreatedAt, 0).Format("01-02 15:04"), triggerLabel(e.Trigger), e.RecordType, simulatedTag(e.Simulated)))
+		message.WriteString(fmt.Sprintf("    `%s` → `%s`\n", old, e.NewForward))
+		if e.OldContent != "" && e.OldContent != e.OldForward {
+			message.WriteString(fmt.Sprintf("    记录: `%s` → `%s`\n", e.OldContent, e.NewContent))
+		} else if e.NewContent != e.NewForward {
+			message.WriteString(fmt.Sprintf("    记录: `%s`\n", e.NewContent))
+		}
+		if e.Reason != "" {
+			message.WriteString(fmt.Sprintf("    原因: %s | 延迟: `%s`\n", escapeMarkdown(e.Reason), latencyText(e.Latency)))
+		}
+	}
+	message.WriteString("\n")
+}
+
+// writeCheckResults 输出最近的主域名检测结果
+func writeCheckResults(message *strings.Builder, checks []models.CheckResult) {
+	message.WriteString("🔍 *最近检测*\n")
+	if len(checks) == 0 {
+		message.WriteString("  暂无记录\n")
+		return
+	}
+	for _, c := range checks {
+		line := fmt.Sprintf("  • `%s` %s %s", time.Unix(c.CreatedAt, 0).Format("01-02 15:04"), triggerLabel(c.Trigger), verdictLabel(c.Verdict))
+		if c.Family == "ipv6" {
+			line += " IPv6"
+		}
+		if c.Verdict == "up" {
+			line += fmt.Sprintf(" 延迟 `%s`", latencyText(c.Latency))
+		}
+		if c.Backend != "" && len(probeBackendList()) > 1 {
+			line += " | " + escapeMarkdown(c.Backend)
+		}
+		message.WriteString(line + "\n")
+	}
+}
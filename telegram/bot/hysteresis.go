@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"telegram-auto-switch-dns-bot/db"
+	"telegram-auto-switch-dns-bot/db/models"
+	"telegram-auto-switch-dns-bot/db/operate"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// DomainStreak 主域名的连续检测状态，用于报告中尚未达到阈值或刚恢复的主域名
+type DomainStreak struct {
+	Target    string // 主域名:端口（双栈时带地址族）
+	Reason    string // 最近一次失败原因，连续成功时为空
+	Streak    int    // 当前连续失败/成功次数
+	Threshold int    // 判定不通/恢复所需次数
+}
+
+// domainHealth 主域名在某个地址族下的连续检测状态
+type domainHealth struct {
+	FailStreak    int
+	SuccessStreak int
+	Down          bool
+}
+
+// downThreshold 返回判定主域名不通所需的连续失败次数，未设置时为 1（首次失败即切换）
+func downThreshold(d models.DomainRecord) int {
+	if d.DownThreshold <= 0 {
+		return 1
+	}
+	return d.DownThreshold
+}
+
+// upThreshold 返回判定主域名恢复所需的连续成功次数，未设置时为 1
+func upThreshold(d models.DomainRecord) int {
+	if d.UpThreshold <= 0 {
+		return 1
+	}
+	return d.UpThreshold
+}
+
+// familyHealth 读取主域名 family 地址族的连续检测状态
+func familyHealth(d models.DomainRecord, family string) domainHealth {
+	if family == "ipv6" {
+		return domainHealth{FailStreak: d.FailStreakV6, SuccessStreak: d.SuccessStreakV6, Down: d.IsDownV6}
+	}
+	return domainHealth{FailStreak: d.FailStreak, SuccessStreak: d.SuccessStreak, Down: d.IsDown}
+}
+
+// setFamilyHealth 设置主域名记录上 family 地址族的连续检测状态
+func setFamilyHealth(d *models.DomainRecord, family string, h domainHealth) {
+	if family == "ipv6" {
+		d.FailStreakV6, d.SuccessStreakV6, d.IsDownV6 = h.FailStreak, h.SuccessStreak, h.Down
+	} else {
+		d.FailStreak, d.SuccessStreak, d.IsDown = h.FailStreak, h.SuccessStreak, h.Down
+	}
+}
+
+// saveFamilyHealth 写回主域名 family 地址族的连续检测状态，只更新该地址族的计数字段，重启后不丢失
+// 模拟运行时保存在进程内，跨检测周期累计但不影响后续真实检测的阈值判断
+func saveFamilyHealth(d *models.DomainRecord, family string, h domainHealth, dryRun bool) {
+	setFamilyHealth(d, family, h)
+	if dryRun {
+		saveDryRunHealth(*d, family, h)
+		return
+	}
+	if err := operate.UpdateDomainHealth(db.DB, d, family == "ipv6"); err != nil {
+		utils.Logger.Warnf("⚠️ 保存主域名 %s 连续检测计数失败: %v", d.Domain, err)
+	}
+}
+
+// recordDomainFailure 记录一次主域名检测失败，返回是否判定不通（需要检测转发池并切换）
+// 已判定不通时任何一次失败都继续切换；手动检测不等待阈值
+func recordDomainFailure(d *models.DomainRecord, family string, reason string, report *CheckReport, opts checkOptions) (bool, int) {
+	h := familyHealth(*d, family)
+	h.FailStreak++
+	h.SuccessStreak = 0
+
+	threshold := downThreshold(*d)
+	if !h.Down && !opts.Manual && h.FailStreak < threshold {
+		saveFamilyHealth(d, family, h, opts.DryRun)
+		utils.Logger.Infof("⏳ 主域名 %s 连续失败 %d/%d 次，暂不切换", domainTarget(*d, family), h.FailStreak, threshold)
+		pending := DomainStreak{
+			Target:    domainTarget(*d, family),
+			Reason:    reason,
+			Streak:    h.FailStreak,
+			Threshold: threshold,
+		}
+		report.add(func() { report.PendingDomains = append(report.PendingDomains, pending) })
+		return false, h.FailStreak
+	}
+
+	h.Down = true
+	saveFamilyHealth(d, family, h, opts.DryRun)
+	return true, h.FailStreak
+}
+
+// recordDomainSuccess 记录一次主域名检测成功，判定不通后连续成功达到阈值时判定恢复
+func recordDomainSuccess(d *models.DomainRecord, family string, report *CheckReport, opts checkOptions) {
+	h := familyHealth(*d, family)
+	h.SuccessStreak++
+	h.FailStreak = 0
+
+	threshold := upThreshold(*d)
+	streak := DomainStreak{Target: domainTarget(*d, family), Streak: h.SuccessStreak, Threshold: threshold}
+	switch {
+	case !h.Down:
+	case h.SuccessStreak >= threshold:
+		h.Down = false
+		utils.Logger.Infof("💚 主域名 %s 连续成功 %d 次，判定已恢复", streak.Target, h.SuccessStreak)
+		report.add(func() { report.RecoveredDomains = append(report.RecoveredDomains, streak) })
+	default:
+		utils.Logger.Infof("⏳ 主域名 %s 连续成功 %d/%d 次，等待确认恢复", streak.Target, h.SuccessStreak, threshold)
+		report.add(func() { report.PendingDomains = append(report.PendingDomains, streak) })
+	}
+	saveFamilyHealth(d, family, h, opts.DryRun)
+}
+
+// thresholdLabel 主域名详情中展示的切换阈值
+func thresholdLabel(d models.DomainRecord) string {
+	return fmt.Sprintf("连续失败 %d 次切换 / 连续成功 %d 次恢复", downThreshold(d), upThreshold(d))
+}
+
+// healthLabel 主域名详情中展示的各地址族连续检测状态
+func healthLabel(d models.DomainRecord) string {
+	var parts []string
+	for _, family := range domainFamilies(d) {
+		h := familyHealth(d, family)
+		state := "正常"
+		if h.Down {
+			state = "不通"
+		}
+		text := fmt.Sprintf("%s 失败 %d / 成功 %d", state, h.FailStreak, h.SuccessStreak)
+		if d.IPFamily == "dual" {
+			text = familyRecordType(family) + " " + text
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, "；")
+}
+
+// parseThresholdInput 解析切换阈值输入：连续失败次数|连续成功次数
+func parseThresholdInput(d *models.DomainRecord, text string) error {
+	parts := strings.Split(text, "|")
+	if len(parts) != 2 {
+		return fmt.Errorf("格式错误，请按 `连续失败次数|连续成功次数` 输入")
+	}
+	down, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+	up, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil || down <= 0 || up <= 0 {
+		return fmt.Errorf("次数必须是正整数")
+	}
+	d.DownThreshold = down
+	d.UpThreshold = up
+	return nil
+}
+
+// writePendingDomains 输出尚未达到切换/恢复阈值的主域名段落
+func writePendingDomains(message *strings.Builder, pending []DomainStreak) {
+	message.WriteString("⏳ *状态待确认*\n")
+	for _, p := range pending {
+		if p.Reason != "" {
+			message.WriteString(fmt.Sprintf("  • `%s` - %s，连续失败 `%d/%d`\n", p.Target, p.Reason, p.Streak, p.Threshold))
+		} else {
+			message.WriteString(fmt.Sprintf("  • `%s` - 恢复中，连续成功 `%d/%d`\n", p.Target, p.Streak, p.Threshold))
+		}
+	}
+	message.WriteString("\n")
+}
+
+// writeRecoveredDomains 输出判定恢复的主域名段落
+func writeRecoveredDomains(message *strings.Builder, recovered []DomainStreak) {
+	message.WriteString("💚 *主域名已恢复*\n")
+	for _, r := range recovered {
+		message.WriteString(fmt.Sprintf("  • `%s` - 连续成功 `%d` 次\n", r.Target, r.Streak))
+	}
+	message.WriteString("\n")
+}
@@ -1,11 +1,8 @@
 package bot
 
 import (
-	"time"
-
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"telegram-auto-switch-dns-bot/cloudflare"
-	"telegram-auto-switch-dns-bot/config"
 	"telegram-auto-switch-dns-bot/utils"
 )
 
@@ -33,8 +30,14 @@ func InitBot(bot *tgbotapi.BotAPI) {
 		}
 	}()
 
-	// 4️⃣ 启动自动检测任务
-	go StartAutoCheck(bot, time.Duration(config.Global.AutoCheck.CheckTime)*time.Minute)
+	// 4️⃣ 启动自动检测任务与历史记录清理
+	go StartAutoCheck(bot)
+	go StartHistoryPrune()
+
+	// 5️⃣ 连接检测后端、接受反向连接并启动状态轮询
+	startBackendSessions()
+	go StartReverseListener()
+	go StartBackendInfoPoll()
 
 	utils.Logger.Infof("Bot 初始化完成")
 }
@@ -154,6 +154,56 @@ func DomainActionsKeyboard(d models.DomainRecord) tgbotapi.InlineKeyboardMarkup
 		tgbotapi.NewInlineKeyboardButtonData(checkText, "dom_toggle_check:"+idStr),
 	))
 
+	// 探测参数按钮随探测类型切换，tcp/tls/icmp 没有可配置的探测参数
+	probeRow := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🧪 探测:"+probeTypeLabel(d.ProbeType), "dom_probe:"+idStr),
+	)
+	switch d.ProbeType {
+	case "http", "https":
+		probeRow = append(probeRow, tgbotapi.NewInlineKeyboardButtonData("🌐 HTTP 探测参数", "dom_edit:"+idStr+":http"))
+	case "udp":
+		probeRow = append(probeRow, tgbotapi.NewInlineKeyboardButtonData("📦 UDP 探测参数", "dom_edit:"+idStr+":udp"))
+	}
+	rows = append(rows, probeRow)
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⚖️ 策略:"+selectStrategyLabel(d.SelectStrategy), "dom_strategy:"+idStr),
+		tgbotapi.NewInlineKeyboardButtonData("📶 延迟上限", "dom_edit:"+idStr+":ceiling"),
+	))
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("📡 A记录发布:"+publishModeLabel(d.PublishMode), "dom_publish:"+idStr),
+		tgbotapi.NewInlineKeyboardButtonData("🧭 解析器", "dom_edit:"+idStr+":resolver"),
+	))
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🌍 地址族:"+ipFamilyLabel(d.IPFamily), "dom_family:"+idStr),
+		tgbotapi.NewInlineKeyboardButtonData("📉 切换阈值", "dom_edit:"+idStr+":threshold"),
+	))
+
+	failbackText := "🔙 回切:关闭"
+	if d.FailbackEnabled {
+		failbackText = "🔙 回切:开启"
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(failbackText, "dom_failback:"+idStr),
+		tgbotapi.NewInlineKeyboardButtonData("⏱ 回切稳定期", "dom_edit:"+idStr+":failback"),
+	))
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🚫 封禁时长", "dom_edit:"+idStr+":ban"),
+		tgbotapi.NewInlineKeyboardButtonData("⏰ 检测计划", "dom_edit:"+idStr+":schedule"),
+	))
+
+	dryRunText := "🧪 模拟:关闭"
+	if d.DryRun {
+		dryRunText = "🧪 模拟:开启"
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(dryRunText, "dom_dryrun:"+idStr),
+		tgbotapi.NewInlineKeyboardButtonData("📜 切换记录", "dom_history:"+idStr),
+	))
+
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("📋 查看转发域名", "dom_forwards:"+idStr),
 		tgbotapi.NewInlineKeyboardButtonData("🗑️ 删除主域名", "dom_delete:"+idStr),
@@ -218,6 +268,7 @@ func ForwardActionsKeyboard(f models.ForwardRecord) tgbotapi.InlineKeyboardMarku
 	// 新增：检测并解析按钮
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("🔍 检测并解析", "fwd_check_resolve:"+idStr),
+		tgbotapi.NewInlineKeyboardButtonData("🛰 路由追踪", "fwd_trace:"+idStr),
 	))
 
 	banText := "🚫 封禁:关闭"
@@ -245,6 +296,7 @@ func ForwardEditTypeKeyboard(forwardID uint) tgbotapi.InlineKeyboardMarkup {
 
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("📝 A 记录", "fwd_edit:value:"+idStr+":type:A"),
+		tgbotapi.NewInlineKeyboardButtonData("📝 AAAA 记录", "fwd_edit:value:"+idStr+":type:AAAA"),
 		tgbotapi.NewInlineKeyboardButtonData("📝 CNAME 记录", "fwd_edit:value:"+idStr+":type:CNAME"),
 	))
 
@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"telegram-auto-switch-dns-bot/db/models"
+)
+
+func TestDomainActionsKeyboardParamButton(t *testing.T) {
+	tests := []struct {
+		probeType string
+		want      string // 探测参数按钮的回调后缀，为空表示没有参数按钮
+	}{
+		{probeType: "", want: ""},
+		{probeType: "tcp", want: ""},
+		{probeType: "http", want: ":http"},
+		{probeType: "https", want: ":http"},
+		{probeType: "tls", want: ""},
+		{probeType: "udp", want: ":udp"},
+		{probeType: "icmp", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.probeType, func(t *testing.T) {
+			kb := DomainActionsKeyboard(models.DomainRecord{ID: 1, ProbeType: tt.probeType})
+			var got []string
+			for _, row := range kb.InlineKeyboard {
+				for _, b := range row {
+					if b.CallbackData == nil {
+						continue
+					}
+					if data := *b.CallbackData; strings.HasSuffix(data, ":http") || strings.HasSuffix(data, ":udp") {
+						got = append(got, strings.TrimPrefix(data, "dom_edit:1"))
+					}
+				}
+			}
+			switch {
+			case tt.want == "" && len(got) > 0:
+				t.Errorf("探测类型 %q 显示了参数按钮 %v", tt.probeType, got)
+			case tt.want != "" && (len(got) != 1 || got[0] != tt.want):
+				t.Errorf("探测类型 %q 参数按钮 = %v, want %s", tt.probeType, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package bot
+
+import (
+	"os"
+	"testing"
+
+	"go.uber.org/zap"
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+func TestMain(m *testing.M) {
+	config.Global = &config.Config{}
+	utils.Logger = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
@@ -0,0 +1,386 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"telegram-auto-switch-dns-bot/db"
+	"telegram-auto-switch-dns-bot/db/models"
+	"telegram-auto-switch-dns-bot/db/operate"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// 维护窗口范围
+const (
+	maintenanceGlobal  = "global"
+	maintenanceDomain  = "domain"
+	maintenanceForward = "forward"
+)
+
+// maintenanceTimeLayout 一次性维护窗口开始时间的输入格式（服务器时区）
+const maintenanceTimeLayout = "2006-01-02 15:04"
+
+// MaintenanceCheck 维护窗口内只观察的主域名检测结果
+type MaintenanceCheck struct {
+	Target  string    // 主域名:端口（双栈时带地址族）
+	Forward string    // 处于维护中的当前转发，主域名本身维护时为空
+	Reason  string    // 检测不通的原因，连通时为空
+	Until   time.Time // 维护窗口结束时间
+}
+
+// windowEnd 返回维护窗口在 now 时是否生效及本次结束时间
+func windowEnd(w models.MaintenanceWindow, now time.Time) (time.Time, bool) {
+	if w.Cron == "" {
+		start, end := time.Unix(w.StartAt, 0), time.Unix(w.EndAt, 0)
+		return end, !now.Before(start) && now.Before(end)
+	}
+	spec, err := parseCron(w.Cron)
+	if err != nil || w.Duration <= 0 {
+		return time.Time{}, false
+	}
+	// 找到 now 之前 Duration 内最早的一次开始时间
+	duration := time.Duration(w.Duration) * time.Second
+	start := spec.next(now.Add(-duration))
+	if start.IsZero() || start.After(now) {
+		return time.Time{}, false
+	}
+	return start.Add(duration), true
+}
+
+// maintenanceSet 检测开始时生效的维护窗口，nil 表示没有生效的窗口
+type maintenanceSet struct {
+	windows []models.MaintenanceWindow
+	ends    []time.Time
+}
+
+// loadMaintenance 读取 now 时生效的维护窗口
+func loadMaintenance(now time.Time) *maintenanceSet {
+	windows, err := operate.ListMaintenanceWindows(db.DB)
+	if err != nil {
+		utils.Logger.Warnf("⚠️ 读取维护窗口失败: %v", err)
+		return nil
+	}
+	set := &maintenanceSet{}
+	for _, w := range windows {
+		if end, ok := windowEnd(w, now); ok {
+			set.windows = append(set.windows, w)
+			set.ends = append(set.ends, end)
+		}
+	}
+	if len(set.windows) == 0 {
+		return nil
+	}
+	return set
+}
+
+// covers 返回覆盖 scope/id 的维护窗口中最晚的结束时间，全局窗口覆盖所有主域名与转发
+func (s *maintenanceSet) covers(scope string, id uint) (time.Time, bool) {
+	if s == nil {
+		return time.Time{}, false
+	}
+	var until time.Time
+	found := false
+	for i, w := range s.windows {
+		if w.Scope == maintenanceGlobal || (w.Scope == scope && w.TargetID == id) {
+			if !found || s.ends[i].After(until) {
+				until = s.ends[i]
+			}
+			found = true
+		}
+	}
+	return until, found
+}
+
+// domain 主域名是否处于维护中
+func (s *maintenanceSet) domain(id uint) (time.Time, bool) {
+	return s.covers(maintenanceDomain, id)
+}
+
+// forward 转发域名是否处于维护中
+func (s *maintenanceSet) forward(id uint) bool {
+	_, ok := s.covers(maintenanceForward, id)
+	return ok
+}
+
+// familyObserved 主域名 family 地址族是否只观察：主域名本身或当前解析到的转发处于维护中
+// 当前转发维护时主域名检测必然失败，不能因此切换，返回维护中的转发域名
+func (s *maintenanceSet) familyObserved(d models.DomainRecord, family string) (time.Time, string, bool) {
+	if until, ok := s.domain(d.ID); ok {
+		return until, "", true
+	}
+	if f := currentForward(d, family); f != nil {
+		if until, ok := s.covers(maintenanceForward, f.ID); ok {
+			return until, f.ForwardDomain, true
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// recordMaintenanceCheck 记录维护窗口内主域名的检测结果，只写日志与报告，不计入连续检测
+func recordMaintenanceCheck(d models.DomainRecord, family string, forward string, until time.Time, result tcpCheckResponseData, report *CheckReport) {
+	check := MaintenanceCheck{Target: domainTarget(d, family), Forward: forward, Until: until}
+	if result.Result {
+		utils.Logger.Infof("🛠 主域名 %s 维护中，连通正常", check.Target)
+	} else {
+		check.Reason = probeFailReason(result.Message)
+		utils.Logger.Warnf("🛠 主域名 %s 维护中，无法连通 (%s)，不切换", check.Target, check.Reason)
+	}
+	report.add(func() { report.MaintenanceDomains = append(report.MaintenanceDomains, check) })
+}
+
+// writeMaintenanceDomains 输出维护中主域名段落
+func writeMaintenanceDomains(message *strings.Builder, checks []MaintenanceCheck) {
+	message.WriteString("🛠 *维护中（仅观察）*\n")
+	for _, c := range checks {
+		state := "连通正常"
+		if c.Reason != "" {
+			state = c.Reason
+		}
+		if c.Forward != "" {
+			state = fmt.Sprintf("当前转发 `%s` 维护中，%s", c.Forward, state)
+		}
+		message.WriteString(fmt.Sprintf("  • `%s` - %s，维护至 `%s`\n", c.Target, state, c.Until.Format("01-02 15:04")))
+	}
+	message.WriteString("\n")
+}
+
+// 暂停自动检测，重启后恢复
+var (
+	pauseMu     sync.Mutex
+	pausedUntil time.Time
+)
+
+// autoCheckPaused 返回自动检测是否处于暂停中及恢复时间
+func autoCheckPaused(now time.Time) (time.Time, bool) {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	return pausedUntil, now.Before(pausedUntil)
+}
+
+// pauseAutoCheck 暂停自动检测 duration
+func pauseAutoCheck(duration time.Duration) time.Time {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	pausedUntil = time.Now().Add(duration)
+	return pausedUntil
+}
+
+// resumeAutoCheck 立即恢复自动检测，返回之前是否处于暂停中
+func resumeAutoCheck() bool {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	paused := time.Now().Before(pausedUntil)
+	pausedUntil = time.Time{}
+	return paused
+}
+
+// commandArgs 返回命令后的参数文本
+func commandArgs(ctx UpdateContext) string {
+	parts := strings.SplitN(strings.TrimSpace(ctx.Update.Message.Text), " ", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
+
+// pauseHandler /pause 命令：暂停整个自动检测循环
+func pauseHandler(ctx UpdateContext) {
+	args := commandArgs(ctx)
+	duration, err := time.ParseDuration(args)
+	if err != nil || duration <= 0 {
+		SendMessage(ctx, ParseModeMarkdown, false,
+			"⏸ *暂停自动检测*\n\n使用方法：`/pause <时长>`\n\n*示例*:\n`/pause 30m`\n`/pause 2h`\n\n暂停期间不执行任何自动检测，到期或 `/resume` 后恢复；重启机器人后暂停失效")
+		return
+	}
+	until := pauseAutoCheck(duration)
+	utils.Logger.Infof("⏸ 用户 %d 暂停自动检测 %s，至 %s", ctx.UserID, duration, until.Format("2006-01-02 15:04:05"))
+	SendMessage(ctx, ParseModeMarkdown, false, "⏸ 自动检测已暂停 `%s`，将于 `%s` 恢复", durationText(duration), until.Format("2006-01-02 15:04:05"))
+}
+
+// resumeHandler /resume 命令：立即恢复自动检测
+func resumeHandler(ctx UpdateContext) {
+	if !resumeAutoCheck() {
+		SendMessage(ctx, 0, false, "▶️ 自动检测未处于暂停状态")
+		return
+	}
+	utils.Logger.Infof("▶️ 用户 %d 恢复自动检测", ctx.UserID)
+	SendMessage(ctx, 0, false, "▶️ 自动检测已恢复，到期的主域名将立即检测")
+}
+
+// parseMaintenanceInput 解析维护窗口：范围|目标ID|开始|时长|备注
+// 开始为 now、YYYY-MM-DD HH:MM（一次性）或 5 段 cron 表达式（周期）
+func parseMaintenanceInput(text string) (models.MaintenanceWindow, error) {
+	var w models.MaintenanceWindow
+	parts := strings.Split(text, "|")
+	if len(parts) < 4 || len(parts) > 5 {
+		return w, fmt.Errorf("格式错误，请按 `范围|目标ID|开始|时长|备注` 输入")
+	}
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	w.Scope = parts[0]
+	switch w.Scope {
+	case maintenanceGlobal:
+	case maintenanceDomain, maintenanceForward:
+		id, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil || id == 0 {
+			return w, fmt.Errorf("目标ID必须是正整数")
+		}
+		w.TargetID = uint(id)
+		if err := checkMaintenanceTarget(w); err != nil {
+			return w, err
+		}
+	default:
+		return w, fmt.Errorf("范围只能是 global、domain 或 forward")
+	}
+
+	duration, err := time.ParseDuration(parts[3])
+	if err != nil || duration < time.Minute {
+		return w, fmt.Errorf("时长至少 1m，如 30m、2h")
+	}
+
+	switch start := parts[2]; {
+	case start == "now":
+		w.StartAt = time.Now().Unix()
+		w.EndAt = time.Now().Add(duration).Unix()
+	case len(strings.Fields(start)) == 5:
+		if _, err := parseCron(start); err != nil {
+			return w, err
+		}
+		w.Cron = strings.Join(strings.Fields(start), " ")
+		w.Duration = int(duration.Seconds())
+	default:
+		t, err := time.ParseInLocation(maintenanceTimeLayout, start, time.Local)
+		if err != nil {
+			return w, fmt.Errorf("无法识别的开始时间: %s", start)
+		}
+		w.StartAt = t.Unix()
+		w.EndAt = t.Add(duration).Unix()
+	}
+	if len(parts) == 5 {
+		w.Note = parts[4]
+	}
+	return w, nil
+}
+
+// checkMaintenanceTarget 检查维护窗口的目标主域名或转发域名是否存在
+func checkMaintenanceTarget(w models.MaintenanceWindow) error {
+	var count int64
+	var err error
+	if w.Scope == maintenanceDomain {
+		err = db.DB.Model(&models.DomainRecord{}).Where("id = ?", w.TargetID).Count(&count).Error
+	} else {
+		err = db.DB.Model(&models.ForwardRecord{}).Where("id = ?", w.TargetID).Count(&count).Error
+	}
+	if err != nil {
+		return fmt.Errorf("查询目标失败: %v", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%s ID=%d 不存在", w.Scope, w.TargetID)
+	}
+	return nil
+}
+
+// maintenanceLabel 维护窗口列表中的一行
+func maintenanceLabel(w models.MaintenanceWindow, now time.Time) string {
+	target := "全局"
+	switch w.Scope {
+	case maintenanceDomain:
+		target = fmt.Sprintf("主域名 #%d", w.TargetID)
+	case maintenanceForward:
+		target = fmt.Sprintf("转发 #%d", w.TargetID)
+	}
+
+	var when string
+	if w.Cron != "" {
+		when = fmt.Sprintf("cron `%s` 每次 %s", w.Cron, durationText(time.Duration(w.Duration)*time.Second))
+	} else {
+		when = fmt.Sprintf("`%s` ~ `%s`", time.Unix(w.StartAt, 0).Format(maintenanceTimeLayout), time.Unix(w.EndAt, 0).Format(maintenanceTimeLayout))
+	}
+
+	state := ""
+	if end, ok := windowEnd(w, now); ok {
+		state = fmt.Sprintf(" 🛠 生效中至 `%s`", end.Format("01-02 15:04"))
+	} else if w.Cron == "" && now.Unix() >= w.EndAt {
+		state = " (已结束)"
+	}
+
+	label := fmt.Sprintf("`#%d` %s | %s%s", w.ID, target, when, state)
+	if w.Note != "" {
+		label += "\n      " + escapeMarkdown(w.Note)
+	}
+	return label
+}
+
+// maintenanceHandler /maintenance 命令：查看、添加与删除维护窗口
+func maintenanceHandler(ctx UpdateContext) {
+	args := commandArgs(ctx)
+	action, rest, _ := strings.Cut(args, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch action {
+	case "add":
+		w, err := parseMaintenanceInput(rest)
+		if err != nil {
+			SendMessage(ctx, ParseModeMarkdown, false, "❌ %s", escapeMarkdown(err.Error()))
+			return
+		}
+		w.CreatedBy = ctx.UserID
+		if err := operate.AddMaintenanceWindow(db.DB, &w); err != nil {
+			SendMessage(ctx, 0, false, "❌ 添加维护窗口失败: %v", err)
+			return
+		}
+		SendMessage(ctx, ParseModeMarkdown, false, "✅ 已添加维护窗口\n\n%s", maintenanceLabel(w, time.Now()))
+	case "del":
+		id, err := strconv.ParseUint(rest, 10, 64)
+		if err != nil {
+			SendMessage(ctx, ParseModeMarkdown, false, "❌ 使用方法：`/maintenance del <ID>`")
+			return
+		}
+		if err := operate.DeleteMaintenanceWindow(db.DB, uint(id)); err != nil {
+			SendMessage(ctx, 0, false, "❌ 删除维护窗口失败: %v", err)
+			return
+		}
+		SendMessage(ctx, 0, false, "✅ 已删除维护窗口 #%d", id)
+	default:
+		SendMessage(ctx, ParseModeMarkdown, true, "%s", formatMaintenanceWindows())
+	}
+}
+
+// formatMaintenanceWindows 生成维护窗口列表与使用说明
+func formatMaintenanceWindows() string {
+	now := time.Now()
+	var sb strings.Builder
+	sb.WriteString("🛠 *维护窗口*\n\n")
+	if until, paused := autoCheckPaused(now); paused {
+		sb.WriteString(fmt.Sprintf("⏸ 自动检测已暂停至 `%s`\n\n", until.Format("2006-01-02 15:04:05")))
+	}
+
+	windows, err := operate.ListMaintenanceWindows(db.DB)
+	switch {
+	case err != nil:
+		sb.WriteString(fmt.Sprintf("❌ 读取失败: %s\n", escapeMarkdown(err.Error())))
+	case len(windows) == 0:
+		sb.WriteString("暂无维护窗口\n")
+	default:
+		for _, w := range windows {
+			sb.WriteString("  • " + maintenanceLabel(w, now) + "\n")
+		}
+	}
+
+	sb.WriteString("\n*添加*: `/maintenance add 范围|目标ID|开始|时长|备注`\n" +
+		"*示例*:\n" +
+		"`/maintenance add domain|3|2026-10-18 02:00|2h|机房割接`\n" +
+		"`/maintenance add forward|12|0 3 * * 0|1h|每周日例行维护`\n" +
+		"`/maintenance add global||now|30m`\n" +
+		"*删除*: `/maintenance del <ID>`\n\n" +
+		"*说明*:\n" +
+		"- 范围: global（全部）、domain（主域名 ID）、forward（转发 ID）\n" +
+		"- 开始: now、`YYYY-MM-DD HH:MM` 或 5 段 cron 表达式（周期窗口）\n" +
+		"- 窗口内照常检测，但不修改 DNS、不封禁转发，结果单独列出不触发告警")
+	return sb.String()
+}
@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"telegram-auto-switch-dns-bot/db/models"
+)
+
+func TestWindowEnd(t *testing.T) {
+	now := time.Date(2026, 10, 17, 3, 20, 0, 0, time.Local)
+	at := func(hour, min int) time.Time { return time.Date(2026, 10, 17, hour, min, 0, 0, time.Local) }
+
+	tests := []struct {
+		name    string
+		w       models.MaintenanceWindow
+		want    time.Time
+		wantHit bool
+	}{
+		{name: "一次性窗口生效", w: models.MaintenanceWindow{StartAt: at(3, 0).Unix(), EndAt: at(4, 0).Unix()}, want: at(4, 0), wantHit: true},
+		{name: "一次性窗口未开始", w: models.MaintenanceWindow{StartAt: at(3, 30).Unix(), EndAt: at(4, 0).Unix()}},
+		{name: "一次性窗口已结束", w: models.MaintenanceWindow{StartAt: at(2, 0).Unix(), EndAt: at(3, 20).Unix()}},
+		{name: "周期窗口生效", w: models.MaintenanceWindow{Cron: "0 3 * * *", Duration: 3600}, want: at(4, 0), wantHit: true},
+		{name: "周期窗口在开始时刻生效", w: models.MaintenanceWindow{Cron: "20 3 * * *", Duration: 600}, want: at(3, 30), wantHit: true},
+		{name: "周期窗口已结束", w: models.MaintenanceWindow{Cron: "0 3 * * *", Duration: 600}},
+		{name: "周期窗口未开始", w: models.MaintenanceWindow{Cron: "0 4 * * *", Duration: 3600}},
+		{name: "重叠的周期取最早开始", w: models.MaintenanceWindow{Cron: "*/10 * * * *", Duration: 1800}, want: at(3, 30), wantHit: true},
+		{name: "无效 cron", w: models.MaintenanceWindow{Cron: "bad", Duration: 3600}},
+		{name: "周期窗口缺少时长", w: models.MaintenanceWindow{Cron: "0 3 * * *"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := windowEnd(tt.w, now)
+			if ok != tt.wantHit {
+				t.Fatalf("windowEnd() active = %v, want %v", ok, tt.wantHit)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("windowEnd() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMaintenanceInput(t *testing.T) {
+	start := time.Date(2026, 10, 18, 2, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    models.MaintenanceWindow
+		wantErr bool
+	}{
+		{name: "一次性窗口", input: "global|0|2026-10-18 02:00|2h|机房迁移",
+			want: models.MaintenanceWindow{Scope: maintenanceGlobal, StartAt: start.Unix(), EndAt: start.Add(2 * time.Hour).Unix(), Note: "机房迁移"}},
+		{name: "周期窗口规范化空白", input: " global | 0 | 0  3 * * 1 | 30m ",
+			want: models.MaintenanceWindow{Scope: maintenanceGlobal, Cron: "0 3 * * 1", Duration: 1800}},
+		{name: "字段过少", input: "global|0|now", wantErr: true},
+		{name: "字段过多", input: "global|0|now|1h|备注|多余", wantErr: true},
+		{name: "未知范围", input: "zone|1|now|1h", wantErr: true},
+		{name: "目标ID不是数字", input: "domain|abc|now|1h", wantErr: true},
+		{name: "目标ID为 0", input: "forward|0|now|1h", wantErr: true},
+		{name: "时长过短", input: "global|0|now|30s", wantErr: true},
+		{name: "无效时长", input: "global|0|now|abc", wantErr: true},
+		{name: "无效 cron", input: "global|0|61 3 * * *|1h", wantErr: true},
+		{name: "无效开始时间", input: "global|0|tomorrow|1h", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMaintenanceInput(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMaintenanceInput(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseMaintenanceInput(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("立即开始", func(t *testing.T) {
+		before := time.Now().Unix()
+		w, err := parseMaintenanceInput("global|0|now|1h")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.StartAt < before || w.EndAt-w.StartAt != 3600 {
+			t.Errorf("parseMaintenanceInput(now) = %+v, want a 1h window starting now", w)
+		}
+	})
+}
+
+func TestMaintenanceCovers(t *testing.T) {
+	until := time.Date(2026, 10, 17, 4, 0, 0, 0, time.UTC)
+	later := until.Add(time.Hour)
+
+	var none *maintenanceSet
+	if _, ok := none.domain(1); ok {
+		t.Error("nil set covers domain 1")
+	}
+
+	s := &maintenanceSet{
+		windows: []models.MaintenanceWindow{{Scope: maintenanceDomain, TargetID: 1}, {Scope: maintenanceForward, TargetID: 10}},
+		ends:    []time.Time{until, until},
+	}
+	tests := []struct {
+		name  string
+		scope string
+		id    uint
+		want  bool
+	}{
+		{name: "主域名维护", scope: maintenanceDomain, id: 1, want: true},
+		{name: "其他主域名", scope: maintenanceDomain, id: 2, want: false},
+		{name: "转发维护", scope: maintenanceForward, id: 10, want: true},
+		{name: "同 ID 不同范围", scope: maintenanceForward, id: 1, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := s.covers(tt.scope, tt.id)
+			if ok != tt.want {
+				t.Fatalf("covers(%s, %d) = %v, want %v", tt.scope, tt.id, ok, tt.want)
+			}
+			if ok && !got.Equal(until) {
+				t.Errorf("covers(%s, %d) until = %s, want %s", tt.scope, tt.id, got, until)
+			}
+		})
+	}
+
+	// 全局窗口覆盖所有目标，多个窗口覆盖时取最晚的结束时间
+	s.windows = append(s.windows, models.MaintenanceWindow{Scope: maintenanceGlobal})
+	s.ends = append(s.ends, later)
+	if got, ok := s.domain(1); !ok || !got.Equal(later) {
+		t.Errorf("domain(1) = (%s, %v), want (%s, true)", got, ok, later)
+	}
+	if !s.forward(99) {
+		t.Error("forward(99) = false under a global window")
+	}
+}
+
+func TestMaintenanceFamilyObserved(t *testing.T) {
+	until := time.Date(2026, 10, 17, 4, 0, 0, 0, time.UTC)
+	d := models.DomainRecord{ID: 1, IPFamily: "dual", Forwards: []models.ForwardRecord{
+		{ID: 10, ForwardDomain: "v4-old.example.com", RecordType: "A"},
+		{ID: 11, ForwardDomain: "v4.example.com", RecordType: "A", ResolveStatus: "success"},
+		{ID: 12, ForwardDomain: "v6.example.com", RecordType: "AAAA", ResolveStatus: "success"},
+	}}
+	set := func(windows ...models.MaintenanceWindow) *maintenanceSet {
+		s := &maintenanceSet{windows: windows}
+		for range windows {
+			s.ends = append(s.ends, until)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name        string
+		set         *maintenanceSet
+		family      string
+		wantForward string
+		wantHit     bool
+	}{
+		{name: "没有维护窗口", set: nil, family: "ipv4"},
+		{name: "主域名维护", set: set(models.MaintenanceWindow{Scope: maintenanceDomain, TargetID: 1}), family: "ipv4", wantHit: true},
+		{name: "全局维护", set: set(models.MaintenanceWindow{Scope: maintenanceGlobal}), family: "ipv6", wantHit: true},
+		{name: "当前转发维护", set: set(models.MaintenanceWindow{Scope: maintenanceForward, TargetID: 11}), family: "ipv4", wantForward: "v4.example.com", wantHit: true},
+		{name: "其他地址族的当前转发维护", set: set(models.MaintenanceWindow{Scope: maintenanceForward, TargetID: 12}), family: "ipv4"},
+		{name: "未发布的转发维护", set: set(models.MaintenanceWindow{Scope: maintenanceForward, TargetID: 10}), family: "ipv4"},
+		{name: "其他主域名维护", set: set(models.MaintenanceWindow{Scope: maintenanceDomain, TargetID: 2}), family: "ipv4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, forward, ok := tt.set.familyObserved(d, tt.family)
+			if ok != tt.wantHit || forward != tt.wantForward {
+				t.Fatalf("familyObserved() = (%q, %v), want (%q, %v)", forward, ok, tt.wantForward, tt.wantHit)
+			}
+			if ok && !got.Equal(until) {
+				t.Errorf("familyObserved() until = %s, want %s", got, until)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/middleware"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+var reverseUpgrader = websocket.Upgrader{
+	HandshakeTimeout: 10 * time.Second,
+	ReadBufferSize:   4096,
+	WriteBufferSize:  4096,
+}
+
+// reverseEndpoint 按名称查找配置为反向连接的检测后端
+func reverseEndpoint(name string) (config.BackendEndpoint, bool) {
+	for _, endpoint := range probeBackendList() {
+		if endpoint.Reverse && endpoint.Name == name {
+			return endpoint, true
+		}
+	}
+	return config.BackendEndpoint{}, false
+}
+
+// endpointKey 返回反向连接后端注册使用的通信密钥
+func endpointKey(endpoint config.BackendEndpoint) string {
+	if endpoint.Key != "" {
+		return endpoint.Key
+	}
+	return config.Global.BackendListen.Key
+}
+
+// reverseHandler 接受反向连接后端的注册（GET /api/v1/reverse/{name}），校验签名后挂载为该后端的任务通道
+func reverseHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	endpoint, ok := reverseEndpoint(name)
+	if !ok {
+		utils.Logger.Warnf("⚠️ 未知的反向连接后端 %q (%s)", name, r.RemoteAddr)
+		http.Error(w, "unknown backend", http.StatusNotFound)
+		return
+	}
+	if err := middleware.VerifySignedRequest(r, nil, endpointKey(endpoint)); err != nil {
+		utils.Logger.Warnf("⚠️ 反向连接后端 %s 签名校验失败 (%s): %v", name, r.RemoteAddr, err)
+		http.Error(w, "signature verification failed", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := reverseUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		utils.Logger.Warnf("⚠️ 反向连接后端 %s WebSocket 升级失败: %v", name, err)
+		return
+	}
+	utils.Logger.Infof("🔌 反向连接后端 %s 已注册 (%s)", name, r.RemoteAddr)
+	err = backendSession(endpoint).serve(conn)
+	utils.Logger.Warnf("⚠️ 反向连接后端 %s 已断开: %v", name, err)
+}
+
+// StartReverseListener 监听 reverse_listen，接受 NAT 后检测后端的反向连接
+func StartReverseListener() {
+	bc := config.Global.BackendURL
+	if bc.ReverseListen == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/reverse/{name}", reverseHandler)
+	srv := &http.Server{
+		Addr:              bc.ReverseListen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	var err error
+	if bc.ReverseCertFile != "" && bc.ReverseKeyFile != "" {
+		utils.Logger.Infof("反向连接正在监听 (HTTPS): %s", srv.Addr)
+		err = srv.ListenAndServeTLS(bc.ReverseCertFile, bc.ReverseKeyFile)
+	} else {
+		utils.Logger.Infof("反向连接正在监听: %s", srv.Addr)
+		err = srv.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		utils.Logger.Error("反向连接监听启动失败:", err)
+	}
+}
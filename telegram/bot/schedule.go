@@ -0,0 +1,466 @@
+package bot
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/db"
+	"telegram-auto-switch-dns-bot/db/models"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// defaultCheckInterval 未配置 auto_check.check_time 时的检测间隔
+const defaultCheckInterval = 6 * time.Minute
+
+// minCheckInterval 主域名允许设置的最短检测间隔
+const minCheckInterval = 10 * time.Second
+
+// schedulerTick 调度器检查到期主域名的间隔
+const schedulerTick = time.Second
+
+// scheduleRefresh 未收到变更通知时重新读取检测计划的间隔（兜底数据库被直接修改的情况）
+const scheduleRefresh = time.Minute
+
+// cronSpec 解析后的 cron 表达式（分 时 日 月 周），每个字段为允许取值的位图
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool // 日/周字段为 * 时，另一字段单独决定日期
+}
+
+// cronFieldRange cron 各字段的取值范围
+var cronFieldRange = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// parseCronField 解析单个 cron 字段，支持 *、数字、a-b、*/n、a-b/n 与逗号列表
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("无效的步长: %s", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			a, err1 := strconv.Atoi(bounds[0])
+			b, err2 := strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil || a > b {
+				return 0, fmt.Errorf("无效的范围: %s", part)
+			}
+			lo, hi = a, b
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("无效的取值: %s", part)
+			}
+			lo = n
+			// 只有带步长时单个数字才表示起点，如 5/15
+			if step == 1 {
+				hi = n
+			}
+		}
+		if lo < min || hi > max {
+			return 0, fmt.Errorf("取值超出范围 %d-%d: %s", min, max, part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// parseCron 解析 5 段 cron 表达式
+func parseCron(expr string) (*cronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron 表达式需要 5 段（分 时 日 月 周）: %s", expr)
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFieldRange[i][0], cronFieldRange[i][1])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+	}
+	// 周日可写作 0 或 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &cronSpec{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+// dayMatches 日与周字段都有限制时满足其一即可（与标准 cron 一致）
+func (c *cronSpec) dayMatches(t time.Time) bool {
+	domOK := c.dom&(1<<uint(t.Day())) != 0
+	dowOK := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dowOK
+	case c.dowAny:
+		return domOK
+	}
+	return domOK || dowOK
+}
+
+// next 返回 t 之后第一个匹配的整分钟，5 年内无匹配时返回零值
+func (c *cronSpec) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		switch {
+		case c.month&(1<<uint(m)) == 0:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// globalCheckInterval 返回全局 auto_check.check_time 检测间隔
+func globalCheckInterval() time.Duration {
+	if config.Global.AutoCheck.CheckTime <= 0 {
+		return defaultCheckInterval
+	}
+	return time.Duration(config.Global.AutoCheck.CheckTime) * time.Minute
+}
+
+// nextCheckTime 按主域名的检测计划计算 from 之后的下一次检测时间（含随机推迟）
+func nextCheckTime(d models.DomainRecord, from time.Time) time.Time {
+	var next time.Time
+	if d.CheckCron != "" {
+		if spec, err := parseCron(d.CheckCron); err == nil {
+			next = spec.next(from)
+		} else {
+			utils.Logger.Warnf("⚠️ 主域名 %s 的 cron 表达式无效: %v，使用检测间隔", d.Domain, err)
+		}
+	}
+	if next.IsZero() {
+		interval := globalCheckInterval()
+		if d.CheckInterval > 0 {
+			interval = time.Duration(d.CheckInterval) * time.Second
+		}
+		next = from.Add(interval)
+	}
+	if d.CheckJitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(d.CheckJitter)*int64(time.Second) + 1)))
+	}
+	return next
+}
+
+// scheduleKey 检测计划的标识，管理员修改计划后调度器据此重新计算下一次检测时间
+func scheduleKey(d models.DomainRecord) string {
+	return fmt.Sprintf("%d|%s|%d", d.CheckInterval, d.CheckCron, d.CheckJitter)
+}
+
+// scheduleEntry 调度器中单个主域名的状态
+type scheduleEntry struct {
+	key  string
+	next time.Time
+}
+
+// checkScheduler 按每个主域名各自的下一次检测时间触发自动检测
+type checkScheduler struct {
+	mu      sync.Mutex
+	entries map[uint]*scheduleEntry
+	running map[uint]bool // 检测中的主域名（自动或手动），结束前不会再次检测
+
+	// 缓存的检测计划，管理员修改主域名后失效
+	schedules []models.DomainRecord
+	loadedAt  time.Time
+	stale     bool
+}
+
+// invalidate 主域名新增、删除或修改后调用，下一次轮询时重新读取检测计划
+func (s *checkScheduler) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stale = true
+}
+
+// loadSchedules 返回缓存的检测计划，缓存失效或超过 scheduleRefresh 时从数据库重新读取
+func (s *checkScheduler) loadSchedules(now time.Time) ([]models.DomainRecord, error) {
+	s.mu.Lock()
+	if !s.stale && !s.loadedAt.IsZero() && now.Sub(s.loadedAt) < scheduleRefresh {
+		defer s.mu.Unlock()
+		return s.schedules, nil
+	}
+	// 读取前清除失效标记，读取期间的修改会在下一次轮询时重新读取
+	s.stale = false
+	s.mu.Unlock()
+
+	// 关闭 SQL 日志避免定时刷新刷屏
+	var domains []models.DomainRecord
+	quiet := db.DB.Session(&gorm.Session{Logger: db.DB.Logger.LogMode(logger.Silent)})
+	if err := quiet.Select("id", "domain", "port", "is_disable_check", "check_interval", "check_cron", "check_jitter").
+		Find(&domains).Error; err != nil {
+		s.invalidate()
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules = domains
+	s.loadedAt = now
+	return domains, nil
+}
+
+// dueDomains 刷新调度表并返回已到期且未在检测中的主域名 ID，返回的主域名标记为检测中
+func (s *checkScheduler) dueDomains(domains []models.DomainRecord, now time.Time) []uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[uint]bool, len(domains))
+	var due []uint
+	for _, d := range domains {
+		if d.IsDisableCheck {
+			continue
+		}
+		seen[d.ID] = true
+		key := scheduleKey(d)
+		e, ok := s.entries[d.ID]
+		if !ok {
+			// 新主域名：按间隔检测的立即检测（带随机推迟），cron 等到下一个匹配时间
+			e = &scheduleEntry{key: key, next: now}
+			if d.CheckCron != "" {
+				e.next = nextCheckTime(d, now)
+			} else if d.CheckJitter > 0 {
+				e.next = now.Add(time.Duration(rand.Int63n(int64(d.CheckJitter)*int64(time.Second) + 1)))
+			}
+			s.entries[d.ID] = e
+		} else if e.key != key {
+			e.key = key
+			e.next = nextCheckTime(d, now)
+			utils.Logger.Infof("⏰ 主域名 %s:%d 检测计划已变更，下次检测: %s", d.Domain, d.Port, e.next.Format("2006-01-02 15:04:05"))
+		}
+		if s.running[d.ID] || now.Before(e.next) {
+			continue
+		}
+		s.running[d.ID] = true
+		due = append(due, d.ID)
+	}
+	// 移除已删除或已禁用检测的主域名
+	for id := range s.entries {
+		if !seen[id] {
+			delete(s.entries, id)
+		}
+	}
+	return due
+}
+
+// finish 主域名检测结束后计算下一次检测时间
+func (s *checkScheduler) finish(d models.DomainRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, d.ID)
+	if e, ok := s.entries[d.ID]; ok {
+		e.next = nextCheckTime(d, time.Now())
+	}
+}
+
+// claim 手动检测前标记主域名为检测中，主域名正在自动检测时返回 false
+func (s *checkScheduler) claim(id uint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[id] {
+		return false
+	}
+	s.running[id] = true
+	return true
+}
+
+// release 手动检测结束后解除检测中标记，不改变下一次自动检测时间
+func (s *checkScheduler) release(id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, id)
+}
+
+// nextCheckAt 返回主域名在调度器中的下一次检测时间，未调度时返回零值
+func (s *checkScheduler) nextCheckAt(id uint) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[id]; ok {
+		return e.next
+	}
+	return time.Time{}
+}
+
+// scheduler 自动检测调度器
+var scheduler = &checkScheduler{entries: make(map[uint]*scheduleEntry), running: make(map[uint]bool)}
+
+// checkWindow 一个全局检测周期，周期内到期的主域名共用一份报告，周期结束且检测全部完成后发送
+type checkWindow struct {
+	start  time.Time
+	report *CheckReport
+	count  int // 周期内派发的检测次数
+	wg     sync.WaitGroup
+}
+
+// newCheckWindow 从 start 开始一个新的检测周期
+func newCheckWindow(start time.Time) *checkWindow {
+	return &checkWindow{start: start, report: newCheckReport(config.Global.AutoCheck.DryRun)}
+}
+
+// done 周期内的一次检测结束
+func (w *checkWindow) done() {
+	w.wg.Done()
+}
+
+// close 结束检测周期，等待周期内的检测全部完成后发送汇总报告
+func (w *checkWindow) close(bot *tgbotapi.BotAPI) {
+	if w.count == 0 {
+		return
+	}
+	go func() {
+		w.wg.Wait()
+		utils.Logger.Infof("✅ 检测周期完成（%s 起，共检测 %d 次），耗时 %s", w.start.Format("15:04:05"), w.count, time.Since(w.start).Round(time.Second))
+		sendReport(bot, w.report)
+	}()
+}
+
+// checkJob 派发给检测 worker 的单个主域名
+type checkJob struct {
+	DomainID uint
+	Window   *checkWindow
+}
+
+// runScheduler 每秒检查一次到期的主域名并派发给固定数量的检测 worker
+// 每个全局检测间隔为一个检测周期，周期内所有主域名的检测结果汇总为一份报告
+func runScheduler(bot *tgbotapi.BotAPI) {
+	jobs := make(chan checkJob)
+	for i := 0; i < checkWorkers(); i++ {
+		go runCheckWorker(jobs)
+	}
+
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	window := newCheckWindow(time.Now())
+	// 等待空闲 worker 的到期主域名，按到期顺序派发
+	var pending []checkJob
+	for {
+		var send chan<- checkJob
+		var next checkJob
+		if len(pending) > 0 {
+			send, next = jobs, pending[0]
+		}
+
+		select {
+		case send <- next:
+			pending = pending[1:]
+			continue
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		if now.Sub(window.start) >= globalCheckInterval() {
+			window.close(bot)
+			window = newCheckWindow(now)
+		}
+
+		if db.DB == nil {
+			if err := db.InitDB(); err != nil {
+				utils.Logger.Errorf("❌ 数据库初始化失败: %v", err)
+				continue
+			}
+		}
+
+		domains, err := scheduler.loadSchedules(now)
+		if err != nil {
+			utils.Logger.Errorf("❌ 获取主域名检测计划失败: %v", err)
+			continue
+		}
+
+		// 暂停期间不派发检测，到期的主域名在恢复后立即检测
+		if _, paused := autoCheckPaused(now); paused {
+			continue
+		}
+
+		for _, id := range scheduler.dueDomains(domains, now) {
+			window.count++
+			window.wg.Add(1)
+			pending = append(pending, checkJob{DomainID: id, Window: window})
+		}
+	}
+}
+
+// scheduleLabel 主域名详情中展示的检测计划
+func scheduleLabel(d models.DomainRecord) string {
+	var label string
+	switch {
+	case d.CheckCron != "":
+		label = "cron " + d.CheckCron
+	case d.CheckInterval > 0:
+		label = "每 " + durationText(time.Duration(d.CheckInterval)*time.Second)
+	default:
+		label = "每 " + durationText(globalCheckInterval()) + "（全局）"
+	}
+	if d.CheckJitter > 0 {
+		label += fmt.Sprintf("，随机推迟 ≤ %ds", d.CheckJitter)
+	}
+	if next := scheduler.nextCheckAt(d.ID); !next.IsZero() {
+		label += "，下次 " + next.Format("01-02 15:04:05")
+	}
+	return label
+}
+
+// parseScheduleInput 解析检测计划输入：间隔（如 30s、5m）或 5 段 cron 表达式，可用 | 附加随机推迟上限；- 表示使用全局间隔
+func parseScheduleInput(d *models.DomainRecord, text string) error {
+	if text == "-" {
+		d.CheckInterval, d.CheckCron, d.CheckJitter = 0, "", 0
+		return nil
+	}
+
+	spec, jitterText, hasJitter := strings.Cut(text, "|")
+	spec = strings.TrimSpace(spec)
+	jitter := 0
+	if hasJitter {
+		dur, err := time.ParseDuration(strings.TrimSpace(jitterText))
+		if err != nil || dur < 0 {
+			return fmt.Errorf("无效的随机推迟: %s", jitterText)
+		}
+		jitter = int(dur.Seconds())
+	}
+
+	if interval, err := time.ParseDuration(spec); err == nil {
+		if interval < minCheckInterval {
+			return fmt.Errorf("检测间隔不能小于 %s", minCheckInterval)
+		}
+		d.CheckInterval, d.CheckCron, d.CheckJitter = int(interval.Seconds()), "", jitter
+		return nil
+	}
+	if _, err := parseCron(spec); err != nil {
+		return fmt.Errorf("无法识别的检测计划: %v", err)
+	}
+	d.CheckInterval, d.CheckCron, d.CheckJitter = 0, strings.Join(strings.Fields(spec), " "), jitter
+	return nil
+}
@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"telegram-auto-switch-dns-bot/db/models"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{expr: "* * * * *"},
+		{expr: "*/15 0-6,22-23 1,15 * 1-5"},
+		{expr: "5/15 * * * 7"},
+		{expr: "* * * *", wantErr: true},
+		{expr: "* * * * * *", wantErr: true},
+		{expr: "60 * * * *", wantErr: true},
+		{expr: "* 24 * * *", wantErr: true},
+		{expr: "* * 0 * *", wantErr: true},
+		{expr: "* * * 13 *", wantErr: true},
+		{expr: "* * * * 8", wantErr: true},
+		{expr: "*/0 * * * *", wantErr: true},
+		{expr: "5-1 * * * *", wantErr: true},
+		{expr: "a * * * *", wantErr: true},
+	}
+	for _, tt := range tests {
+		if _, err := parseCron(tt.expr); (err != nil) != tt.wantErr {
+			t.Errorf("parseCron(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	// 2026-10-17 为周六
+	from := at("2026-10-17 10:07:30")
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want string // 为空表示 5 年内无匹配
+	}{
+		{name: "每 15 分钟", expr: "*/15 * * * *", from: from, want: "2026-10-17 10:15:00"},
+		{name: "严格晚于起始时间", expr: "*/15 * * * *", from: at("2026-10-17 10:15:00"), want: "2026-10-17 10:30:00"},
+		{name: "带起点的步长", expr: "5/15 * * * *", from: from, want: "2026-10-17 10:20:00"},
+		{name: "每天凌晨跨日", expr: "0 3 * * *", from: from, want: "2026-10-18 03:00:00"},
+		{name: "工作日跳过周末", expr: "30 9 * * 1-5", from: from, want: "2026-10-19 09:30:00"},
+		{name: "周日写作 7", expr: "0 0 * * 7", from: from, want: "2026-10-18 00:00:00"},
+		{name: "日与周满足其一", expr: "0 0 1 * 0", from: from, want: "2026-10-18 00:00:00"},
+		{name: "指定月份", expr: "0 12 1 11 *", from: from, want: "2026-11-01 12:00:00"},
+		{name: "闰日", expr: "0 0 29 2 *", from: from, want: "2028-02-29 00:00:00"},
+		{name: "不存在的日期", expr: "0 0 31 2 *", from: from, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron(%q) error = %v", tt.expr, err)
+			}
+			got := spec.next(tt.from)
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("next() = %s, want zero", got)
+				}
+				return
+			}
+			if want := at(tt.want); !got.Equal(want) {
+				t.Errorf("next() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestParseScheduleInput(t *testing.T) {
+	tests := []struct {
+		input        string
+		wantInterval int
+		wantCron     string
+		wantJitter   int
+		wantErr      bool
+	}{
+		{input: "-"},
+		{input: "30s", wantInterval: 30},
+		{input: "5m | 30s", wantInterval: 300, wantJitter: 30},
+		{input: "*/5  *   * * *", wantCron: "*/5 * * * *"},
+		{input: "0 3 * * * | 1m", wantCron: "0 3 * * *", wantJitter: 60},
+		{input: "5s", wantErr: true},
+		{input: "5m | -1s", wantErr: true},
+		{input: "5m | abc", wantErr: true},
+		{input: "61 * * * *", wantErr: true},
+		{input: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d := models.DomainRecord{CheckInterval: 99, CheckCron: "* * * * *", CheckJitter: 9}
+			err := parseScheduleInput(&d, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseScheduleInput(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if d.CheckInterval != tt.wantInterval || d.CheckCron != tt.wantCron || d.CheckJitter != tt.wantJitter {
+				t.Errorf("parseScheduleInput(%q) = (%d, %q, %d), want (%d, %q, %d)", tt.input,
+					d.CheckInterval, d.CheckCron, d.CheckJitter, tt.wantInterval, tt.wantCron, tt.wantJitter)
+			}
+		})
+	}
+}
+
+func TestNextCheckTime(t *testing.T) {
+	from := time.Date(2026, 10, 17, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		d    models.DomainRecord
+		want time.Time
+	}{
+		{name: "全局间隔", d: models.DomainRecord{}, want: from.Add(defaultCheckInterval)},
+		{name: "自定义间隔", d: models.DomainRecord{CheckInterval: 120}, want: from.Add(2 * time.Minute)},
+		{name: "cron 优先于间隔", d: models.DomainRecord{CheckInterval: 120, CheckCron: "0 * * * *"}, want: time.Date(2026, 10, 17, 11, 0, 0, 0, time.UTC)},
+		{name: "无效 cron 使用间隔", d: models.DomainRecord{CheckInterval: 120, CheckCron: "bad"}, want: from.Add(2 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextCheckTime(tt.d, from); !got.Equal(tt.want) {
+				t.Errorf("nextCheckTime() = %s, want %s", got, tt.want)
+			}
+			// 随机推迟不超过上限
+			tt.d.CheckJitter = 30
+			got := nextCheckTime(tt.d, from)
+			if got.Before(tt.want) || got.After(tt.want.Add(30*time.Second)) {
+				t.Errorf("nextCheckTime() with jitter = %s, want within 30s after %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSchedulerDueDomains(t *testing.T) {
+	s := &checkScheduler{entries: make(map[uint]*scheduleEntry), running: make(map[uint]bool)}
+	// finish 按当前时间计算下一次检测，测试时间以当前整点为基准
+	hour := time.Now().UTC().Truncate(time.Hour)
+	now := hour.Add(7*time.Minute + 30*time.Second)
+	domains := []models.DomainRecord{
+		{ID: 1, CheckInterval: 60},
+		{ID: 2, CheckCron: "0 * * * *"},
+		{ID: 3, IsDisableCheck: true},
+	}
+
+	// 按间隔检测的新主域名立即到期，cron 等到下一个匹配时间，禁用检测的不调度
+	if due := s.dueDomains(domains, now); len(due) != 1 || due[0] != 1 {
+		t.Fatalf("dueDomains() = %v, want [1]", due)
+	}
+	if next := s.nextCheckAt(2); !next.Equal(hour.Add(time.Hour)) {
+		t.Errorf("nextCheckAt(2) = %s, want %s", next, hour.Add(time.Hour))
+	}
+	if next := s.nextCheckAt(3); !next.IsZero() {
+		t.Errorf("nextCheckAt(3) = %s, want zero", next)
+	}
+
+	// 检测中的主域名不会再次到期，也不能被手动检测占用
+	if due := s.dueDomains(domains, now.Add(time.Hour)); len(due) != 1 || due[0] != 2 {
+		t.Fatalf("dueDomains() while running = %v, want [2]", due)
+	}
+	if s.claim(1) {
+		t.Error("claim(1) = true while auto check is running")
+	}
+	s.finish(domains[0])
+	if !s.claim(1) {
+		t.Error("claim(1) = false after finish")
+	}
+	if due := s.dueDomains(domains, now.Add(2*time.Hour)); len(due) != 0 {
+		t.Errorf("dueDomains() while claimed = %v, want none", due)
+	}
+	s.release(1)
+	if due := s.dueDomains(domains, now.Add(2*time.Hour)); len(due) != 1 || due[0] != 1 {
+		t.Errorf("dueDomains() after release = %v, want [1]", due)
+	}
+
+	// 已删除的主域名移出调度表
+	s.dueDomains(domains[:1], now.Add(3*time.Hour))
+	if next := s.nextCheckAt(2); !next.IsZero() {
+		t.Errorf("nextCheckAt(2) after delete = %s, want zero", next)
+	}
+}
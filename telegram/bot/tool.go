@@ -53,6 +53,14 @@ func escapeMarkdown(s string) string {
 	return replacer.Replace(s)
 }
 
+// markdownCode Markdown (v1) 行内代码，内容包含反引号时无法放入代码中，改为转义后的普通文本
+func markdownCode(s string) string {
+	if strings.Contains(s, "`") {
+		return escapeMarkdown(s)
+	}
+	return "`" + s + "`"
+}
+
 // SendMessage 封装发送消息
 // parseModeFlag: 0=普通文本, 1=Markdown, 2=MarkdownV2, 3=HTML
 // disableNotification: 是否静默发送
@@ -170,9 +178,10 @@ func parseBatchUploadContent(content string) ([]models.DomainRecord, error) {
 		}
 	}
 
-	// 转换为切片
+	// 转换为切片，并按转发记录类型推断地址族
 	result := make([]models.DomainRecord, 0, len(domainMap))
 	for _, domain := range domainMap {
+		domain.IPFamily = inferIPFamily(domain.Forwards)
 		result = append(result, *domain)
 	}
 
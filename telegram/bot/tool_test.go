@@ -0,0 +1,20 @@
+package bot
+
+import "testing"
+
+func TestMarkdownCode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "GET /health", want: "`GET /health`"},
+		{input: `^ok_[a-z]*$`, want: "`^ok_[a-z]*$`"},
+		{input: "a`b_c", want: "a\\`b\\_c"},
+		{input: "*[x]`", want: "\\*\\[x]\\`"},
+	}
+	for _, tt := range tests {
+		if got := markdownCode(tt.input); got != tt.want {
+			t.Errorf("markdownCode(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"telegram-auto-switch-dns-bot/CheckBackend"
+	"telegram-auto-switch-dns-bot/db"
+	"telegram-auto-switch-dns-bot/db/models"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+// traceEditInterval 追踪过程中刷新消息的最短间隔，避免触发 Telegram 编辑频率限制
+const traceEditInterval = 1500 * time.Millisecond
+
+// traceStreamLine 路由追踪接口的单行响应
+type traceStreamLine struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// callBackendTrace 调用检测后端的路由追踪接口，每收到一跳回调一次 onHop
+func callBackendTrace(d models.DomainRecord, target string, family string, onHop func(CheckBackend.TraceHop)) (CheckBackend.TraceResponse, error) {
+	// UDP 服务使用 UDP 追踪，其余使用 TCP-SYN 追踪到业务端口
+	protocol := "tcp"
+	if d.ProbeType == "udp" {
+		protocol = "udp"
+	}
+	payload := CheckBackend.TraceRequest{
+		Target:   target,
+		Port:     d.Port,
+		Key:      legacyKey(),
+		Protocol: protocol,
+		Resolver: d.Resolver,
+		Family:   family,
+	}
+	buf, _ := json.Marshal(payload)
+
+	// 路由追踪只需一个后端，多后端时使用第一个
+	resp, err := doBackend(probeBackendList()[0], http.MethodPost, "/api/v1/trace", buf, 2*time.Minute)
+	if err != nil {
+		return CheckBackend.TraceResponse{}, fmt.Errorf("调用后端接口失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var streamLine traceStreamLine
+		if err := json.Unmarshal(line, &streamLine); err != nil {
+			utils.Logger.Warnf("Failed to parse trace response line: %s, error: %v", line, err)
+			continue
+		}
+
+		switch {
+		case streamLine.Code == 1 && streamLine.Message == "hop":
+			var hop CheckBackend.TraceHop
+			if err := json.Unmarshal(streamLine.Data, &hop); err == nil {
+				onHop(hop)
+			}
+		case streamLine.Code == 0:
+			var result CheckBackend.TraceResponse
+			if err := json.Unmarshal(streamLine.Data, &result); err != nil {
+				return CheckBackend.TraceResponse{}, fmt.Errorf("解析追踪结果失败: %w", err)
+			}
+			return result, nil
+		default:
+			return CheckBackend.TraceResponse{}, fmt.Errorf("后端返回错误: %s", streamLine.Message)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return CheckBackend.TraceResponse{}, fmt.Errorf("读取追踪结果失败: %w", err)
+	}
+	return CheckBackend.TraceResponse{}, fmt.Errorf("后端未返回追踪结果")
+}
+
+// formatTraceHops 将每跳结果渲染为等宽文本
+func formatTraceHops(hops []CheckBackend.TraceHop) string {
+	var sb strings.Builder
+	for _, h := range hops {
+		if h.Timeout {
+			sb.WriteString(fmt.Sprintf("%2d  *\n", h.TTL))
+			continue
+		}
+		ip := h.IP
+		if ip == "" {
+			ip = "?"
+		}
+		sb.WriteString(fmt.Sprintf("%2d  %-15s %7.1fms", h.TTL, ip, h.RTT))
+		if h.Note != "" {
+			sb.WriteString("  " + h.Note)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// traceMessage 生成路由追踪消息文本
+func traceMessage(f models.ForwardRecord, d models.DomainRecord, hops []CheckBackend.TraceHop, result *CheckBackend.TraceResponse) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🛰 *路由追踪* `%s:%d`\n", f.ForwardDomain, d.Port))
+	if result == nil {
+		sb.WriteString("⏳ 追踪中...\n")
+	} else {
+		if result.TargetIp != "" {
+			sb.WriteString(fmt.Sprintf("目标 IP: `%s` | 协议: %s\n", result.TargetIp, strings.ToUpper(result.Protocol)))
+		}
+		if result.PathMTU > 0 {
+			sb.WriteString(fmt.Sprintf("路径 MTU: %d\n", result.PathMTU))
+		}
+		status := "⚠️"
+		if result.Result {
+			status = "✅"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s\n", status, escapeMarkdown(result.Message)))
+	}
+	if len(hops) > 0 {
+		sb.WriteString("```\n" + formatTraceHops(hops) + "```")
+	}
+	return sb.String()
+}
+
+// handleForwardTrace 从检测后端对转发域名进行路由追踪，逐跳刷新消息
+func handleForwardTrace(bot *tgbotapi.BotAPI, chatID int64, messageID int, forwardID uint) {
+	if db.DB == nil {
+		if err := db.InitDB(); err != nil {
+			edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ 数据库初始化失败")
+			_, _ = bot.Send(edit)
+			return
+		}
+	}
+
+	var f models.ForwardRecord
+	if err := db.DB.Where("id = ?", forwardID).First(&f).Error; err != nil {
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ 未找到该转发记录")
+		_, _ = bot.Send(edit)
+		return
+	}
+	var d models.DomainRecord
+	if err := db.DB.Where("id = ?", f.DomainRecordID).First(&d).Error; err != nil {
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ 未找到对应的主域名")
+		_, _ = bot.Send(edit)
+		return
+	}
+
+	backKb := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回转发详情", "fwd:"+strconv.FormatUint(uint64(f.ID), 10)),
+	))
+	render := func(hops []CheckBackend.TraceHop, result *CheckBackend.TraceResponse) {
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, traceMessage(f, d, hops, result))
+		edit.ParseMode = "Markdown"
+		if result != nil {
+			edit.ReplyMarkup = &backKb
+		}
+		_, _ = bot.Send(edit)
+	}
+	render(nil, nil)
+
+	var hops []CheckBackend.TraceHop
+	lastEdit := time.Now()
+	result, err := callBackendTrace(d, f.ForwardDomain, forwardFamily(d, f), func(hop CheckBackend.TraceHop) {
+		hops = append(hops, hop)
+		if time.Since(lastEdit) >= traceEditInterval {
+			render(hops, nil)
+			lastEdit = time.Now()
+		}
+	})
+	if err != nil {
+		utils.Logger.Warnf("❌ 路由追踪 %s 失败: %v", f.ForwardDomain, err)
+		result = CheckBackend.TraceResponse{Message: fmt.Sprintf("路由追踪失败: %v", err)}
+	} else {
+		hops = result.Hops
+	}
+	render(hops, &result)
+}
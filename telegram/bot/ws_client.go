@@ -0,0 +1,331 @@
+package bot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"telegram-auto-switch-dns-bot/CheckBackend"
+	"telegram-auto-switch-dns-bot/config"
+	"telegram-auto-switch-dns-bot/utils"
+)
+
+var (
+	errWSUnavailable = errors.New("WebSocket 通道未连接")
+	errWSClosed      = errors.New("WebSocket 通道已断开")
+)
+
+// wsJobBody 任务响应体：读取循环按行写入、调用方读取
+// 写入不会阻塞，慢任务不会阻塞同一连接上的其他任务
+type wsJobBody struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	buf     bytes.Buffer
+	err     error // 任务结束原因，io.EOF 表示正常结束
+	closed  bool
+	done    chan struct{}
+	onClose func() // 调用方提前关闭时取消任务
+}
+
+func newWSJobBody() *wsJobBody {
+	b := &wsJobBody{done: make(chan struct{})}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *wsJobBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.buf.Len() == 0 && b.err == nil && !b.closed {
+		b.cond.Wait()
+	}
+	if b.buf.Len() > 0 {
+		return b.buf.Read(p)
+	}
+	if b.closed {
+		return 0, io.ErrClosedPipe
+	}
+	return 0, b.err
+}
+
+func (b *wsJobBody) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	finished := b.err != nil
+	b.cond.Broadcast()
+	b.mu.Unlock()
+
+	if !finished && b.onClose != nil {
+		b.onClose()
+	}
+	return nil
+}
+
+// writeLine 追加一行响应
+func (b *wsJobBody) writeLine(line string) {
+	b.mu.Lock()
+	b.buf.WriteString(line)
+	b.buf.WriteByte('\n')
+	b.cond.Broadcast()
+	b.mu.Unlock()
+}
+
+// finish 结束任务，只记录第一次的结束原因
+func (b *wsJobBody) finish(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return
+	}
+	b.err = err
+	close(b.done)
+	b.cond.Broadcast()
+}
+
+// wsJob 通道上等待响应的任务
+type wsJob struct {
+	conn *websocket.Conn // 提交任务的连接
+	head chan int        // 响应状态码
+	body *wsJobBody
+}
+
+// wsSession 与单个检测后端之间的 WebSocket 任务通道，一条连接复用多个并发任务
+type wsSession struct {
+	name    string
+	writeMu sync.Mutex
+	nextID  atomic.Uint64
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+	jobs map[string]*wsJob
+}
+
+func newWSSession(name string) *wsSession {
+	return &wsSession{name: name, jobs: make(map[string]*wsJob)}
+}
+
+var (
+	wsSessionsMu sync.Mutex
+	wsSessions   = make(map[string]*wsSession) // key 为 endpointID
+)
+
+// backendSession 返回检测后端的任务通道，首次调用时在后台建立连接并保持重连
+// 反向连接后端由后端主动连接，这里只创建等待注册的通道
+func backendSession(endpoint config.BackendEndpoint) *wsSession {
+	wsSessionsMu.Lock()
+	defer wsSessionsMu.Unlock()
+	id := endpointID(endpoint)
+	s, ok := wsSessions[id]
+	if !ok {
+		s = newWSSession(endpoint.Name)
+		wsSessions[id] = s
+		if !endpoint.Reverse {
+			go s.dialLoop(endpoint)
+		}
+	}
+	return s
+}
+
+// startBackendSessions 开启 WebSocket 时预先连接所有检测后端
+func startBackendSessions() {
+	if !config.Global.BackendURL.WebSocket {
+		return
+	}
+	for _, endpoint := range probeBackendList() {
+		if !endpoint.Reverse {
+			backendSession(endpoint)
+		}
+	}
+}
+
+// dialLoop 连接检测后端并在断开后按指数退避重连
+func (s *wsSession) dialLoop(endpoint config.BackendEndpoint) {
+	backoff := CheckBackend.WSMinBackoff
+	for {
+		start := time.Now()
+		conn, err := dialBackendWS(endpoint)
+		if err == nil {
+			utils.Logger.Infof("🔌 已与检测后端 %s 建立 WebSocket 通道", s.name)
+			err = s.serve(conn)
+		}
+		// 连接保持足够久后重新从最短退避开始
+		if time.Since(start) > CheckBackend.WSMaxBackoff {
+			backoff = CheckBackend.WSMinBackoff
+		}
+		utils.Logger.Warnf("⚠️ 检测后端 %s 的 WebSocket 通道不可用: %v，%s 后重连", s.name, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > CheckBackend.WSMaxBackoff {
+			backoff = CheckBackend.WSMaxBackoff
+		}
+	}
+}
+
+// dialBackendWS 使用签名请求头（兼容模式下附带 key 请求头）连接检测后端的 /api/v1/ws
+func dialBackendWS(endpoint config.BackendEndpoint) (*websocket.Conn, error) {
+	wsURL := strings.TrimRight(endpoint.Api, "/") + "/api/v1/ws"
+	switch {
+	case strings.HasPrefix(wsURL, "https://"):
+		wsURL = "wss://" + strings.TrimPrefix(wsURL, "https://")
+	case strings.HasPrefix(wsURL, "http://"):
+		wsURL = "ws://" + strings.TrimPrefix(wsURL, "http://")
+	}
+	req, err := newBackendRequest(http.MethodGet, wsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := backendTLSClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:  tlsConfig,
+	}
+	conn, resp, err := dialer.Dial(wsURL, req.Header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("%w (HTTP %d)", err, resp.StatusCode)
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// serve 挂载连接并读取响应帧，直到连接断开；断开时该连接上未完成的任务以错误结束
+// 同一后端重复注册时新连接替换旧连接
+func (s *wsSession) serve(conn *websocket.Conn) error {
+	s.mu.Lock()
+	old := s.conn
+	s.conn = conn
+	s.mu.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
+
+	stopPing := CheckBackend.KeepAlive(conn, &s.writeMu)
+	defer func() {
+		stopPing()
+		_ = conn.Close()
+		s.mu.Lock()
+		if s.conn == conn {
+			s.conn = nil
+		}
+		var closed []*wsJob
+		for id, job := range s.jobs {
+			if job.conn == conn {
+				closed = append(closed, job)
+				delete(s.jobs, id)
+			}
+		}
+		s.mu.Unlock()
+		for _, job := range closed {
+			job.body.finish(errWSClosed)
+		}
+	}()
+
+	for {
+		var frame CheckBackend.WSFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			return err
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(CheckBackend.WSPongWait))
+
+		s.mu.Lock()
+		job, ok := s.jobs[frame.ID]
+		if ok && frame.Type == CheckBackend.WSFrameDone {
+			delete(s.jobs, frame.ID)
+		}
+		s.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		switch frame.Type {
+		case CheckBackend.WSFrameHead:
+			select {
+			case job.head <- frame.Status:
+			default:
+			}
+		case CheckBackend.WSFrameLine:
+			job.body.writeLine(frame.Line)
+		case CheckBackend.WSFrameDone:
+			if frame.Error != "" {
+				job.body.finish(errors.New(frame.Error))
+			} else {
+				job.body.finish(io.EOF)
+			}
+		}
+	}
+}
+
+// send 写入一帧
+func (s *wsSession) send(conn *websocket.Conn, frame CheckBackend.WSFrame) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_ = conn.SetWriteDeadline(time.Now().Add(CheckBackend.WSWriteTimeout))
+	return conn.WriteJSON(frame)
+}
+
+// cancel 取消未完成的任务并通知后端
+func (s *wsSession) cancel(id string) {
+	s.mu.Lock()
+	_, ok := s.jobs[id]
+	delete(s.jobs, id)
+	conn := s.conn
+	s.mu.Unlock()
+	if ok && conn != nil {
+		_ = s.send(conn, CheckBackend.WSFrame{Type: CheckBackend.WSFrameCancel, ID: id})
+	}
+}
+
+// Do 通过通道提交任务，收到响应状态码后返回，响应体按行读取（与 HTTP 流式响应一致）
+func (s *wsSession) Do(method string, path string, body []byte, timeout time.Duration) (*backendResponse, error) {
+	s.mu.Lock()
+	conn := s.conn
+	if conn == nil {
+		s.mu.Unlock()
+		return nil, errWSUnavailable
+	}
+	id := strconv.FormatUint(s.nextID.Add(1), 10)
+	job := &wsJob{conn: conn, head: make(chan int, 1), body: newWSJobBody()}
+	job.body.onClose = func() { s.cancel(id) }
+	s.jobs[id] = job
+	s.mu.Unlock()
+
+	if err := s.send(conn, CheckBackend.WSFrame{Type: CheckBackend.WSFrameJob, ID: id, Method: method, Path: path, Body: body}); err != nil {
+		s.cancel(id)
+		return nil, fmt.Errorf("提交任务失败: %w", err)
+	}
+
+	// 超时后结束任务并通知后端取消
+	timer := time.AfterFunc(timeout, func() {
+		job.body.finish(fmt.Errorf("任务超时 (%s)", timeout))
+		s.cancel(id)
+	})
+	go func() {
+		<-job.body.done
+		timer.Stop()
+	}()
+
+	select {
+	case status := <-job.head:
+		return &backendResponse{StatusCode: status, Body: job.body}, nil
+	case <-job.body.done:
+		return nil, job.body.err
+	}
+}